@@ -1,9 +1,15 @@
 package main
 
 import (
+	"context"
+
 	"item-comparison-ai-api/config"
+	"item-comparison-ai-api/internal/blobstore"
 	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/handlers"
+	"item-comparison-ai-api/internal/i18n"
 	"item-comparison-ai-api/internal/logger"
+	"item-comparison-ai-api/internal/repositories"
 	"item-comparison-ai-api/internal/routes"
 	"item-comparison-ai-api/internal/server"
 
@@ -15,15 +21,35 @@ func main() {
 	var config = config.New()
 	var loggerAdapter = logger.NewLogger(config.Environment)
 	var logger = loggerAdapter.GetLogger()
-	db := database.NewClient(&database.Database{})
+	db := database.NewClient(&database.Database{LockTimeout: config.FileLockTimeout})
 	if db == nil {
 		logger.Fatal("Failed to create database client")
 	}
+	productRepo, err := repositories.OpenProductRepository(db, config)
+	if err != nil {
+		logger.Fatalf("Failed to open %s storage: %v", config.StorageDriver, err)
+	}
+	history := repositories.NewProductHistory(db, config.HistoryPath)
+	prices := repositories.NewPriceHistory(db, config.PriceHistoryPath)
+	reviews := repositories.NewReviewStore(db, config.ReviewsPath)
+	if err := reviews.SyncRatings(productRepo); err != nil {
+		logger.Fatalf("Failed to sync product ratings: %v", err)
+	}
+	brands := repositories.NewBrandStore(db, config.BrandsPath)
+	idempotency := repositories.NewIdempotencyStore(db, config.IdempotencyPath, config.IdempotencyTTL)
+	exchangeRates := repositories.NewExchangeRateStore(db, config.ExchangeRatesPath)
+	images := blobstore.NewDisk(config.ImageDir)
+	locales := i18n.NewLocales(config.DefaultLocale, config.SupportedLocales)
+	snapshots := repositories.NewSnapshotManager(db, productRepo, config.SnapshotDir)
+	snapshots.TrackStores(config)
+	purgeRecords := []repositories.ProductRecords{history, prices, reviews, handlers.ImageRecords{Store: images}}
+	go repositories.RunTrashPurger(context.Background(), productRepo, config.TrashRetention, config.TrashPurgeInterval, purgeRecords...)
 	var server = server.New(config, db, engine, loggerAdapter).
 		WithMiddlewares().
 		WithHealthcheck().
 		WithHandlers("",
-			&routes.ProductRouter{},
+			&routes.ProductRouter{Repository: productRepo, RequireIfMatch: config.RequireIfMatch, History: history, Idempotency: idempotency, ExchangeRates: exchangeRates, PriceHistory: prices, Reviews: reviews, Images: images, MaxImageSize: config.MaxImageSize, Locales: &locales, Brands: brands},
+			&routes.AdminRouter{Snapshots: snapshots, Products: productRepo, ExchangeRates: exchangeRates, PurgeRecords: purgeRecords},
 		)
 
 	logger.Println("Start Item Comparison AI API...")
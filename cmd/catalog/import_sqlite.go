@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"item-comparison-ai-api/config"
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/repositories"
+)
+
+// importSQLite copies the products of the JSON data file into SQLite
+func importSQLite(conf *config.AppConfig, args []string) error {
+	fs := newFlagSet("import-sqlite")
+	from := fs.String("from", conf.DatabasePath, "JSON data file to import")
+	to := fs.String("to", conf.SQLitePath, "SQLite database to import into")
+	force := fs.Bool("force", false, "replace products already present in the database")
+	fs.Parse(args)
+
+	source := *conf
+	source.DatabasePath = *from
+	jsonRepo := repositories.NewProductRepository(repositories.NewBaseRepository(&database.Database{LockTimeout: conf.FileLockTimeout}, &source))
+
+	products, err := jsonRepo.LoadProducts()
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", *from, err)
+	}
+
+	sqliteRepo, err := repositories.NewSQLiteRepository(*to)
+	if err != nil {
+		return err
+	}
+	defer sqliteRepo.Close()
+
+	existing, err := sqliteRepo.LoadProducts()
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 && !*force {
+		return errors.New("database already contains products, use -force to replace them")
+	}
+
+	if err := sqliteRepo.SaveProducts(products); err != nil {
+		return err
+	}
+
+	fmt.Printf("imported %d products from %s into %s\n", len(products), *from, *to)
+	return nil
+}
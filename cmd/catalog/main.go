@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"item-comparison-ai-api/config"
+	"item-comparison-ai-api/internal/logger"
+)
+
+// command is a catalog maintenance subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(conf *config.AppConfig, args []string) error
+}
+
+var commands = []command{
+	{name: "migrate", summary: "upgrade a data file to the current format version (-dry-run to preview)", run: migrate},
+	{name: "snapshot", summary: "create, list, download or restore catalog snapshots", run: snapshot},
+	{name: "import-sqlite", summary: "one-shot import of the JSON data file into the SQLite database", run: importSQLite},
+}
+
+func main() {
+	var conf = config.New()
+	var logger = logger.NewLogger(conf.Environment).GetLogger()
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(conf, os.Args[2:]); err != nil {
+				logger.Fatalf("%s: %v", cmd.name, err)
+			}
+			return
+		}
+	}
+
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: catalog <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// newFlagSet creates the flag set of a subcommand
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet(name, flag.ExitOnError)
+}
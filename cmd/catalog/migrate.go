@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+
+	"item-comparison-ai-api/config"
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/migrations"
+)
+
+// migrate upgrades a data file to the current format version in place
+func migrate(conf *config.AppConfig, args []string) error {
+	fs := newFlagSet("migrate")
+	file := fs.String("file", conf.DatabasePath, "data file to migrate")
+	dryRun := fs.Bool("dry-run", false, "print the changes without writing the file")
+	fs.Parse(args)
+
+	store := &database.Database{LockTimeout: conf.FileLockTimeout}
+	data, err := store.Read(*file)
+	if err != nil {
+		return err
+	}
+
+	upgraded, from, err := migrations.Upgrade(data)
+	if err != nil {
+		return err
+	}
+
+	current := migrations.CurrentVersion()
+	if from == current {
+		fmt.Printf("%s is already at version %d\n", *file, current)
+		return nil
+	}
+
+	fmt.Printf("%s: version %d -> %d\n", *file, from, current)
+	for _, step := range migrations.Steps() {
+		if step.From >= from {
+			fmt.Printf("  %d -> %d: %s\n", step.From, step.From+1, step.Description)
+		}
+	}
+
+	if *dryRun {
+		original, _, err := parseProducts(data)
+		if err != nil {
+			return err
+		}
+		printProductDiff(os.Stdout, original, upgraded["products"])
+		return nil
+	}
+
+	// Going through the typed model keeps the usual field order in the file
+	doc, err := migrations.DecodeDocument(data)
+	if err != nil {
+		return err
+	}
+	out, err := migrations.EncodeDocument(doc)
+	if err != nil {
+		return err
+	}
+	if err := store.Write(*file, out, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("migrated %s\n", *file)
+	return nil
+}
+
+// parseProducts extracts the generic product list of a data file of any version
+func parseProducts(data []byte) (interface{}, int, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, 0, err
+	}
+	if doc, ok := raw.(map[string]interface{}); ok {
+		version, _ := doc["version"].(float64)
+		return doc["products"], int(version), nil
+	}
+	return raw, migrations.LegacyVersion, nil
+}
+
+// printProductDiff prints every product whose stored representation changes,
+// as removed (-) and added (+) lines
+func printProductDiff(w io.Writer, before, after interface{}) {
+	beforeList, _ := before.([]interface{})
+	afterList, _ := after.([]interface{})
+
+	changed := 0
+	for i := 0; i < max(len(beforeList), len(afterList)); i++ {
+		var was, now interface{}
+		if i < len(beforeList) {
+			was = beforeList[i]
+		}
+		if i < len(afterList) {
+			now = afterList[i]
+		}
+		if reflect.DeepEqual(was, now) {
+			continue
+		}
+
+		changed++
+		if was != nil {
+			line, _ := json.Marshal(was)
+			fmt.Fprintf(w, "- %s\n", line)
+		}
+		if now != nil {
+			line, _ := json.Marshal(now)
+			fmt.Fprintf(w, "+ %s\n", line)
+		}
+	}
+
+	fmt.Fprintf(w, "%d of %d products change\n", changed, len(afterList))
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"item-comparison-ai-api/config"
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/repositories"
+)
+
+// snapshot mirrors the /admin/snapshots endpoints:
+//
+//	catalog snapshot create [name]
+//	catalog snapshot list
+//	catalog snapshot download [-out file] <name>
+//	catalog snapshot restore [-api-stopped] <name>
+//
+// The memory and journal drivers keep the catalog in the memory of the API
+// process, which would write its own state over a restore on its next change.
+// Restoring is refused for memory, where the CLI has no catalog to restore
+// into, and needs -api-stopped for journal.
+func snapshot(conf *config.AppConfig, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: catalog snapshot create|list|download|restore [name]")
+	}
+	action := args[0]
+
+	fs := newFlagSet("snapshot " + action)
+	dir := fs.String("dir", conf.SnapshotDir, "snapshot directory")
+	out := fs.String("out", "", "file to download to (default stdout)")
+	apiStopped := fs.Bool("api-stopped", false, "confirm that no API process serves the catalog while restoring")
+	fs.Parse(args[1:])
+	name := fs.Arg(0)
+
+	db := database.NewClient(&database.Database{LockTimeout: conf.FileLockTimeout})
+	products, err := repositories.OpenProductRepository(db, conf)
+	if err != nil {
+		return err
+	}
+	if closer, ok := products.(io.Closer); ok {
+		defer closer.Close()
+	}
+	snapshots := repositories.NewSnapshotManager(db, products, *dir)
+	snapshots.TrackStores(conf)
+
+	switch action {
+	case "create":
+		info, err := snapshots.Create(name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("created snapshot %s with %d products\n", info.Name, info.ProductCount)
+
+	case "list":
+		list, err := snapshots.List()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tCREATED\tPRODUCTS")
+		for _, info := range list {
+			fmt.Fprintf(w, "%s\t%s\t%d\n", info.Name, info.CreatedAt.Format(time.RFC3339), info.ProductCount)
+		}
+		return w.Flush()
+
+	case "download":
+		data, err := snapshots.Download(name)
+		if err != nil {
+			return err
+		}
+		if *out == "" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		return os.WriteFile(*out, data, 0644)
+
+	case "restore":
+		switch conf.StorageDriver {
+		case "memory":
+			return errors.New("the memory driver keeps the catalog inside the API process, restore it with POST /admin/snapshots/{name}/restore")
+		case "journal":
+			if !*apiStopped {
+				return errors.New("the journal driver keeps the catalog in the API's memory, which would overwrite the restore: stop the API and pass -api-stopped, or use POST /admin/snapshots/{name}/restore")
+			}
+		}
+		info, err := snapshots.Restore(name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("restored snapshot %s (%d products)\n", info.Name, info.ProductCount)
+
+	default:
+		return fmt.Errorf("unknown snapshot action %q", action)
+	}
+
+	return nil
+}
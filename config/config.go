@@ -3,6 +3,9 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,6 +14,69 @@ type AppConfig struct {
 	BindAddr     string
 	DatabasePath string
 	Environment  string
+
+	// FileLockTimeout bounds the wait for a data file locked by another process
+	FileLockTimeout time.Duration
+
+	// StorageDriver selects the product persistence backend: "json", "memory",
+	// "journal" or "sqlite"
+	StorageDriver string
+
+	// HotReload watches the JSON data file and swaps in external edits
+	HotReload             bool
+	HotReloadPollInterval time.Duration
+
+	// Memory backend settings
+	MemorySeedPath string
+
+	// Journal backend settings
+	JournalPath             string
+	JournalCompactInterval  time.Duration
+	JournalCompactThreshold int
+
+	// SQLite backend settings
+	SQLitePath string
+
+	// RequireIfMatch rejects product mutations sent without an If-Match header
+	RequireIfMatch bool
+
+	// SnapshotDir is where catalog snapshots are stored
+	SnapshotDir string
+
+	// HistoryPath is the file holding the product revision history
+	HistoryPath string
+
+	// PriceHistoryPath is the file holding the price changes of products
+	PriceHistoryPath string
+
+	// ReviewsPath is the file holding the product reviews
+	ReviewsPath string
+
+	// BrandsPath is the file holding the brands products link to
+	BrandsPath string
+
+	// Trashed products are purged once they were deleted longer than
+	// TrashRetention ago; zero keeps them until purged by hand
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+
+	// Responses to requests sent with an Idempotency-Key are kept in
+	// IdempotencyPath for IdempotencyTTL
+	IdempotencyPath string
+	IdempotencyTTL  time.Duration
+
+	// ExchangeRatesPath is the file holding the exchange-rate table
+	ExchangeRatesPath string
+
+	// Uploaded product images are stored in ImageDir; larger uploads than
+	// MaxImageSize bytes are rejected
+	ImageDir     string
+	MaxImageSize int64
+
+	// Product texts are in DefaultLocale and can be translated into the
+	// other SupportedLocales
+	DefaultLocale    string
+	SupportedLocales []string
 }
 
 // New - responsible to store env configs
@@ -19,9 +85,97 @@ func New() *AppConfig {
 		fmt.Println("WARN - ERROR TO LOAD .ENV FILE")
 	}
 
+	databasePath := os.Getenv("DATA_FILE_PATH")
+
 	return &AppConfig{
 		BindAddr:     os.Getenv("BIND_ADDR"),
-		DatabasePath: os.Getenv("DATA_FILE_PATH"),
+		DatabasePath: databasePath,
 		Environment:  os.Getenv("ENVIRONMENT"),
+
+		FileLockTimeout: getEnvDuration("FILE_LOCK_TIMEOUT", 5*time.Second),
+
+		StorageDriver: getEnv("STORAGE_DRIVER", "json"),
+
+		HotReload:             getEnvBool("HOT_RELOAD", false),
+		HotReloadPollInterval: getEnvDuration("HOT_RELOAD_POLL_INTERVAL", 2*time.Second),
+
+		MemorySeedPath: getEnv("MEMORY_SEED_FILE", databasePath),
+
+		JournalPath:             getEnv("JOURNAL_FILE_PATH", databasePath+".journal"),
+		JournalCompactInterval:  getEnvDuration("JOURNAL_COMPACT_INTERVAL", time.Minute),
+		JournalCompactThreshold: getEnvInt("JOURNAL_COMPACT_THRESHOLD", 1000),
+
+		SQLitePath: getEnv("SQLITE_PATH", "catalog.db"),
+
+		RequireIfMatch: getEnvBool("REQUIRE_IF_MATCH", false),
+
+		SnapshotDir: getEnv("SNAPSHOT_DIR", "snapshots"),
+
+		HistoryPath: getEnv("HISTORY_FILE_PATH", databasePath+".history"),
+
+		PriceHistoryPath: getEnv("PRICE_HISTORY_FILE_PATH", databasePath+".prices"),
+
+		ReviewsPath: getEnv("REVIEWS_FILE_PATH", databasePath+".reviews"),
+
+		BrandsPath: getEnv("BRANDS_FILE_PATH", databasePath+".brands"),
+
+		TrashRetention:     getEnvDuration("TRASH_RETENTION", 30*24*time.Hour),
+		TrashPurgeInterval: getEnvDuration("TRASH_PURGE_INTERVAL", time.Hour),
+
+		IdempotencyPath: getEnv("IDEMPOTENCY_FILE_PATH", databasePath+".idempotency"),
+		IdempotencyTTL:  getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+
+		ExchangeRatesPath: getEnv("EXCHANGE_RATES_FILE_PATH", "exchange_rates.json"),
+
+		ImageDir:     getEnv("IMAGE_DIR", "images"),
+		MaxImageSize: int64(getEnvInt("MAX_IMAGE_SIZE", 10<<20)),
+
+		DefaultLocale:    getEnv("DEFAULT_LOCALE", "en"),
+		SupportedLocales: getEnvList("SUPPORTED_LOCALES", []string{"en", "de", "fr"}),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvList splits a comma separated variable, ignoring empty items
+func getEnvList(key string, fallback []string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return fallback
+	}
+	return items
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
 	}
+	return value
 }
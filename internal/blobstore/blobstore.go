@@ -0,0 +1,112 @@
+package blobstore
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+var (
+	// ErrNotFound is returned when no blob is stored under a key
+	ErrNotFound = errors.New("blob not found")
+	// ErrInvalidKey is returned for keys that are not clean relative
+	// slash-separated paths
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Store keeps binary objects, such as uploaded images, under slash-separated
+// keys like "products/1/a1b2.jpg"
+type Store interface {
+	// Put stores the content of r under key, replacing any previous blob
+	Put(key string, r io.Reader) error
+	// Open returns the blob stored under key
+	Open(key string) (io.ReadSeekCloser, error)
+	// Delete removes the blob stored under key; missing blobs are ignored
+	Delete(key string) error
+}
+
+// Disk stores blobs as files below a root directory
+type Disk struct {
+	root string
+}
+
+// NewDisk returns a Store keeping its blobs below root, which is created on
+// the first Put
+func NewDisk(root string) *Disk {
+	return &Disk{root: root}
+}
+
+// Put writes the blob to a temporary file which is then renamed over the
+// target, so readers never see a partial blob
+func (d *Disk) Put(key string, r io.Reader) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Open opens the file of the blob
+func (d *Disk) Open(key string) (io.ReadSeekCloser, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info, err := f.Stat(); err != nil || info.IsDir() {
+		f.Close()
+		return nil, ErrNotFound
+	}
+	return f, nil
+}
+
+// Delete removes the file of the blob
+func (d *Disk) Delete(key string) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// path maps a key to its file, refusing keys that would leave the root
+func (d *Disk) path(key string) (string, error) {
+	if !fs.ValidPath(key) || key == "." {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(d.root, filepath.FromSlash(key)), nil
+}
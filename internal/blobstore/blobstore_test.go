@@ -0,0 +1,41 @@
+package blobstore
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisk_PutOpenDelete(t *testing.T) {
+	store := NewDisk(t.TempDir())
+
+	assert.NoError(t, store.Put("products/1/a.jpg", strings.NewReader("first")))
+	assert.NoError(t, store.Put("products/1/a.jpg", strings.NewReader("second")))
+
+	blob, err := store.Open("products/1/a.jpg")
+	if assert.NoError(t, err) {
+		data, _ := io.ReadAll(blob)
+		blob.Close()
+		assert.Equal(t, "second", string(data))
+	}
+
+	_, err = store.Open("products/1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, store.Delete("products/1/a.jpg"))
+	assert.NoError(t, store.Delete("products/1/a.jpg"))
+	_, err = store.Open("products/1/a.jpg")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDisk_RejectsKeysOutsideRoot(t *testing.T) {
+	store := NewDisk(t.TempDir())
+
+	for _, key := range []string{"", ".", "../secret", "/etc/passwd", "products/../../x", "products//a.jpg"} {
+		assert.ErrorIs(t, store.Put(key, strings.NewReader("x")), ErrInvalidKey, key)
+		_, err := store.Open(key)
+		assert.ErrorIs(t, err, ErrInvalidKey, key)
+	}
+}
@@ -2,28 +2,107 @@ package database
 
 import (
 	"os"
+	"path/filepath"
+	"time"
 )
 
 // FileStore defines the interface for file operations
 type FileStore interface {
 	Read(filename string) ([]byte, error)
 	Write(filename string, data []byte, perm os.FileMode) error
+	Append(filename string, data []byte, perm os.FileMode) error
 	CheckLiveness(filename string) error
 }
 
-func NewClient(fileStore FileStore) *Database {
-	return &Database{}
+// NewClient returns the FileStore the application should use, falling back
+// to the os backed Database when none is given
+func NewClient(fileStore FileStore) FileStore {
+	if fileStore == nil {
+		return &Database{}
+	}
+	return fileStore
 }
 
-// Database is an implementation of FileStore that uses os package for file operations
-type Database struct{}
+// Database is an implementation of FileStore that uses os package for file
+// operations. Reads take a shared and writes an exclusive advisory lock on the
+// file, so several processes can safely share it.
+type Database struct {
+	// LockTimeout bounds how long an operation waits for a lock held by
+	// another process; zero means five seconds
+	LockTimeout time.Duration
+}
 
 func (fs *Database) Read(filename string) ([]byte, error) {
-	return os.ReadFile(filename)
+	var data []byte
+	err := fs.withFileLock(filename, false, func() error {
+		var err error
+		data, err = os.ReadFile(filename)
+		return err
+	})
+	return data, err
 }
 
+// Write replaces the file content atomically: data goes to a temporary file in
+// the same directory which is then renamed over the target, so readers never
+// observe a half-written file. Missing parent directories are created.
 func (fs *Database) Write(filename string, data []byte, perm os.FileMode) error {
-	return os.WriteFile(filename, data, perm)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	return fs.withFileLock(filename, true, func() error {
+		return writeAtomic(filename, data, perm)
+	})
+}
+
+func writeAtomic(filename string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
+
+// Append adds data to the end of the file, creating it when missing
+func (fs *Database) Append(filename string, data []byte, perm os.FileMode) error {
+	return fs.withFileLock(filename, true, func() error {
+		return appendFile(filename, data, perm)
+	})
+}
+
+func appendFile(filename string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
 }
 
 func (conn *Database) CheckLiveness(filename string) error {
@@ -13,6 +13,7 @@ import (
 type MockFileStore struct {
 	ReadFunc          func(filename string) ([]byte, error)
 	WriteFunc         func(filename string, data []byte, perm os.FileMode) error
+	AppendFunc        func(filename string, data []byte, perm os.FileMode) error
 	CheckLivenessFunc func(filename string) error
 }
 
@@ -30,6 +31,13 @@ func (m *MockFileStore) Write(filename string, data []byte, perm os.FileMode) er
 	return errors.New("WriteFunc not implemented")
 }
 
+func (m *MockFileStore) Append(filename string, data []byte, perm os.FileMode) error {
+	if m.AppendFunc != nil {
+		return m.AppendFunc(filename, data, perm)
+	}
+	return errors.New("AppendFunc not implemented")
+}
+
 func (m *MockFileStore) CheckLiveness(filename string) error {
 	if m.CheckLivenessFunc != nil {
 		return m.CheckLivenessFunc(filename)
@@ -41,6 +49,9 @@ func TestNewClient(t *testing.T) {
 	mockFileStore := &MockFileStore{}
 	client := NewClient(mockFileStore)
 	assert.NotNil(t, client)
+	assert.Same(t, mockFileStore, client)
+
+	assert.IsType(t, &Database{}, NewClient(nil))
 }
 
 func TestOSFileStore_Read(t *testing.T) {
@@ -87,3 +98,21 @@ func TestOSFileStore_Write(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, content, readContent)
 }
+
+func TestOSFileStore_Append(t *testing.T) {
+	// Create a temporary directory for test files
+	testDir, err := os.MkdirTemp("", "db_test")
+	assert.NoError(t, err)
+	defer os.RemoveAll(testDir) // Clean up the directory after tests
+
+	// Append twice to a file that does not exist yet
+	fs := &Database{}
+	fileName := testDir + "/journal.log"
+	assert.NoError(t, fs.Append(fileName, []byte("hello "), 0644))
+	assert.NoError(t, fs.Append(fileName, []byte("world"), 0644))
+
+	// Read the file and assert that both writes were kept
+	readContent, err := os.ReadFile(fileName)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello world"), readContent)
+}
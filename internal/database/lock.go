@@ -0,0 +1,136 @@
+package database
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrLockTimeout is returned when another process holds a file lock for
+// longer than the configured timeout
+var ErrLockTimeout = errors.New("timed out waiting for the file lock")
+
+const defaultLockTimeout = 5 * time.Second
+
+// Locker is implemented by file stores that can hold an exclusive lock on a
+// file across several operations, such as a whole read-modify-write
+type Locker interface {
+	Lock(filename string) (*FileLock, error)
+}
+
+// FileLock is the exclusive lock of one file. Reads and writes of that file
+// made through the FileLock run under it; everyone else, other goroutines of
+// this process included, still waits for the lock.
+type FileLock struct {
+	fs       *Database
+	filename string
+	f        *os.File
+}
+
+// Lock takes the exclusive lock of filename until Unlock is called on the
+// returned FileLock, which the holder uses for its own reads and writes
+func (fs *Database) Lock(filename string) (*FileLock, error) {
+	f, err := acquireFileLock(filename, true, fs.lockTimeout())
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileLock{fs: fs, filename: filename, f: f}, nil
+}
+
+// Unlock releases the lock
+func (l *FileLock) Unlock() error {
+	return releaseFileLock(l.f)
+}
+
+// Read reads filename, without locking it again when it is the locked file
+func (l *FileLock) Read(filename string) ([]byte, error) {
+	if filename != l.filename {
+		return l.fs.Read(filename)
+	}
+	return os.ReadFile(filename)
+}
+
+// Write replaces the content of filename like Database.Write, without locking
+// it again when it is the locked file
+func (l *FileLock) Write(filename string, data []byte, perm os.FileMode) error {
+	if filename != l.filename {
+		return l.fs.Write(filename, data, perm)
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return writeAtomic(filename, data, perm)
+}
+
+// Append adds data to the end of filename, without locking it again when it
+// is the locked file
+func (l *FileLock) Append(filename string, data []byte, perm os.FileMode) error {
+	if filename != l.filename {
+		return l.fs.Append(filename, data, perm)
+	}
+	return appendFile(filename, data, perm)
+}
+
+func (l *FileLock) CheckLiveness(filename string) error {
+	return l.fs.CheckLiveness(filename)
+}
+
+// withFileLock runs fn holding a shared or exclusive lock on filename
+func (fs *Database) withFileLock(filename string, exclusive bool, fn func() error) error {
+	f, err := acquireFileLock(filename, exclusive, fs.lockTimeout())
+	if err != nil {
+		// Without a directory there is no file to protect; let fn report it
+		if !exclusive && os.IsNotExist(err) {
+			return fn()
+		}
+		return err
+	}
+	defer releaseFileLock(f)
+
+	return fn()
+}
+
+func (fs *Database) lockTimeout() time.Duration {
+	if fs.LockTimeout > 0 {
+		return fs.LockTimeout
+	}
+	return defaultLockTimeout
+}
+
+// acquireFileLock locks the sidecar "<filename>.lock" file. The data file
+// itself is not locked because atomic writes replace it with a rename, which
+// would leave other processes holding a lock on the old inode.
+func acquireFileLock(filename string, exclusive bool, timeout time.Duration) (*os.File, error) {
+	flags := os.O_CREATE | os.O_RDWR
+	f, err := os.OpenFile(filename+".lock", flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	backoff := time.Millisecond
+	for {
+		acquired, err := tryLockFile(f, exclusive)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if acquired {
+			return f, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, ErrLockTimeout
+		}
+
+		time.Sleep(backoff)
+		backoff = min(backoff*2, 50*time.Millisecond)
+	}
+}
+
+func releaseFileLock(f *os.File) error {
+	unlockFile(f)
+	return f.Close()
+}
@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package database
+
+import "os"
+
+// tryLockFile has no flock to call on this platform; only the in-process
+// mutexes protect the data file here
+func tryLockFile(f *os.File, exclusive bool) (bool, error) {
+	return true, nil
+}
+
+func unlockFile(f *os.File) {}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDatabase_LockExcludesOtherProcesses(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.json")
+	assert.NoError(t, os.WriteFile(filename, []byte("[]"), 0644))
+
+	// Two Database values open the lock file separately, exactly like two
+	// processes would
+	owner := &Database{}
+	other := &Database{LockTimeout: 50 * time.Millisecond}
+
+	lock, err := owner.Lock(filename)
+	assert.NoError(t, err)
+
+	// The lock holder keeps reading and writing without deadlocking
+	assert.NoError(t, lock.Write(filename, []byte(`[{"id":1}]`), 0644))
+	data, err := lock.Read(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"id":1}]`, string(data))
+
+	_, err = other.Read(filename)
+	assert.ErrorIs(t, err, ErrLockTimeout)
+	assert.ErrorIs(t, other.Write(filename, []byte("[]"), 0644), ErrLockTimeout)
+
+	assert.NoError(t, lock.Unlock())
+
+	data, err = other.Read(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, `[{"id":1}]`, string(data))
+}
+
+func TestDatabase_LockExcludesOtherCallersOfTheSameDatabase(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.json")
+	assert.NoError(t, os.WriteFile(filename, []byte("[]"), 0644))
+
+	fs := &Database{LockTimeout: 50 * time.Millisecond}
+	lock, err := fs.Lock(filename)
+	assert.NoError(t, err)
+
+	// Only the FileLock works under the held lock; reads and writes made
+	// straight through the Database, as other goroutines do, still wait
+	_, err = fs.Read(filename)
+	assert.ErrorIs(t, err, ErrLockTimeout)
+	assert.ErrorIs(t, fs.Write(filename, []byte(`[{"id":2}]`), 0644), ErrLockTimeout)
+
+	assert.NoError(t, lock.Unlock())
+
+	data, err := fs.Read(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, "[]", string(data))
+}
+
+func TestDatabase_SharedLocksDoNotBlockReaders(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "data.json")
+	assert.NoError(t, os.WriteFile(filename, []byte("[]"), 0644))
+
+	reader, err := acquireFileLock(filename, false, time.Second)
+	assert.NoError(t, err)
+	defer releaseFileLock(reader)
+
+	fs := &Database{LockTimeout: 50 * time.Millisecond}
+	_, err = fs.Read(filename)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, fs.Write(filename, []byte("[]"), 0644), ErrLockTimeout)
+}
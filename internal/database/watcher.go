@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// errNotifyUnsupported is returned by watchNotify on platforms without a
+// native file notification API
+var errNotifyUnsupported = errors.New("file notifications are not supported on this platform")
+
+// watchDebounce coalesces the bursts of events an editor or script produces
+// while saving a file into a single change notification
+const watchDebounce = 100 * time.Millisecond
+
+// WatchFile calls onChange whenever filename changes on disk until ctx is
+// done. It uses the platform notification API when available and falls back
+// to polling the file every pollInterval.
+func WatchFile(ctx context.Context, filename string, pollInterval time.Duration, onChange func()) {
+	notify := debounce(ctx, watchDebounce, onChange)
+
+	err := watchNotify(ctx, filename, notify)
+	if err == nil || ctx.Err() != nil {
+		return
+	}
+
+	logrus.WithError(err).Warnf("watcher: falling back to polling %s every %s", filename, pollInterval)
+	watchPoll(ctx, filename, pollInterval, notify)
+}
+
+// watchPoll compares the modification time and size of filename on every tick
+func watchPoll(ctx context.Context, filename string, interval time.Duration, onChange func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last, lastErr := os.Stat(filename)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		current, err := os.Stat(filename)
+		switch {
+		case err != nil && lastErr != nil:
+			// still missing
+		case err != nil || lastErr != nil:
+			onChange()
+		case !current.ModTime().Equal(last.ModTime()) || current.Size() != last.Size():
+			onChange()
+		}
+		last, lastErr = current, err
+	}
+}
+
+// debounce returns a function that calls fn once calls have stopped for wait
+func debounce(ctx context.Context, wait time.Duration, fn func()) func() {
+	var (
+		mu    sync.Mutex
+		timer *time.Timer
+	)
+
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = time.AfterFunc(wait, func() {
+			if ctx.Err() == nil {
+				fn()
+			}
+		})
+	}
+}
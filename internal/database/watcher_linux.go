@@ -0,0 +1,68 @@
+//go:build linux
+
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// watchNotify watches the directory of filename with inotify. The directory is
+// watched rather than the file itself because atomic writes replace the file
+// with a rename, which would silently detach a watch on the old inode.
+func watchNotify(ctx context.Context, filename string, onChange func()) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return err
+	}
+
+	const mask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_CREATE | syscall.IN_DELETE
+	if _, err := syscall.InotifyAddWatch(fd, filepath.Dir(filename), mask); err != nil {
+		syscall.Close(fd)
+		return err
+	}
+
+	// Wrapping the non-blocking descriptor in an os.File hands it to the
+	// runtime poller, so closing the file unblocks a pending Read.
+	events := os.NewFile(uintptr(fd), "inotify")
+	go func() {
+		<-ctx.Done()
+		events.Close()
+	}()
+
+	base := filepath.Base(filename)
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := events.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			nameEnd := nameStart + int(event.Len)
+			name := string(trimNul(buf[nameStart:nameEnd]))
+			offset = nameEnd
+
+			if name == base {
+				onChange()
+			}
+		}
+	}
+}
+
+func trimNul(b []byte) []byte {
+	for i, c := range b {
+		if c == 0 {
+			return b[:i]
+		}
+	}
+	return b
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package database
+
+import "context"
+
+// watchNotify has no native implementation here; WatchFile polls instead
+func watchNotify(ctx context.Context, filename string, onChange func()) error {
+	return errNotifyUnsupported
+}
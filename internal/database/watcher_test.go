@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func assertWatchReportsWrites(t *testing.T, watch func(ctx context.Context, filename string, onChange func())) {
+	filename := filepath.Join(t.TempDir(), "data.json")
+	assert.NoError(t, os.WriteFile(filename, []byte("[]"), 0644))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan struct{}, 16)
+	go watch(ctx, filename, func() { changes <- struct{}{} })
+
+	// Give the watcher time to register before writing
+	time.Sleep(50 * time.Millisecond)
+	fs := &Database{}
+	assert.NoError(t, fs.Write(filename, []byte(`[{"id":1}]`), 0644))
+
+	select {
+	case <-changes:
+	case <-time.After(5 * time.Second):
+		t.Fatal("change was not reported")
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	assertWatchReportsWrites(t, func(ctx context.Context, filename string, onChange func()) {
+		WatchFile(ctx, filename, 10*time.Millisecond, onChange)
+	})
+}
+
+func TestWatchPoll(t *testing.T) {
+	assertWatchReportsWrites(t, func(ctx context.Context, filename string, onChange func()) {
+		watchPoll(ctx, filename, 10*time.Millisecond, onChange)
+	})
+}
@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/money"
+	"item-comparison-ai-api/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BrandStats are the aggregates shown on a brand page, over its live
+// products
+type BrandStats struct {
+	Brand        models.Brand `json:"brand"`
+	ProductCount int          `json:"product_count"`
+	// AverageRating is the mean of every approved review of the brand's
+	// products, zero without reviews
+	AverageRating float64      `json:"average_rating"`
+	ReviewCount   int          `json:"review_count"`
+	PriceRanges   []PriceRange `json:"price_ranges"`
+	Categories    []FacetValue `json:"categories"`
+}
+
+// PriceRange is the lowest and highest price in one currency
+type PriceRange struct {
+	Min money.Money `json:"min"`
+	Max money.Money `json:"max"`
+}
+
+// Facets counts the products of a listing by category and brand
+type Facets struct {
+	Total      int          `json:"total"`
+	Categories []FacetValue `json:"categories"`
+	Brands     []BrandFacet `json:"brands"`
+	// Unbranded counts the products without a brand
+	Unbranded int `json:"unbranded"`
+}
+
+// FacetValue is a value of a field and the number of products having it
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// BrandFacet is a brand and the number of its products
+type BrandFacet struct {
+	BrandID int    `json:"brand_id"`
+	Name    string `json:"name,omitempty"`
+	Slug    string `json:"slug,omitempty"`
+	Count   int    `json:"count"`
+}
+
+// BrandGroup lists the compared products of one brand
+type BrandGroup struct {
+	BrandID    int    `json:"brand_id"`
+	Name       string `json:"name,omitempty"`
+	ProductIDs []int  `json:"product_ids"`
+}
+
+// WithBrands enables the brand endpoints and checks that products link to
+// brands of the store
+func (h *ProductHandler) WithBrands(brands *repositories.BrandStore) *ProductHandler {
+	h.brands = brands
+	return h
+}
+
+// brandSet holds the IDs of the existing brands. A nil set accepts every
+// brand, for handlers without a brand store.
+type brandSet map[int]bool
+
+// loadBrandSet reads the brand IDs products may link to
+func (h *ProductHandler) loadBrandSet() (brandSet, error) {
+	if h.brands == nil {
+		return nil, nil
+	}
+	brands, err := h.brands.List()
+	if err != nil {
+		return nil, err
+	}
+	set := make(brandSet, len(brands))
+	for _, b := range brands {
+		set[b.ID] = true
+	}
+	return set, nil
+}
+
+// checkBrand reports a product linked to a brand that does not exist. It is
+// called inside product transactions, which a brand is deleted in too, so
+// the brand cannot go between the check and the save.
+func (h *ProductHandler) checkBrand(p models.Product) error {
+	brands, err := h.loadBrandSet()
+	if err != nil {
+		return storageError(err, ErrFailedToLoad)
+	}
+	if verr := brands.check(p); verr != nil {
+		return verr
+	}
+	return nil
+}
+
+// check reports a product linked to a brand that does not exist
+func (s brandSet) check(p models.Product) *ValidationError {
+	if s == nil || p.BrandID == 0 || s[p.BrandID] {
+		return nil
+	}
+	return &ValidationError{Fields: []FieldError{{"brand_id", CodeInvalid, "must be the ID of an existing brand"}}}
+}
+
+// ListBrands returns every brand, ordered by name
+func (h *ProductHandler) ListBrands(c *gin.Context) {
+	brands, err := h.brands.List()
+	if err != nil {
+		HandleError(c, storageError(err, ErrFailedToLoad))
+		return
+	}
+	c.JSON(http.StatusOK, brands)
+}
+
+// CreateBrand adds a brand. Without a slug one is derived from the name.
+func (h *ProductHandler) CreateBrand(c *gin.Context) {
+	var brand models.Brand
+	if !bindValid(c, &brand) {
+		return
+	}
+
+	brand, err := h.brands.Create(brand)
+	if err != nil {
+		HandleError(c, brandError(err, ErrFailedToSave))
+		return
+	}
+	c.JSON(http.StatusCreated, brand)
+}
+
+// GetBrand retrieves a brand by ID or slug
+func (h *ProductHandler) GetBrand(c *gin.Context) {
+	brand, herr := h.findBrand(c)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+	c.JSON(http.StatusOK, brand)
+}
+
+// UpdateBrand replaces a brand, found by ID or slug
+func (h *ProductHandler) UpdateBrand(c *gin.Context) {
+	current, herr := h.findBrand(c)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+
+	var brand models.Brand
+	if !bindValid(c, &brand) {
+		return
+	}
+
+	brand, err := h.brands.Replace(current.ID, brand)
+	if err != nil {
+		HandleError(c, brandError(err, ErrFailedToSave))
+		return
+	}
+	c.JSON(http.StatusOK, brand)
+}
+
+// DeleteBrand removes a brand no product links to any more, trashed ones
+// included. The check and the delete run inside a product transaction, so no
+// product can be linked to the brand in between.
+func (h *ProductHandler) DeleteBrand(c *gin.Context) {
+	brand, herr := h.findBrand(c)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+
+	err := h.repo.Update(func(tx *repositories.ProductTx) error {
+		for _, p := range tx.Products {
+			if p.BrandID == brand.ID {
+				return ErrBrandInUse
+			}
+		}
+		if err := h.brands.Delete(brand.ID); err != nil {
+			return brandError(err, ErrFailedToSave)
+		}
+		return nil
+	})
+	if err != nil {
+		HandleError(c, updateError(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// BrandStats returns the product count, average rating, price ranges and
+// categories of a brand. Prices are converted to ?currency= if given, which
+// yields a single range.
+func (h *ProductHandler) BrandStats(c *gin.Context) {
+	brand, herr := h.findBrand(c)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+	convert, herr := h.priceConverter(c)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+
+	products, err := h.queryProducts(repositories.ProductFilter{BrandID: brand.ID, Limit: -1})
+	if err != nil {
+		HandleError(c, storageError(err, ErrFailedToLoad))
+		return
+	}
+
+	stats := BrandStats{Brand: brand, ProductCount: len(products), PriceRanges: make([]PriceRange, 0)}
+	ratingTotal := 0.0
+	ranges := make(map[string]*PriceRange)
+	var currencies []string
+	for _, p := range products {
+		ratingTotal += p.Rating * float64(p.ReviewCount)
+		stats.ReviewCount += p.ReviewCount
+
+		price := p.Price
+		if price.IsZero() {
+			continue
+		}
+		if convert != nil {
+			if price, herr = convert(price); herr != nil {
+				HandleError(c, herr)
+				return
+			}
+		}
+		r, ok := ranges[price.Currency]
+		if !ok {
+			ranges[price.Currency] = &PriceRange{Min: price, Max: price}
+			currencies = append(currencies, price.Currency)
+			continue
+		}
+		if price.Amount.Cmp(r.Min.Amount) < 0 {
+			r.Min = price
+		}
+		if price.Amount.Cmp(r.Max.Amount) > 0 {
+			r.Max = price
+		}
+	}
+	if stats.ReviewCount > 0 {
+		stats.AverageRating = math.Round(ratingTotal/float64(stats.ReviewCount)*100) / 100
+	}
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		stats.PriceRanges = append(stats.PriceRanges, *ranges[currency])
+	}
+	stats.Categories = categoryFacets(products)
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// ProductFacets counts the products matching the listing filters, without
+// pagination, by category and brand
+func (h *ProductHandler) ProductFacets(c *gin.Context) {
+	filter, herr := parseProductFilter(c)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+	filter.Sort = ""
+	filter.Limit = -1
+	filter.Offset = 0
+
+	products, err := h.queryProducts(filter)
+	if err != nil {
+		HandleError(c, storageError(err, ErrFailedToLoad))
+		return
+	}
+	names, err := h.brandsByID()
+	if err != nil {
+		HandleError(c, storageError(err, ErrFailedToLoad))
+		return
+	}
+
+	facets := Facets{Total: len(products), Categories: categoryFacets(products), Brands: make([]BrandFacet, 0)}
+	counts := make(map[int]int)
+	for _, p := range products {
+		if p.BrandID == 0 {
+			facets.Unbranded++
+		} else {
+			counts[p.BrandID]++
+		}
+	}
+	for id, count := range counts {
+		brand := names[id]
+		facets.Brands = append(facets.Brands, BrandFacet{BrandID: id, Name: brand.Name, Slug: brand.Slug, Count: count})
+	}
+	sort.Slice(facets.Brands, func(i, j int) bool {
+		a, b := facets.Brands[i], facets.Brands[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.BrandID < b.BrandID
+	})
+
+	c.JSON(http.StatusOK, facets)
+}
+
+// addBrandGroups groups the compared products by brand, in the order the
+// brands first appear. Comparisons without branded products have no groups.
+func (h *ProductHandler) addBrandGroups(comparison *Comparison) *Error {
+	names, err := h.brandsByID()
+	if err != nil {
+		return storageError(err, ErrFailedToLoad)
+	}
+
+	index := make(map[int]int)
+	branded := false
+	for _, p := range comparison.Products {
+		branded = branded || p.BrandID != 0
+		i, ok := index[p.BrandID]
+		if !ok {
+			i = len(comparison.Brands)
+			index[p.BrandID] = i
+			comparison.Brands = append(comparison.Brands, BrandGroup{BrandID: p.BrandID, Name: names[p.BrandID].Name})
+		}
+		comparison.Brands[i].ProductIDs = append(comparison.Brands[i].ProductIDs, p.ID)
+	}
+	if !branded {
+		comparison.Brands = nil
+	}
+	return nil
+}
+
+// brandsByID returns the brands by ID, or nil without a brand store
+func (h *ProductHandler) brandsByID() (map[int]models.Brand, error) {
+	if h.brands == nil {
+		return nil, nil
+	}
+	brands, err := h.brands.List()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]models.Brand, len(brands))
+	for _, b := range brands {
+		byID[b.ID] = b
+	}
+	return byID, nil
+}
+
+// findBrand loads the brand of the :brand parameter, an ID or a slug
+func (h *ProductHandler) findBrand(c *gin.Context) (models.Brand, *Error) {
+	param := c.Param("brand")
+	var (
+		brand models.Brand
+		err   error
+	)
+	if id, convErr := strconv.Atoi(param); convErr == nil {
+		brand, err = h.brands.Get(id)
+	} else {
+		brand, err = h.brands.BySlug(param)
+	}
+	if err != nil {
+		return models.Brand{}, brandError(err, ErrFailedToLoad)
+	}
+	return brand, nil
+}
+
+// brandError turns an error of the brand store into a response
+func brandError(err error, fallback *Error) *Error {
+	switch {
+	case errors.Is(err, repositories.ErrBrandNotFound):
+		return ErrBrandNotFound
+	case errors.Is(err, repositories.ErrDuplicateSlug):
+		return ErrDuplicateBrandSlug
+	}
+	return storageError(err, fallback)
+}
+
+// categoryFacets counts products by category, most common first. Products
+// without a category are left out.
+func categoryFacets(products []models.Product) []FacetValue {
+	counts := make(map[string]int)
+	for _, p := range products {
+		if p.Category != "" {
+			counts[p.Category]++
+		}
+	}
+
+	facets := make([]FacetValue, 0, len(counts))
+	for value, count := range counts {
+		facets = append(facets, FacetValue{Value: value, Count: count})
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Value < facets[j].Value
+	})
+	return facets
+}
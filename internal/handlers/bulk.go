@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"item-comparison-ai-api/internal/jsonpatch"
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Bulk modes
+const (
+	// BulkAtomic saves the operations only when every one of them succeeds
+	BulkAtomic = "atomic"
+	// BulkBestEffort saves the operations that succeed and reports the others
+	BulkBestEffort = "best_effort"
+)
+
+// maxBulkOperations caps the size of a single bulk request
+const maxBulkOperations = 10000
+
+// BulkRequest is the body of POST /products/bulk
+type BulkRequest struct {
+	Mode       string          `json:"mode"`
+	Operations []BulkOperation `json:"operations"`
+}
+
+// BulkOperation is a single create, update, patch or delete. Product holds
+// the new product of a create or update and IfMatch an optional ETag
+// precondition on the current product. Patch is a JSON Merge Patch object or
+// a JSON Patch array.
+type BulkOperation struct {
+	Op      string          `json:"op"`
+	ID      int             `json:"id,omitempty"`
+	IfMatch string          `json:"if_match,omitempty"`
+	Product *models.Product `json:"product,omitempty"`
+	Patch   json.RawMessage `json:"patch,omitempty"`
+}
+
+// BulkResult reports the outcome of the operation at Index
+type BulkResult struct {
+	Index   int             `json:"index"`
+	Op      string          `json:"op"`
+	Status  int             `json:"status"`
+	ID      int             `json:"id,omitempty"`
+	Product *models.Product `json:"product,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Fields  []FieldError    `json:"fields,omitempty"`
+}
+
+// errBulkFailed aborts an atomic bulk update after an operation failed
+var errBulkFailed = errors.New("bulk operation failed")
+
+// BulkProducts applies a list of operations to the catalog with a single
+// repository save
+func (h *ProductHandler) BulkProducts(c *gin.Context) {
+	var req BulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		HandleError(c, ErrBindJSON)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = BulkAtomic
+	}
+	if req.Mode != BulkAtomic && req.Mode != BulkBestEffort {
+		HandleError(c, ErrInvalidBulkMode)
+		return
+	}
+	if len(req.Operations) == 0 {
+		HandleError(c, ErrEmptyBulk)
+		return
+	}
+	if len(req.Operations) > maxBulkOperations {
+		HandleError(c, ErrBulkTooLarge)
+		return
+	}
+
+	var results []BulkResult
+	err := h.update(c, repositories.RevisionUpdate, func(tx *repositories.ProductTx) error {
+		brands, err := h.loadBrandSet()
+		if err != nil {
+			return storageError(err, ErrFailedToLoad)
+		}
+		results = make([]BulkResult, len(req.Operations))
+		failed := false
+
+		for i, op := range req.Operations {
+			result, err := h.applyBulkOperation(tx, op, brands)
+			result.Index = i
+			result.Op = op.Op
+			if err != nil {
+				result.Status, result.Error, result.Fields = errorDetails(err)
+				failed = true
+			}
+			results[i] = result
+		}
+
+		if failed && req.Mode == BulkAtomic {
+			return errBulkFailed
+		}
+		return nil
+	})
+
+	if errors.Is(err, errBulkFailed) {
+		c.JSON(ErrBulkRolledBack.Code, gin.H{
+			"error":     ErrBulkRolledBack.Message,
+			"committed": false,
+			"results":   results,
+		})
+		return
+	}
+	if err != nil {
+		HandleError(c, updateError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"committed": true,
+		"results":   results,
+	})
+}
+
+// applyBulkOperation applies op to tx. Operations that fail leave tx as it was.
+func (h *ProductHandler) applyBulkOperation(tx *repositories.ProductTx, op BulkOperation, brands brandSet) (BulkResult, error) {
+	if op.Op == "create" {
+		if op.Product == nil {
+			return BulkResult{}, ErrBindJSON
+		}
+		p := *op.Product
+		p.DeletedAt = nil
+		if verr := validateProduct(p); verr != nil {
+			return BulkResult{}, verr
+		}
+		if verr := brands.check(p); verr != nil {
+			return BulkResult{}, verr
+		}
+		if err := tx.CheckConstraints(p); err != nil {
+			return BulkResult{}, err
+		}
+		p = tx.Insert(p)
+		return BulkResult{Status: http.StatusCreated, ID: p.ID, Product: &p}, nil
+	}
+
+	current, found := tx.Get(op.ID)
+	switch op.Op {
+	case "update", "patch", "delete":
+		if !found || current.IsDeleted() {
+			return BulkResult{ID: op.ID}, ErrNotFound
+		}
+		if herr := h.matchETag(op.IfMatch, current); herr != nil {
+			return BulkResult{ID: op.ID}, herr
+		}
+	default:
+		return BulkResult{ID: op.ID}, ErrInvalidBulkOperation
+	}
+
+	var (
+		p      models.Product
+		action string
+		status = http.StatusOK
+	)
+	switch op.Op {
+	case "update":
+		if op.Product == nil {
+			return BulkResult{ID: op.ID}, ErrBindJSON
+		}
+		p = *op.Product
+		p.ID = op.ID
+		p.DeletedAt = nil
+		if verr := validateProduct(p); verr != nil {
+			return BulkResult{ID: op.ID}, verr
+		}
+		action = repositories.RevisionUpdate
+	case "patch":
+		mediaType := jsonpatch.MergePatchType
+		if trimmed := bytes.TrimSpace(op.Patch); len(trimmed) > 0 && trimmed[0] == '[' {
+			mediaType = jsonpatch.JSONPatchType
+		}
+		var err error
+		if p, err = patchProduct(current, mediaType, op.Patch); err != nil {
+			return BulkResult{ID: op.ID}, err
+		}
+		action = repositories.RevisionPatch
+	case "delete":
+		p = current
+		now := time.Now().UTC()
+		p.DeletedAt = &now
+		action = repositories.RevisionDelete
+		status = http.StatusNoContent
+	}
+
+	if verr := brands.check(p); verr != nil {
+		return BulkResult{ID: op.ID}, verr
+	}
+	if err := tx.CheckConstraints(p); err != nil {
+		return BulkResult{ID: op.ID}, err
+	}
+	tx.SetAction(action)
+	p, _ = tx.Replace(p)
+
+	result := BulkResult{Status: status, ID: op.ID}
+	if op.Op != "delete" {
+		result.Product = &p
+	}
+	return result, nil
+}
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 
+	"item-comparison-ai-api/internal/database"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -20,18 +23,89 @@ func NewError(code int, message string) *Error {
 	}
 }
 
+// Error lets handlers return an *Error from inside a repository update
+func (e *Error) Error() string {
+	return e.Message
+}
+
 // Error messages
 var (
-	ErrInvalidID       = NewError(http.StatusBadRequest, "Invalid ID")
-	ErrFailedToLoad   = NewError(http.StatusInternalServerError, "Failed to load")
-	ErrNotFound        = NewError(http.StatusNotFound, "Not found")
+	ErrInvalidID              = NewError(http.StatusBadRequest, "Invalid ID")
+	ErrFailedToLoad           = NewError(http.StatusInternalServerError, "Failed to load")
+	ErrNotFound               = NewError(http.StatusNotFound, "Not found")
 	ErrInvalidLimitParameter  = NewError(http.StatusBadRequest, "Invalid limit parameter")
 	ErrInvalidOffsetParameter = NewError(http.StatusBadRequest, "Invalid offset parameter")
-	ErrFailedToSave   = NewError(http.StatusInternalServerError, "Failed to save")
+	ErrInvalidSortParameter   = NewError(http.StatusBadRequest, "Invalid sort parameter")
+	ErrFailedToSave           = NewError(http.StatusInternalServerError, "Failed to save")
 	ErrBindJSON               = NewError(http.StatusBadRequest, "Invalid request body")
+	ErrPreconditionFailed     = NewError(http.StatusPreconditionFailed, "Product was modified, If-Match does not match the current ETag")
+	ErrPreconditionRequired   = NewError(http.StatusPreconditionRequired, "If-Match header is required")
+	ErrStorageBusy            = NewError(http.StatusServiceUnavailable, "Storage is locked by another process, retry later")
+	ErrSnapshotNotFound       = NewError(http.StatusNotFound, "Snapshot not found")
+	ErrSnapshotExists         = NewError(http.StatusConflict, "Snapshot already exists")
+	ErrInvalidSnapshotName    = NewError(http.StatusBadRequest, "Invalid snapshot name")
+	ErrNotInTrash             = NewError(http.StatusNotFound, "Product is not in the trash")
+	ErrInvalidOlderThan       = NewError(http.StatusBadRequest, "Invalid older_than parameter")
+	ErrInvalidRevision        = NewError(http.StatusBadRequest, "Invalid revision")
+	ErrRevisionNotFound       = NewError(http.StatusNotFound, "Revision not found")
+	ErrRevisionLineage        = NewError(http.StatusConflict, "Revision belongs to an earlier product with this ID")
+	ErrInvalidPatch           = NewError(http.StatusBadRequest, "Invalid patch")
+	ErrPatchTestFailed        = NewError(http.StatusConflict, "Patch test operation failed")
+	ErrUnsupportedPatchType   = NewError(http.StatusUnsupportedMediaType, "Unsupported patch type, use application/merge-patch+json or application/json-patch+json")
+	ErrInvalidBulkMode        = NewError(http.StatusBadRequest, "Invalid bulk mode, use atomic or best_effort")
+	ErrInvalidBulkOperation   = NewError(http.StatusBadRequest, "Invalid bulk operation, use create, update, patch or delete")
+	ErrEmptyBulk              = NewError(http.StatusBadRequest, "Bulk request has no operations")
+	ErrBulkTooLarge           = NewError(http.StatusRequestEntityTooLarge, "Bulk request has too many operations")
+	ErrBulkRolledBack         = NewError(http.StatusUnprocessableEntity, "Bulk request rolled back, an operation failed")
+	ErrValidation             = NewError(http.StatusBadRequest, "Validation failed")
+	ErrInvalidFormat          = NewError(http.StatusBadRequest, "Invalid format, use csv or jsonl")
+	ErrInvalidImportMode      = NewError(http.StatusBadRequest, "Invalid import mode, use upsert or append")
+	ErrInvalidImportFile      = NewError(http.StatusBadRequest, "Invalid import file")
+	ErrImportFailed           = NewError(http.StatusUnprocessableEntity, "Import rejected, some rows are invalid")
+	ErrInvalidIdempotencyKey  = NewError(http.StatusBadRequest, "Idempotency-Key must be at most 255 characters")
+	ErrIdempotencyKeyReused   = NewError(http.StatusUnprocessableEntity, "Idempotency-Key was already used for a different request")
+	ErrIdempotencyKeyInUse    = NewError(http.StatusConflict, "A request with this Idempotency-Key is still in progress")
+	ErrDuplicateIdentifier    = NewError(http.StatusConflict, "Duplicate product identifier")
+	ErrInvalidVariant         = NewError(http.StatusConflict, "Invalid product variant")
+	ErrInvalidVariantsParam   = NewError(http.StatusBadRequest, "Invalid variants parameter, use collapse")
+	ErrInvalidCompareIDs      = NewError(http.StatusBadRequest, "Invalid ids parameter, give 2 to 50 comma separated product IDs")
+	ErrInvalidCurrency        = NewError(http.StatusBadRequest, "Invalid currency parameter, use an ISO 4217 code")
+	ErrNoExchangeRate         = NewError(http.StatusUnprocessableEntity, "Price cannot be converted")
+	ErrInvalidExchangeRates   = NewError(http.StatusBadRequest, "Invalid exchange rates")
+	ErrInvalidSince           = NewError(http.StatusBadRequest, "Invalid since parameter, use an RFC 3339 timestamp or a duration")
+	ErrInvalidMinPercent      = NewError(http.StatusBadRequest, "Invalid min_pct parameter, use a number from 0 to 100")
+	ErrInvalidReviewStatus    = NewError(http.StatusBadRequest, "Invalid review status, use pending, approved or rejected")
+	ErrReviewNotFound         = NewError(http.StatusNotFound, "Review not found")
+	ErrInvalidImageUpload     = NewError(http.StatusBadRequest, "Invalid image upload, send the file in the image field of a multipart form")
+	ErrImageTooLarge          = NewError(http.StatusRequestEntityTooLarge, "Image is too large")
+	ErrUnsupportedImageType   = NewError(http.StatusUnsupportedMediaType, "Unsupported image type, use JPEG, PNG or GIF")
+	ErrInvalidImage           = NewError(http.StatusBadRequest, "Invalid image")
+	ErrImageNotFound          = NewError(http.StatusNotFound, "Image not found")
+	ErrUnsupportedLocale      = NewError(http.StatusBadRequest, "Unsupported locale parameter")
+	ErrInvalidBrandID         = NewError(http.StatusBadRequest, "Invalid brand_id parameter")
+	ErrBrandNotFound          = NewError(http.StatusNotFound, "Brand not found")
+	ErrDuplicateBrandSlug     = NewError(http.StatusConflict, "Brand slug already in use")
+	ErrBrandInUse             = NewError(http.StatusConflict, "Brand still has products")
 )
 
+// withDetail returns a copy of e whose message ends with detail
+func (e *Error) withDetail(detail string) *Error {
+	return NewError(e.Code, e.Message+": "+detail)
+}
+
 // HandleError sends an error response.
 func HandleError(c *gin.Context, err *Error) {
+	if err.Code == http.StatusServiceUnavailable {
+		c.Header("Retry-After", "1")
+	}
 	c.JSON(err.Code, gin.H{"error": err.Message})
 }
+
+// storageError maps a repository failure to a response, telling lock
+// contention apart from other failures
+func storageError(err error, fallback *Error) *Error {
+	if errors.Is(err, database.ErrLockTimeout) {
+		return ErrStorageBusy
+	}
+	return fallback
+}
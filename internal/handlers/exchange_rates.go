@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/money"
+	"item-comparison-ai-api/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExchangeRateHandler lets administrators read and replace the exchange-rate
+// table
+type ExchangeRateHandler struct {
+	rates *repositories.ExchangeRateStore
+}
+
+// NewExchangeRateHandler creates a new ExchangeRateHandler
+func NewExchangeRateHandler(rates *repositories.ExchangeRateStore) *ExchangeRateHandler {
+	return &ExchangeRateHandler{rates: rates}
+}
+
+// GetExchangeRates returns the current exchange-rate table
+func (h *ExchangeRateHandler) GetExchangeRates(c *gin.Context) {
+	rates, err := h.rates.Load()
+	if err != nil {
+		HandleError(c, storageError(err, ErrFailedToLoad))
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// UpdateExchangeRates replaces the exchange-rate table. Without updated_at
+// the rates are taken as of now.
+func (h *ExchangeRateHandler) UpdateExchangeRates(c *gin.Context) {
+	var rates money.Rates
+	if err := c.ShouldBindJSON(&rates); err != nil {
+		HandleError(c, ErrBindJSON)
+		return
+	}
+	if rates.UpdatedAt.IsZero() {
+		rates.UpdatedAt = time.Now().UTC()
+	}
+	if err := rates.Validate(); err != nil {
+		HandleError(c, ErrInvalidExchangeRates.withDetail(err.Error()))
+		return
+	}
+
+	if err := h.rates.Save(rates); err != nil {
+		HandleError(c, storageError(err, ErrFailedToSave))
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// WithExchangeRates enables ?currency= on reads, converting prices with the
+// rates of the store
+func (h *ProductHandler) WithExchangeRates(rates *repositories.ExchangeRateStore) *ProductHandler {
+	h.rates = rates
+	return h
+}
+
+// presentProducts adapts products in place to what a read asked for: prices
+// converted to ?currency= and texts translated into the requested locale
+func (h *ProductHandler) presentProducts(c *gin.Context, products []models.Product) *Error {
+	if herr := h.convertPrices(c, products); herr != nil {
+		return herr
+	}
+	return h.localize(c, products)
+}
+
+// convertPrices converts the prices of products in place to the currency
+// asked for with ?currency=, if any. Every converted price states the rate
+// it was converted with.
+func (h *ProductHandler) convertPrices(c *gin.Context, products []models.Product) *Error {
+	convert, herr := h.priceConverter(c)
+	if herr != nil || convert == nil {
+		return herr
+	}
+
+	for i := range products {
+		if products[i].Price, herr = convert(products[i].Price); herr != nil {
+			return herr
+		}
+	}
+	return nil
+}
+
+// priceConverter returns the function converting prices to the currency
+// asked for with ?currency=, or nil when none was asked for
+func (h *ProductHandler) priceConverter(c *gin.Context) (func(money.Money) (money.Money, *Error), *Error) {
+	currency := c.Query("currency")
+	if currency == "" {
+		return nil, nil
+	}
+	if !money.ValidCurrency(currency) {
+		return nil, ErrInvalidCurrency
+	}
+
+	rates, herr := h.loadRates()
+	if herr != nil {
+		return nil, herr
+	}
+
+	return func(m money.Money) (money.Money, *Error) {
+		return convertPrice(rates, m, currency)
+	}, nil
+}
+
+// loadRates reads the exchange-rate table; without a rate store only the
+// default currency is known
+func (h *ProductHandler) loadRates() (money.Rates, *Error) {
+	if h.rates == nil {
+		return money.Rates{Base: money.DefaultCurrency}, nil
+	}
+	rates, err := h.rates.Load()
+	if err != nil {
+		return money.Rates{}, storageError(err, ErrFailedToLoad)
+	}
+	return rates, nil
+}
+
+// convertPrice expresses m in currency with rates, reporting a missing rate
+// as a response
+func convertPrice(rates money.Rates, m money.Money, currency string) (money.Money, *Error) {
+	converted, err := rates.Convert(m, currency)
+	if errors.Is(err, money.ErrNoRate) {
+		return money.Money{}, ErrNoExchangeRate.withDetail(err.Error())
+	}
+	if err != nil {
+		return money.Money{}, ErrFailedToLoad
+	}
+	return converted, nil
+}
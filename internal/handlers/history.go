@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ActorHeader names the header identifying who made a change; it is stored
+// with the revision
+const ActorHeader = "X-Actor"
+
+// WithHistory records a revision for every product mutation in history
+func (h *ProductHandler) WithHistory(history *repositories.ProductHistory) *ProductHandler {
+	h.history = history
+	return h
+}
+
+// update runs fn in a repository update, with action naming the revisions
+// of the changes it makes. The revisions and price points are stored while
+// the catalog is still locked, so they follow the order of the commits, and
+// a change whose records cannot be stored is not saved.
+func (h *ProductHandler) update(c *gin.Context, action string, fn func(tx *repositories.ProductTx) error) error {
+	return h.repo.Update(func(tx *repositories.ProductTx) error {
+		tx.SetAction(action)
+		if err := fn(tx); err != nil {
+			return err
+		}
+		// Records are only stored for a catalog the repository will accept
+		if err := tx.Check(); err != nil {
+			return err
+		}
+		return h.recordChanges(c.GetHeader(ActorHeader), tx.Changes())
+	})
+}
+
+// recordChanges stores the revisions and price points of changes
+func (h *ProductHandler) recordChanges(actor string, changes []repositories.Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	if h.prices != nil {
+		if err := h.prices.RecordAll(changes); err != nil {
+			return err
+		}
+	}
+	if h.history != nil {
+		if _, err := h.history.RecordAll(actor, changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetHistory lists the revisions of a product, oldest first
+func (h *ProductHandler) GetHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		HandleError(c, ErrInvalidID)
+		return
+	}
+
+	revisions, err := h.history.List(id)
+	if err != nil {
+		HandleError(c, storageError(err, ErrFailedToLoad))
+		return
+	}
+	if len(revisions) == 0 {
+		HandleError(c, ErrNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// GetRevision returns a single revision of a product
+func (h *ProductHandler) GetRevision(c *gin.Context) {
+	id, rev, herr := parseRevisionParams(c)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+
+	revision, err := h.history.Get(id, rev)
+	if err != nil {
+		HandleError(c, revisionError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+// RollbackProduct brings a product back to its state at a revision. The
+// rollback is itself recorded as a new revision. The restored state goes
+// through the same checks as an update, and only revisions of the current
+// product, since its latest creation, can be restored.
+func (h *ProductHandler) RollbackProduct(c *gin.Context) {
+	id, rev, herr := parseRevisionParams(c)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+
+	var target models.Product
+	err := h.update(c, repositories.RevisionRollback, func(tx *repositories.ProductTx) error {
+		revisions, err := h.history.List(id)
+		if err != nil {
+			return err
+		}
+		revision, found := findRevision(revisions, rev)
+		if !found {
+			return ErrRevisionNotFound
+		}
+
+		current, found := tx.Get(id)
+		if !found {
+			return ErrNotFound
+		}
+		if rev < lineageStart(revisions) {
+			return ErrRevisionLineage
+		}
+		if herr := h.checkIfMatch(c, current); herr != nil {
+			return herr
+		}
+
+		target = *revision.Product
+		target.ID = id
+		if verr := validateProduct(target); verr != nil {
+			return verr
+		}
+		if err := h.checkBrand(target); err != nil {
+			return err
+		}
+
+		target, _ = tx.Replace(target)
+		return nil
+	})
+	if err != nil {
+		respondUpdateError(c, err)
+		return
+	}
+
+	c.Header("ETag", target.ETag())
+	c.JSON(http.StatusOK, target)
+}
+
+// findRevision returns the revision with the given number
+func findRevision(revisions []repositories.Revision, rev int) (repositories.Revision, bool) {
+	for _, r := range revisions {
+		if r.Rev == rev {
+			return r, true
+		}
+	}
+	return repositories.Revision{}, false
+}
+
+// lineageStart returns the number of the latest creation among revisions.
+// Older revisions belong to an earlier product that had the same ID.
+func lineageStart(revisions []repositories.Revision) int {
+	start := 0
+	for _, r := range revisions {
+		if r.Action == repositories.RevisionCreate && r.Rev > start {
+			start = r.Rev
+		}
+	}
+	return start
+}
+
+func parseRevisionParams(c *gin.Context) (id, rev int, herr *Error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return 0, 0, ErrInvalidID
+	}
+
+	rev, err = strconv.Atoi(c.Param("rev"))
+	if err != nil || rev < 1 {
+		return 0, 0, ErrInvalidRevision
+	}
+
+	return id, rev, nil
+}
+
+func revisionError(err error) *Error {
+	if errors.Is(err, repositories.ErrRevisionNotFound) {
+		return ErrRevisionNotFound
+	}
+	return storageError(err, ErrFailedToLoad)
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"item-comparison-ai-api/internal/blobstore"
+	"item-comparison-ai-api/internal/imaging"
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ImagesPath is the path uploaded images are served under
+	ImagesPath = "/images"
+	// DefaultMaxImageSize is the upload limit used when none is configured
+	DefaultMaxImageSize = 10 << 20
+	// maxImagePixels bounds the dimensions of an upload, width times height.
+	// The decoded image is held as RGBA, 4 bytes a pixel, while thumbnails
+	// are made.
+	maxImagePixels = 16_000_000
+)
+
+// imageThumbnails are the sizes thumbnails are generated in, named by the
+// square they fit in
+var imageThumbnails = []struct {
+	name string
+	box  int
+}{
+	{"small", 160},
+	{"medium", 480},
+}
+
+// WithImages enables the image endpoints, keeping the uploads in store and
+// rejecting files larger than maxSize bytes
+func (h *ProductHandler) WithImages(store blobstore.Store, maxSize int64) *ProductHandler {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxImageSize
+	}
+	h.images = store
+	h.maxImageSize = maxSize
+	return h
+}
+
+// UploadImage adds the image sent as the "image" field of a multipart form to
+// the gallery of a product. The type is sniffed from the content, and
+// thumbnails are generated before the product is updated.
+func (h *ProductHandler) UploadImage(c *gin.Context) {
+	id, herr := h.liveProductID(c)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+
+	// The form may carry a little more than the file itself
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxImageSize+1<<20)
+	file, header, err := c.Request.FormFile("image")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			HandleError(c, ErrImageTooLarge)
+			return
+		}
+		HandleError(c, ErrInvalidImageUpload)
+		return
+	}
+	defer file.Close()
+	if header.Size > h.maxImageSize {
+		HandleError(c, ErrImageTooLarge)
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, h.maxImageSize+1))
+	if err != nil {
+		HandleError(c, ErrInvalidImageUpload)
+		return
+	}
+	if int64(len(data)) > h.maxImageSize {
+		HandleError(c, ErrImageTooLarge)
+		return
+	}
+
+	contentType, ext, err := imaging.Sniff(data)
+	if err != nil {
+		HandleError(c, ErrUnsupportedImageType)
+		return
+	}
+	img, err := imaging.Decode(data, maxImagePixels)
+	if errors.Is(err, imaging.ErrTooManyPixels) {
+		HandleError(c, ErrImageTooLarge)
+		return
+	}
+	if err != nil {
+		HandleError(c, ErrInvalidImage.withDetail(err.Error()))
+		return
+	}
+
+	imageID, err := newImageID()
+	if err != nil {
+		HandleError(c, ErrFailedToSave)
+		return
+	}
+	base := fmt.Sprintf("products/%d/%s", id, imageID)
+	image := models.Image{
+		ID:          imageID,
+		URL:         imageURL(base + ext),
+		ContentType: contentType,
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+		Size:        int64(len(data)),
+		Thumbnails:  make([]models.Thumbnail, 0, len(imageThumbnails)),
+		UploadedAt:  time.Now().UTC(),
+	}
+
+	keys := []string{base + ext}
+	if err := h.images.Put(keys[0], bytes.NewReader(data)); err != nil {
+		HandleError(c, ErrFailedToSave)
+		return
+	}
+	thumbType, thumbExt := imaging.ThumbnailType(contentType)
+	src := imaging.RGBA(img)
+	for _, size := range imageThumbnails {
+		thumb := imaging.Thumbnail(src, size.box)
+		var buf bytes.Buffer
+		err := imaging.Encode(&buf, thumb, thumbType)
+		key := base + "-" + size.name + thumbExt
+		if err == nil {
+			err = h.images.Put(key, &buf)
+		}
+		if err != nil {
+			h.deleteBlobs(keys)
+			HandleError(c, ErrFailedToSave)
+			return
+		}
+		keys = append(keys, key)
+		image.Thumbnails = append(image.Thumbnails, models.Thumbnail{
+			Name:   size.name,
+			URL:    imageURL(key),
+			Width:  thumb.Bounds().Dx(),
+			Height: thumb.Bounds().Dy(),
+		})
+	}
+
+	var after models.Product
+	err = h.update(c, repositories.RevisionUpdate, func(tx *repositories.ProductTx) error {
+		p, found := tx.Get(id)
+		if !found || p.IsDeleted() {
+			return ErrNotFound
+		}
+		if herr := h.checkIfMatch(c, p); herr != nil {
+			return herr
+		}
+
+		images := append(append([]models.Image(nil), p.Images...), image)
+		after, _ = tx.SetImages(id, images)
+		return nil
+	})
+	if err != nil {
+		h.deleteBlobs(keys)
+		HandleError(c, updateError(err))
+		return
+	}
+
+	c.Header("ETag", after.ETag())
+	c.JSON(http.StatusCreated, image)
+}
+
+// DeleteImage removes an image from the gallery of a product along with its
+// thumbnails
+func (h *ProductHandler) DeleteImage(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		HandleError(c, ErrInvalidID)
+		return
+	}
+	imageID := c.Param("image_id")
+
+	var removed models.Image
+	err = h.update(c, repositories.RevisionUpdate, func(tx *repositories.ProductTx) error {
+		p, found := tx.Get(id)
+		if !found || p.IsDeleted() {
+			return ErrNotFound
+		}
+		if herr := h.checkIfMatch(c, p); herr != nil {
+			return herr
+		}
+
+		images := make([]models.Image, 0, len(p.Images))
+		for _, img := range p.Images {
+			if img.ID == imageID {
+				removed = img
+			} else {
+				images = append(images, img)
+			}
+		}
+		if removed.ID == "" {
+			return ErrImageNotFound
+		}
+
+		tx.SetImages(id, images)
+		return nil
+	})
+	if err != nil {
+		HandleError(c, updateError(err))
+		return
+	}
+
+	keys := []string{imageKey(removed.URL)}
+	for _, thumb := range removed.Thumbnails {
+		keys = append(keys, imageKey(thumb.URL))
+	}
+	h.deleteBlobs(keys)
+
+	c.Status(http.StatusNoContent)
+}
+
+// ImageRecords deletes the uploaded images of purged products
+type ImageRecords struct {
+	Store blobstore.Store
+}
+
+// Purge removes the images and thumbnails of products. Failures only leave
+// unreferenced files behind, so they are ignored.
+func (r ImageRecords) Purge(products []models.Product) error {
+	for _, p := range products {
+		for _, img := range p.Images {
+			_ = r.Store.Delete(imageKey(img.URL))
+			for _, thumb := range img.Thumbnails {
+				_ = r.Store.Delete(imageKey(thumb.URL))
+			}
+		}
+	}
+	return nil
+}
+
+// ServeImage sends an uploaded image or thumbnail. Image keys are never
+// reused, so clients may cache them forever.
+func (h *ProductHandler) ServeImage(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	blob, err := h.images.Open(key)
+	if errors.Is(err, blobstore.ErrNotFound) || errors.Is(err, blobstore.ErrInvalidKey) {
+		HandleError(c, ErrImageNotFound)
+		return
+	}
+	if err != nil {
+		HandleError(c, ErrFailedToLoad)
+		return
+	}
+	defer blob.Close()
+
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	http.ServeContent(c.Writer, c.Request, path.Base(key), time.Time{}, blob)
+}
+
+// deleteBlobs removes stored images. Failures only leave unreferenced files
+// behind, so they are ignored.
+func (h *ProductHandler) deleteBlobs(keys []string) {
+	for _, key := range keys {
+		_ = h.images.Delete(key)
+	}
+}
+
+func newImageID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func imageURL(key string) string {
+	return ImagesPath + "/" + key
+}
+
+func imageKey(url string) string {
+	return strings.TrimPrefix(url, ImagesPath+"/")
+}
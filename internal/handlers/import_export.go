@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/productio"
+	"item-comparison-ai-api/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Import modes
+const (
+	// ImportUpsert replaces the products whose ID is given and creates only
+	// the rows without an ID. Like bulk updates, a row whose ID is unknown,
+	// trashed or purged fails instead of creating that product.
+	ImportUpsert = "upsert"
+	// ImportAppend creates every row as a new product, ignoring IDs
+	ImportAppend = "append"
+)
+
+const (
+	// maxImportSize bounds the body of an import request
+	maxImportSize = 32 << 20
+	// exportFlushEvery is how many rows an export buffers between flushes
+	exportFlushEvery = 500
+)
+
+// ImportReport summarises what an import did, or would do on a dry run
+type ImportReport struct {
+	DryRun  bool          `json:"dry_run"`
+	Mode    string        `json:"mode"`
+	Total   int           `json:"total"`
+	Created int           `json:"created"`
+	Updated int           `json:"updated"`
+	Failed  int           `json:"failed"`
+	Errors  []ImportError `json:"errors"`
+}
+
+// ImportError is the error of one row; Row is its line in the file
+type ImportError struct {
+	Row    int          `json:"row"`
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// errImportFailed discards an import in which a row failed
+var errImportFailed = errors.New("import failed")
+
+// ImportProducts loads products from a CSV or JSON Lines body. The file is
+// imported only when every row is valid; otherwise nothing is saved and the
+// row errors are reported. dry_run=true validates without saving.
+func (h *ProductHandler) ImportProducts(c *gin.Context) {
+	format := importFormat(c)
+	if format != productio.FormatCSV && format != productio.FormatJSONL {
+		HandleError(c, ErrInvalidFormat)
+		return
+	}
+
+	mode := c.DefaultQuery("mode", ImportUpsert)
+	if mode != ImportUpsert && mode != ImportAppend {
+		HandleError(c, ErrInvalidImportMode)
+		return
+	}
+	dryRun := c.Query("dry_run") == "true"
+
+	rows, err := productio.Read(format, http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize))
+	if err != nil {
+		HandleError(c, ErrInvalidImportFile.withDetail(err.Error()))
+		return
+	}
+
+	var report ImportReport
+	apply := func(tx *repositories.ProductTx) error {
+		brands, err := h.loadBrandSet()
+		if err != nil {
+			return storageError(err, ErrFailedToLoad)
+		}
+		report = importRows(tx, rows, mode, brands)
+		report.DryRun = dryRun
+		if report.Failed > 0 {
+			return errImportFailed
+		}
+		return nil
+	}
+
+	if dryRun {
+		products, err := h.repo.LoadProducts()
+		if err != nil {
+			HandleError(c, storageError(err, ErrFailedToLoad))
+			return
+		}
+		if err := apply(repositories.NewProductTx(products, h.repo.GetNextID)); err != nil && !errors.Is(err, errImportFailed) {
+			HandleError(c, updateError(err))
+			return
+		}
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	err = h.update(c, repositories.RevisionUpdate, apply)
+	if errors.Is(err, errImportFailed) {
+		c.JSON(ErrImportFailed.Code, gin.H{
+			"error":  ErrImportFailed.Message,
+			"report": report,
+		})
+		return
+	}
+	if err != nil {
+		HandleError(c, updateError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// importRows applies the decoded rows to tx, rejecting those linked to a
+// brand missing from brands
+func importRows(tx *repositories.ProductTx, rows []productio.Row, mode string, brands brandSet) ImportReport {
+	report := ImportReport{Mode: mode, Total: len(rows), Errors: make([]ImportError, 0)}
+
+	for _, row := range rows {
+		if row.Err == nil && row.HasID && row.Product.ID <= 0 {
+			row.Err = errors.New("id must be a positive integer")
+		}
+		if row.Err != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, ImportError{Row: row.Line, Error: row.Err.Error()})
+			continue
+		}
+
+		p := row.Product
+		p.DeletedAt = nil
+		verr := validateProduct(p)
+		if verr == nil {
+			verr = brands.check(p)
+		}
+		if verr != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, ImportError{Row: row.Line, Error: verr.Error(), Fields: verr.Fields})
+			continue
+		}
+		replace := mode == ImportUpsert && row.HasID
+		if !replace {
+			p.ID = 0
+		} else if current, found := tx.Get(p.ID); !found || current.IsDeleted() {
+			report.Failed++
+			report.Errors = append(report.Errors, ImportError{Row: row.Line, Error: fmt.Sprintf("product %d does not exist", p.ID)})
+			continue
+		}
+		if err := tx.CheckConstraints(p); err != nil {
+			report.Failed++
+			report.Errors = append(report.Errors, ImportError{Row: row.Line, Error: err.Error()})
+			continue
+		}
+
+		if replace {
+			tx.Replace(p)
+			report.Updated++
+			continue
+		}
+		tx.Insert(p)
+		report.Created++
+	}
+
+	return report
+}
+
+// importFormat reads the format from the query or the Content-Type header
+func importFormat(c *gin.Context) string {
+	if format := c.Query("format"); format != "" {
+		return format
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	switch mediaType {
+	case "text/csv":
+		return productio.FormatCSV
+	case "application/x-ndjson", "application/jsonl", "application/x-jsonlines":
+		return productio.FormatJSONL
+	}
+	return ""
+}
+
+// productWriter is implemented by the export writers of productio
+type productWriter interface {
+	Write(p models.Product) error
+}
+
+// ExportProducts streams the products selected by the listing query
+// parameters as CSV or JSON Lines. Without limit every product is exported.
+func (h *ProductHandler) ExportProducts(c *gin.Context) {
+	format := c.DefaultQuery("format", productio.FormatCSV)
+	if format != productio.FormatCSV && format != productio.FormatJSONL {
+		HandleError(c, ErrInvalidFormat)
+		return
+	}
+
+	filter, herr := parseProductFilter(c)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+	if c.Query("limit") == "" {
+		filter.Limit = -1
+	}
+
+	products, herr := h.listProducts(filter)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+
+	c.Header("Content-Type", productio.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="products.%s"`, format))
+	c.Status(http.StatusOK)
+
+	var (
+		writer productWriter
+		flush  = func() error { c.Writer.Flush(); return nil }
+	)
+	if format == productio.FormatCSV {
+		csvWriter, err := productio.NewCSVWriter(c.Writer, productio.SpecKeys(products), productio.TranslationColumns(products))
+		if err != nil {
+			logrus.WithError(err).Error("product export failed")
+			return
+		}
+		writer = csvWriter
+		flush = func() error {
+			if err := csvWriter.Flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+			return nil
+		}
+	} else {
+		writer = productio.NewJSONLWriter(c.Writer)
+	}
+
+	for i, p := range products {
+		if err := writer.Write(p); err != nil {
+			logrus.WithError(err).Error("product export failed")
+			return
+		}
+		if (i+1)%exportFlushEvery == 0 {
+			if err := flush(); err != nil {
+				logrus.WithError(err).Error("product export failed")
+				return
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		logrus.WithError(err).Error("product export failed")
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"item-comparison-ai-api/internal/jsonpatch"
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// acceptPatch lists the patch formats PatchProduct understands
+const acceptPatch = jsonpatch.MergePatchType + ", " + jsonpatch.JSONPatchType
+
+// PatchProduct partially updates an existing product by ID. The body is a
+// JSON Merge Patch (application/merge-patch+json, also assumed for plain
+// application/json) or a JSON Patch (application/json-patch+json).
+func (h *ProductHandler) PatchProduct(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		HandleError(c, ErrInvalidID)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	patch, err := c.GetRawData()
+	if err != nil {
+		HandleError(c, ErrBindJSON)
+		return
+	}
+
+	var patched models.Product
+	err = h.update(c, repositories.RevisionPatch, func(tx *repositories.ProductTx) error {
+		p, found := tx.Get(id)
+		if !found || p.IsDeleted() {
+			return ErrNotFound
+		}
+		if herr := h.checkIfMatch(c, p); herr != nil {
+			return herr
+		}
+
+		p, err := patchProduct(p, mediaType, patch)
+		if err != nil {
+			return err
+		}
+		if err := h.checkBrand(p); err != nil {
+			return err
+		}
+
+		p, _ = tx.Replace(p)
+		patched = p
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrUnsupportedPatchType) {
+			c.Header("Accept-Patch", acceptPatch)
+		}
+		respondUpdateError(c, err)
+		return
+	}
+
+	c.Header("ETag", patched.ETag())
+	c.JSON(http.StatusOK, patched)
+}
+
+// patchProduct applies a patch of the given media type to current, then
+// decodes and validates the result as a full product. The ID and trash state
+// cannot be patched. Errors are an *Error or a *ValidationError.
+func patchProduct(current models.Product, mediaType string, patch []byte) (models.Product, error) {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return current, ErrFailedToSave
+	}
+
+	var patchedDoc []byte
+	switch mediaType {
+	case jsonpatch.JSONPatchType:
+		patchedDoc, err = jsonpatch.Apply(doc, patch)
+	case jsonpatch.MergePatchType, "application/json", "":
+		patchedDoc, err = jsonpatch.MergePatch(doc, patch)
+	default:
+		return current, ErrUnsupportedPatchType
+	}
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		return current, ErrPatchTestFailed.withDetail(err.Error())
+	}
+	if err != nil {
+		return current, ErrInvalidPatch.withDetail(err.Error())
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(patchedDoc))
+	decoder.DisallowUnknownFields()
+	var p models.Product
+	if err := decoder.Decode(&p); err != nil {
+		if verr := validationError(err); verr != nil {
+			return current, verr
+		}
+		return current, ErrInvalidPatch.withDetail(err.Error())
+	}
+
+	p.ID = current.ID
+	p.DeletedAt = current.DeletedAt
+	if verr := validateProduct(p); verr != nil {
+		return current, verr
+	}
+	return p, nil
+}
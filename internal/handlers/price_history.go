@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/money"
+	"item-comparison-ai-api/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// lowestPriceWindow is the period of the lowest price shown with comparisons
+const lowestPriceWindow = 30 * 24 * time.Hour
+
+// defaultDropsWindow is how far back price drops are listed without ?since=
+const defaultDropsWindow = 7 * 24 * time.Hour
+
+// PriceHistory is the price series of a product with its statistics
+type PriceHistory struct {
+	ProductID    int                       `json:"product_id"`
+	Points       []repositories.PricePoint `json:"points"`
+	Min          money.Money               `json:"min"`
+	Max          money.Money               `json:"max"`
+	Average      money.Money               `json:"average"`
+	Lowest30Days money.Money               `json:"lowest_30_days"`
+}
+
+// PriceDrop is a price drop together with the name of the product
+type PriceDrop struct {
+	repositories.PriceDrop
+	Name string `json:"name"`
+}
+
+// WithPriceHistory records a price point for every price change in prices
+func (h *ProductHandler) WithPriceHistory(prices *repositories.PriceHistory) *ProductHandler {
+	h.prices = prices
+	return h
+}
+
+// GetPriceHistory returns the price series of a product, oldest first, with
+// its lowest, highest and average price and the lowest of the last 30 days
+func (h *ProductHandler) GetPriceHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		HandleError(c, ErrInvalidID)
+		return
+	}
+
+	points, err := h.prices.Series(id)
+	if err != nil {
+		HandleError(c, storageError(err, ErrFailedToLoad))
+		return
+	}
+	stats, ok := repositories.Stats(points)
+	if !ok {
+		HandleError(c, ErrNotFound)
+		return
+	}
+	lowest, _ := repositories.LowestSince(points, time.Now().Add(-lowestPriceWindow))
+
+	c.JSON(http.StatusOK, PriceHistory{
+		ProductID:    id,
+		Points:       points,
+		Min:          stats.Min,
+		Max:          stats.Max,
+		Average:      stats.Average,
+		Lowest30Days: lowest,
+	})
+}
+
+// ListPriceDrops lists the price drops of live products, newest first.
+// ?since= is a timestamp or a duration back from now (default 168h) and
+// ?min_pct= the smallest drop in percent.
+func (h *ProductHandler) ListPriceDrops(c *gin.Context) {
+	since := time.Now().Add(-defaultDropsWindow)
+	if value := c.Query("since"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			d, derr := time.ParseDuration(value)
+			if derr != nil || d < 0 {
+				HandleError(c, ErrInvalidSince)
+				return
+			}
+			t = time.Now().Add(-d)
+		}
+		since = t
+	}
+
+	minPercent := 0.0
+	if value := c.Query("min_pct"); value != "" {
+		pct, err := strconv.ParseFloat(value, 64)
+		if err != nil || pct < 0 || pct > 100 {
+			HandleError(c, ErrInvalidMinPercent)
+			return
+		}
+		minPercent = pct
+	}
+
+	drops, err := h.prices.Drops(since, minPercent)
+	if err != nil {
+		HandleError(c, storageError(err, ErrFailedToLoad))
+		return
+	}
+	products, err := h.repo.LoadProducts()
+	if err != nil {
+		HandleError(c, storageError(err, ErrFailedToLoad))
+		return
+	}
+
+	live := make(map[int]models.Product, len(products))
+	for _, p := range products {
+		if !p.IsDeleted() {
+			live[p.ID] = p
+		}
+	}
+
+	result := make([]PriceDrop, 0, len(drops))
+	for _, d := range drops {
+		if p, ok := live[d.ProductID]; ok {
+			result = append(result, PriceDrop{PriceDrop: d, Name: p.Name})
+		}
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// addLowestPrices fills the lowest price of the last 30 days of every
+// compared product, converted like the products themselves
+func (h *ProductHandler) addLowestPrices(c *gin.Context, comparison *Comparison) *Error {
+	if h.prices == nil {
+		return nil
+	}
+
+	ids := make([]int, len(comparison.Products))
+	for i, p := range comparison.Products {
+		ids[i] = p.ID
+	}
+	series, err := h.prices.SeriesOf(ids)
+	if err != nil {
+		return storageError(err, ErrFailedToLoad)
+	}
+	convert, herr := h.priceConverter(c)
+	if herr != nil {
+		return herr
+	}
+
+	since := time.Now().Add(-lowestPriceWindow)
+	comparison.Lowest30Days = make(map[int]money.Money)
+	for _, id := range ids {
+		lowest, ok := repositories.LowestSince(series[id], since)
+		if !ok {
+			continue
+		}
+		if convert != nil {
+			if lowest, herr = convert(lowest); herr != nil {
+				return herr
+			}
+		}
+		comparison.Lowest30Days[id] = lowest
+	}
+	return nil
+}
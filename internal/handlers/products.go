@@ -1,10 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"item-comparison-ai-api/internal/blobstore"
+	"item-comparison-ai-api/internal/i18n"
 	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/money"
 	"item-comparison-ai-api/internal/repositories"
 
 	"github.com/gin-gonic/gin"
@@ -12,7 +19,17 @@ import (
 
 // ProductHandler holds the database client
 type ProductHandler struct {
-	repo repositories.ProductRepository
+	repo           repositories.ProductRepository
+	requireIfMatch bool
+	history        *repositories.ProductHistory
+	rates          *repositories.ExchangeRateStore
+	prices         *repositories.PriceHistory
+	reviews        *repositories.ReviewStore
+	images         blobstore.Store
+	maxImageSize   int64
+	locales        *i18n.Locales
+	brands         *repositories.BrandStore
+	purgeRecords   []repositories.ProductRecords
 }
 
 // NewProductHandler creates a new ProductHandler
@@ -20,6 +37,51 @@ func NewProductHandler(repository repositories.ProductRepository) *ProductHandle
 	return &ProductHandler{repo: repository}
 }
 
+// WithRequireIfMatch makes an If-Match header mandatory on PUT, PATCH and DELETE
+func (h *ProductHandler) WithRequireIfMatch(require bool) *ProductHandler {
+	h.requireIfMatch = require
+	return h
+}
+
+// checkIfMatch evaluates the If-Match precondition of a mutation against the
+// current state of the product
+func (h *ProductHandler) checkIfMatch(c *gin.Context, current models.Product) *Error {
+	return h.matchETag(c.GetHeader("If-Match"), current)
+}
+
+// matchETag evaluates an If-Match value against the current state of the product
+func (h *ProductHandler) matchETag(header string, current models.Product) *Error {
+	if header == "" {
+		if h.requireIfMatch {
+			return ErrPreconditionRequired
+		}
+		return nil
+	}
+
+	etag := current.ETag()
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		// If-Match uses strong comparison, so weak tags never match
+		if candidate == "*" || candidate == etag {
+			return nil
+		}
+	}
+
+	return ErrPreconditionFailed
+}
+
+// setETag tags a read of stored as presented. A read showing the product as
+// stored gets its strong tag, the one If-Match compares against. A converted
+// or translated read is another representation, so it gets a weak tag of its
+// own content, which If-Match never accepts.
+func setETag(c *gin.Context, stored, presented models.Product) {
+	etag := stored.ETag()
+	if tag := presented.ETag(); tag != etag {
+		etag = "W/" + tag
+	}
+	c.Header("ETag", etag)
+}
+
 // GetProduct retrieves a product by its ID
 func (h *ProductHandler) GetProduct(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -30,13 +92,20 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 
 	products, err := h.repo.LoadProducts()
 	if err != nil {
-		HandleError(c, ErrFailedToLoad)
+		HandleError(c, storageError(err, ErrFailedToLoad))
 		return
 	}
 
 	for _, p := range products {
-		if p.ID == id {
-			c.JSON(http.StatusOK, p)
+		if p.ID == id && !p.IsDeleted() {
+			converted := []models.Product{p}
+			if herr := h.presentProducts(c, converted); herr != nil {
+				HandleError(c, herr)
+				return
+			}
+			setETag(c, p, converted[0])
+			c.Header("Accept-Patch", acceptPatch)
+			c.JSON(http.StatusOK, converted[0])
 			return
 		}
 	}
@@ -44,195 +113,212 @@ func (h *ProductHandler) GetProduct(c *gin.Context) {
 	HandleError(c, ErrNotFound)
 }
 
-// GetAllProducts retrieves all products with optional pagination and filtering
+// GetAllProducts retrieves all products with optional pagination, filtering and sorting
 func (h *ProductHandler) GetAllProducts(c *gin.Context) {
-	products, err := h.repo.LoadProducts()
-	if err != nil {
-		HandleError(c, ErrFailedToLoad)
+	filter, herr := parseProductFilter(c)
+	if herr != nil {
+		HandleError(c, herr)
 		return
 	}
 
-	// Filter by category if the query parameter is provided
-	category := c.Query("category")
-	if category != "" {
-		var filteredProducts []models.Product
-		for _, p := range products {
-			if p.Category == category {
-				filteredProducts = append(filteredProducts, p)
-			}
-		}
-		products = filteredProducts
+	products, herr := h.listProducts(filter)
+	if herr != nil {
+		HandleError(c, herr)
+		return
 	}
-
-	// Pagination
-	limitStr := c.DefaultQuery("limit", "10")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 0 {
-		HandleError(c, ErrInvalidLimitParameter)
+	if herr := h.presentProducts(c, products); herr != nil {
+		HandleError(c, herr)
 		return
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		HandleError(c, ErrInvalidOffsetParameter)
+	if filter.ExcludeVariants {
+		collapsed, err := h.collapseVariants(products)
+		if err != nil {
+			HandleError(c, storageError(err, ErrFailedToLoad))
+			return
+		}
+		c.JSON(http.StatusOK, collapsed)
 		return
 	}
 
-	start := offset
-	end := offset + limit
+	c.JSON(http.StatusOK, products)
+}
 
-	if start > len(products) {
-		start = len(products)
-	}
-	if end > len(products) {
-		end = len(products)
+// parseProductFilter reads the listing query parameters:
+// category, brand_id, spec[<key>]=<value>, variants, sort, limit and offset
+func parseProductFilter(c *gin.Context) (repositories.ProductFilter, *Error) {
+	filter := repositories.ProductFilter{
+		Category: c.Query("category"),
+		Specs:    c.QueryMap("spec"),
+		Sort:     c.Query("sort"),
 	}
 
-	c.JSON(http.StatusOK, products[start:end])
-}
+	if value := c.Query("brand_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 0 {
+			return filter, ErrInvalidBrandID
+		}
+		filter.BrandID = id
+	}
 
-// CreateProduct adds a new product
-func (h *ProductHandler) CreateProduct(c *gin.Context) {
-	var newProduct models.Product
-	if err := c.ShouldBindJSON(&newProduct); err != nil {
-		HandleError(c, ErrBindJSON)
-		return
+	switch c.Query("variants") {
+	case "":
+	case "collapse":
+		filter.ExcludeVariants = true
+	default:
+		return filter, ErrInvalidVariantsParam
 	}
 
-	products, err := h.repo.LoadProducts()
-	if err != nil {
-		HandleError(c, ErrFailedToLoad)
-		return
+	if filter.Sort != "" {
+		if _, _, err := repositories.ParseSort(filter.Sort); err != nil {
+			return filter, ErrInvalidSortParameter
+		}
 	}
 
-	newProduct.ID = h.repo.GetNextID(products)
-	products = append(products, newProduct)
+	// Pagination
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 0 {
+		return filter, ErrInvalidLimitParameter
+	}
 
-	if err := h.repo.SaveProducts(products); err != nil {
-		HandleError(c, ErrFailedToSave)
-		return
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		return filter, ErrInvalidOffsetParameter
 	}
 
-	c.JSON(http.StatusCreated, newProduct)
+	filter.Limit = limit
+	filter.Offset = offset
+
+	return filter, nil
 }
 
-// UpdateProduct updates an existing product by ID
-func (h *ProductHandler) UpdateProduct(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
+// queryProducts pushes the filter down to the repository when it supports
+// querying, and evaluates it in memory otherwise
+func (h *ProductHandler) queryProducts(filter repositories.ProductFilter) ([]models.Product, error) {
+	if querier, ok := h.repo.(repositories.ProductQuerier); ok {
+		return querier.QueryProducts(filter)
+	}
+
+	products, err := h.repo.LoadProducts()
 	if err != nil {
-		HandleError(c, ErrInvalidID)
-		return
+		return nil, err
 	}
 
-	var updatedProduct models.Product
-	if err := c.ShouldBindJSON(&updatedProduct); err != nil {
-		HandleError(c, ErrBindJSON)
-		return
+	return repositories.FilterProducts(products, filter)
+}
+
+// listProducts runs a listing filter. Prices of different currencies do not
+// compare by amount, so sort=price is done here on prices converted to the
+// base currency, over every matching product before paginating.
+func (h *ProductHandler) listProducts(filter repositories.ProductFilter) ([]models.Product, *Error) {
+	field, desc, err := repositories.ParseSort(filter.Sort)
+	if filter.Sort == "" || err != nil || field != "price" {
+		products, err := h.queryProducts(filter)
+		if errors.Is(err, repositories.ErrInvalidSortField) {
+			return nil, ErrInvalidSortParameter
+		}
+		if err != nil {
+			return nil, storageError(err, ErrFailedToLoad)
+		}
+		return products, nil
 	}
 
-	products, err := h.repo.LoadProducts()
+	all := filter
+	all.Sort, all.Limit, all.Offset = "", -1, 0
+	products, err := h.queryProducts(all)
 	if err != nil {
-		HandleError(c, ErrFailedToLoad)
-		return
+		return nil, storageError(err, ErrFailedToLoad)
+	}
+	rates, herr := h.loadRates()
+	if herr != nil {
+		return nil, herr
 	}
 
-	found := false
-	for i, p := range products {
-		if p.ID == id {
-			updatedProduct.ID = id // Ensure the ID from the URL is used
-			products[i] = updatedProduct
-			found = true
-			break
+	amounts := make(map[int]money.Decimal, len(products))
+	for _, p := range products {
+		if p.Price.IsZero() {
+			continue
+		}
+		base, herr := convertPrice(rates, p.Price, rates.Base)
+		if herr != nil {
+			return nil, herr
 		}
+		amounts[p.ID] = base.Amount
 	}
+	slices.SortStableFunc(products, func(a, b models.Product) int {
+		if desc {
+			a, b = b, a
+		}
+		return amounts[a.ID].Cmp(amounts[b.ID])
+	})
 
-	if !found {
-		HandleError(c, ErrNotFound)
+	return filter.Paginate(products), nil
+}
+
+// CreateProduct adds a new product
+func (h *ProductHandler) CreateProduct(c *gin.Context) {
+	var newProduct models.Product
+	if !bindProduct(c, &newProduct) {
 		return
 	}
 
-	if err := h.repo.SaveProducts(products); err != nil {
-		HandleError(c, ErrFailedToSave)
+	newProduct.DeletedAt = nil
+	err := h.update(c, repositories.RevisionCreate, func(tx *repositories.ProductTx) error {
+		if err := h.checkBrand(newProduct); err != nil {
+			return err
+		}
+		newProduct = tx.Insert(newProduct)
+		return nil
+	})
+	if err != nil {
+		respondUpdateError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, updatedProduct)
+	c.Header("ETag", newProduct.ETag())
+	c.JSON(http.StatusCreated, newProduct)
 }
 
-// PatchProduct partially updates an existing product by ID
-func (h *ProductHandler) PatchProduct(c *gin.Context) {
+// UpdateProduct updates an existing product by ID
+func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		HandleError(c, ErrInvalidID)
 		return
 	}
 
-	var updates map[string]interface{}
-	if err := c.ShouldBindJSON(&updates); err != nil {
-		HandleError(c, ErrBindJSON)
-		return
-	}
-
-	products, err := h.repo.LoadProducts()
-	if err != nil {
-		HandleError(c, ErrFailedToLoad)
+	var updatedProduct models.Product
+	if !bindProduct(c, &updatedProduct) {
 		return
 	}
 
-	found := false
-	for i, p := range products {
-		if p.ID == id {
-			if name, ok := updates["name"]; ok {
-				p.Name = name.(string)
-			}
-			if imageURL, ok := updates["image_url"]; ok {
-				p.ImageURL = imageURL.(string)
-			}
-			if description, ok := updates["description"]; ok {
-				p.Description = description.(string)
-			}
-			if price, ok := updates["price"]; ok {
-				p.Price = price.(float64)
-			}
-			if rating, ok := updates["rating"]; ok {
-				p.Rating = rating.(float64)
-			}
-			if specs, ok := updates["specifications"]; ok {
-				if specMap, isMap := specs.(map[string]interface{}); isMap {
-					convertedSpecs := make(map[string]string)
-					for k, v := range specMap {
-						if strVal, isString := v.(string); isString {
-							convertedSpecs[k] = strVal
-						}
-					}
-					p.Specifications = convertedSpecs
-				}
-			}
-			if category, ok := updates["category"]; ok {
-				p.Category = category.(string)
-			}
-			products[i] = p
-			found = true
-			break
+	err = h.update(c, repositories.RevisionUpdate, func(tx *repositories.ProductTx) error {
+		current, found := tx.Get(id)
+		if !found || current.IsDeleted() {
+			return ErrNotFound
+		}
+		if herr := h.checkIfMatch(c, current); herr != nil {
+			return herr
+		}
+		if err := h.checkBrand(updatedProduct); err != nil {
+			return err
 		}
-	}
-
-	if !found {
-		HandleError(c, ErrNotFound)
-		return
-	}
 
-	if err := h.repo.SaveProducts(products); err != nil {
-		HandleError(c, ErrFailedToSave)
+		updatedProduct.ID = id // Ensure the ID from the URL is used
+		updatedProduct.DeletedAt = nil
+		updatedProduct, _ = tx.Replace(updatedProduct)
+		return nil
+	})
+	if err != nil {
+		respondUpdateError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, products[id-1]) // Assuming IDs are sequential for simplicity
+	c.Header("ETag", updatedProduct.ETag())
+	c.JSON(http.StatusOK, updatedProduct)
 }
 
-// DeleteProduct removes a product by ID
+// DeleteProduct moves a product to the trash by ID
 func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -240,30 +326,41 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	products, err := h.repo.LoadProducts()
+	err = h.update(c, repositories.RevisionDelete, func(tx *repositories.ProductTx) error {
+		current, found := tx.Get(id)
+		if !found || current.IsDeleted() {
+			return ErrNotFound
+		}
+		if herr := h.checkIfMatch(c, current); herr != nil {
+			return herr
+		}
+
+		now := time.Now().UTC()
+		current.DeletedAt = &now
+		tx.Replace(current)
+		return nil
+	})
 	if err != nil {
-		HandleError(c, ErrFailedToLoad)
+		HandleError(c, updateError(err))
 		return
 	}
 
-	found := false
-	for i, p := range products {
-		if p.ID == id {
-			products = append(products[:i], products[i+1:]...)
-			found = true
-			break
-		}
-	}
+	c.Status(http.StatusNoContent)
+}
 
-	if !found {
-		HandleError(c, ErrNotFound)
-		return
+// updateError turns the error of a repository update into a response. Errors
+// returned by the update function are already responses; anything else
+// failed in the repository itself.
+func updateError(err error) *Error {
+	var herr *Error
+	if errors.As(err, &herr) {
+		return herr
 	}
-
-	if err := h.repo.SaveProducts(products); err != nil {
-		HandleError(c, ErrFailedToSave)
-		return
+	if errors.Is(err, repositories.ErrDuplicateIdentifier) {
+		return ErrDuplicateIdentifier.withDetail(err.Error())
 	}
-
-	c.Status(http.StatusNoContent)
+	if errors.Is(err, repositories.ErrInvalidVariant) {
+		return ErrInvalidVariant.withDetail(err.Error())
+	}
+	return storageError(err, ErrFailedToSave)
 }
@@ -5,9 +5,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"item-comparison-ai-api/internal/database"
 	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/money"
 	"item-comparison-ai-api/internal/repositories"
 
 	"github.com/gin-gonic/gin"
@@ -20,6 +24,11 @@ type MockProductRepository struct {
 	mock.Mock
 }
 
+// usd is a price in US dollars
+func usd(amount string) money.Money {
+	return money.New(money.MustParseDecimal(amount), money.DefaultCurrency)
+}
+
 func (m *MockProductRepository) LoadProducts() ([]models.Product, error) {
 	args := m.Called()
 	return args.Get(0).([]models.Product), args.Error(1)
@@ -35,6 +44,22 @@ func (m *MockProductRepository) GetNextID(products []models.Product) int {
 	return args.Int(0)
 }
 
+// Update runs fn against the mocked LoadProducts and saves through the mocked
+// SaveProducts, so tests keep setting expectations on those
+func (m *MockProductRepository) Update(fn func(tx *repositories.ProductTx) error) error {
+	products, err := m.LoadProducts()
+	if err != nil {
+		return err
+	}
+
+	tx := repositories.NewProductTx(products, m.GetNextID)
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return m.SaveProducts(tx.Products)
+}
+
 func setupTestRouter(repo repositories.ProductRepository) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
@@ -45,6 +70,7 @@ func setupTestRouter(repo repositories.ProductRepository) *gin.Engine {
 	r.PUT("/products/:id", h.UpdateProduct)
 	r.PATCH("/products/:id", h.PatchProduct)
 	r.DELETE("/products/:id", h.DeleteProduct)
+	r.POST("/products/bulk", h.BulkProducts)
 	return r
 }
 
@@ -207,3 +233,312 @@ func TestDeleteProduct(t *testing.T) {
 	assert.Equal(t, http.StatusNoContent, w.Code)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestGetProductReturnsETag(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	product := models.Product{ID: 1, Name: "Laptop"}
+	mockRepo.On("LoadProducts").Return([]models.Product{product}, nil)
+
+	r := setupTestRouter(mockRepo)
+	req, _ := http.NewRequest(http.MethodGet, "/products/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, product.ETag(), w.Header().Get("ETag"))
+}
+
+func TestIfMatchPreconditions(t *testing.T) {
+	current := models.Product{ID: 1, Name: "Laptop", Category: "Electronics"}
+	body, _ := json.Marshal(models.Product{Name: "Updated Laptop"})
+
+	t.Run("MatchingETag", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		mockRepo.On("LoadProducts").Return([]models.Product{current}, nil)
+		mockRepo.On("SaveProducts", mock.Anything).Return(nil)
+
+		r := setupTestRouter(mockRepo)
+		req, _ := http.NewRequest(http.MethodPut, "/products/1", bytes.NewBuffer(body))
+		req.Header.Set("If-Match", current.ETag())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEqual(t, current.ETag(), w.Header().Get("ETag"))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("StaleETag", func(t *testing.T) {
+		for _, method := range []string{http.MethodPut, http.MethodPatch, http.MethodDelete} {
+			mockRepo := new(MockProductRepository)
+			mockRepo.On("LoadProducts").Return([]models.Product{current}, nil)
+
+			r := setupTestRouter(mockRepo)
+			req, _ := http.NewRequest(method, "/products/1", bytes.NewBuffer(body))
+			req.Header.Set("If-Match", `"stale"`)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusPreconditionFailed, w.Code, method)
+			mockRepo.AssertNotCalled(t, "SaveProducts", mock.Anything)
+		}
+	})
+
+	t.Run("RequiredButMissing", func(t *testing.T) {
+		mockRepo := new(MockProductRepository)
+		mockRepo.On("LoadProducts").Return([]models.Product{current}, nil)
+
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		h := NewProductHandler(mockRepo).WithRequireIfMatch(true)
+		r.DELETE("/products/:id", h.DeleteProduct)
+
+		req, _ := http.NewRequest(http.MethodDelete, "/products/1", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+		mockRepo.AssertNotCalled(t, "SaveProducts", mock.Anything)
+	})
+}
+
+func TestStorageLockedReturnsServiceUnavailable(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	mockRepo.On("LoadProducts").Return([]models.Product(nil), database.ErrLockTimeout)
+
+	r := setupTestRouter(mockRepo)
+	req, _ := http.NewRequest(http.MethodDelete, "/products/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	assert.JSONEq(t, `{"error":"Storage is locked by another process, retry later"}`, w.Body.String())
+}
+
+func TestUnrecordedChangeIsNotSaved(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	mockRepo.On("LoadProducts").Return([]models.Product{{ID: 1, Name: "Laptop"}}, nil)
+
+	// A directory cannot be read as the history file
+	history := repositories.NewProductHistory(&database.Database{}, t.TempDir())
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/products/:id", NewProductHandler(mockRepo).WithHistory(history).UpdateProduct)
+
+	req, _ := http.NewRequest(http.MethodPut, "/products/1", strings.NewReader(`{"name": "Gaming Laptop"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	mockRepo.AssertNotCalled(t, "SaveProducts", mock.Anything)
+}
+
+func TestRollbackProductChecksLineageAndValidation(t *testing.T) {
+	history := repositories.NewProductHistory(&database.Database{}, t.TempDir()+"/history.json")
+	_, err := history.RecordAll("", []repositories.Change{
+		{Action: repositories.RevisionCreate, After: &models.Product{ID: 1, Name: "Purged product"}},
+		{Action: repositories.RevisionCreate, After: &models.Product{ID: 1, Name: "Laptop"}},
+		{Action: repositories.RevisionUpdate, Before: &models.Product{ID: 1, Name: "Laptop"}, After: &models.Product{ID: 1, Name: "", Price: usd("-1")}},
+		{Action: repositories.RevisionUpdate, Before: &models.Product{ID: 1, Name: "", Price: usd("-1")}, After: &models.Product{ID: 1, Name: "Gaming Laptop"}},
+	})
+	assert.NoError(t, err)
+
+	mockRepo := new(MockProductRepository)
+	mockRepo.On("LoadProducts").Return([]models.Product{{ID: 1, Name: "Gaming Laptop"}}, nil)
+	mockRepo.On("SaveProducts", mock.Anything).Return(nil)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/products/:id/rollback/:rev", NewProductHandler(mockRepo).WithHistory(history).RollbackProduct)
+	rollback := func(rev string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/products/1/rollback/"+rev, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusConflict, rollback("1").Code, "the revision belongs to an earlier product")
+	assert.Equal(t, http.StatusBadRequest, rollback("3").Code, "the revision fails validation")
+	assert.Equal(t, http.StatusNotFound, rollback("9").Code)
+	mockRepo.AssertNotCalled(t, "SaveProducts", mock.Anything)
+
+	w := rollback("2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"Laptop"`)
+}
+
+func TestBulkProductsSavesOnce(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	mockRepo.On("LoadProducts").Return([]models.Product{{ID: 1, Name: "Laptop"}}, nil)
+	mockRepo.On("GetNextID", mock.Anything).Return(2).Once()
+	mockRepo.On("GetNextID", mock.Anything).Return(3).Once()
+	mockRepo.On("SaveProducts", mock.MatchedBy(func(products []models.Product) bool {
+		return len(products) == 3 && products[0].Name == "Gaming Laptop"
+	})).Return(nil).Once()
+
+	r := setupTestRouter(mockRepo)
+	body := `{"operations": [
+		{"op": "create", "product": {"name": "Mouse"}},
+		{"op": "create", "product": {"name": "Keyboard"}},
+		{"op": "update", "id": 1, "product": {"name": "Gaming Laptop"}}
+	]}`
+	req, _ := http.NewRequest(http.MethodPost, "/products/bulk", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockRepo.AssertNumberOfCalls(t, "SaveProducts", 1)
+}
+
+func TestImportUpsertFailsUnknownIDs(t *testing.T) {
+	deletedAt := time.Now()
+	mockRepo := new(MockProductRepository)
+	mockRepo.On("LoadProducts").Return([]models.Product{{ID: 1, Name: "Laptop"}, {ID: 2, Name: "Phone", DeletedAt: &deletedAt}}, nil)
+	mockRepo.On("GetNextID", mock.Anything).Return(3)
+
+	r := setupTestRouter(mockRepo)
+	r.POST("/products/import", NewProductHandler(mockRepo).ImportProducts)
+	body := "id,name\n1,Laptop Pro\n99,Ghost\n2,Phone\n,Mouse\n"
+	req, _ := http.NewRequest(http.MethodPost, "/products/import", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// Only the row without an ID is created; unknown and trashed IDs fail
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var resp struct {
+		Report ImportReport `json:"report"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, 1, resp.Report.Updated)
+	assert.Equal(t, 1, resp.Report.Created)
+	assert.Equal(t, []ImportError{
+		{Row: 3, Error: "product 99 does not exist"},
+		{Row: 4, Error: "product 2 does not exist"},
+	}, resp.Report.Errors)
+	mockRepo.AssertNotCalled(t, "SaveProducts", mock.Anything)
+}
+
+func TestPatchProductFormats(t *testing.T) {
+	laptop := models.Product{ID: 1, Name: "Laptop", Price: usd("1200"), Specifications: map[string]string{"RAM": "16GB", "Storage": "512GB"}}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantCode    int
+		wantSpecs   map[string]string
+		wantPrice   string
+	}{
+		{"merge patch removes a spec key", "application/merge-patch+json", `{"specifications": {"Storage": null, "GPU": "RTX"}}`, http.StatusOK, map[string]string{"RAM": "16GB", "GPU": "RTX"}, "1200"},
+		{"plain json is a merge patch", "application/json", `{"price": 999}`, http.StatusOK, laptop.Specifications, "999"},
+		{"json patch with passing test", "application/json-patch+json", `[{"op": "test", "path": "/price/amount", "value": "1200"}, {"op": "replace", "path": "/price/amount", "value": "1100"}, {"op": "remove", "path": "/specifications/RAM"}]`, http.StatusOK, map[string]string{"Storage": "512GB"}, "1100"},
+		{"json patch with failing test", "application/json-patch+json", `[{"op": "test", "path": "/price/amount", "value": "1"}, {"op": "replace", "path": "/price/amount", "value": "1100"}]`, http.StatusConflict, nil, ""},
+		{"json patch on a missing path", "application/json-patch+json", `[{"op": "remove", "path": "/specifications/GPU"}]`, http.StatusBadRequest, nil, ""},
+		{"wrong type is a bad request", "application/merge-patch+json", `{"price": true}`, http.StatusBadRequest, nil, ""},
+		{"spec values must be strings", "application/merge-patch+json", `{"specifications": {"RAM": 16}}`, http.StatusBadRequest, nil, ""},
+		{"unknown fields are rejected", "application/merge-patch+json", `{"colour": "red"}`, http.StatusBadRequest, nil, ""},
+		{"unsupported media type", "text/plain", `price=10`, http.StatusUnsupportedMediaType, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockProductRepository)
+			mockRepo.On("LoadProducts").Return([]models.Product{laptop}, nil)
+			mockRepo.On("SaveProducts", mock.Anything).Return(nil)
+
+			r := setupTestRouter(mockRepo)
+			req, _ := http.NewRequest(http.MethodPatch, "/products/1", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			if tt.wantCode != http.StatusOK {
+				mockRepo.AssertNotCalled(t, "SaveProducts", mock.Anything)
+				return
+			}
+
+			var returned models.Product
+			json.Unmarshal(w.Body.Bytes(), &returned)
+			assert.Equal(t, 1, returned.ID)
+			assert.Equal(t, usd(tt.wantPrice), returned.Price)
+			assert.Equal(t, tt.wantSpecs, returned.Specifications)
+		})
+	}
+}
+
+func TestProductValidation(t *testing.T) {
+	longKey := strings.Repeat("k", 65)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantFields map[string]string
+	}{
+		{"create lists every invalid field", http.MethodPost, "/products", `{"price": -1, "rating": 99, "image_url": "not a url"}`, map[string]string{"name": CodeRequired, "price.amount": CodeTooSmall, "image_url": CodeInvalidFormat}},
+		{"spec keys are limited", http.MethodPost, "/products", `{"name": "Laptop", "specifications": {"` + longKey + `": "x"}}`, map[string]string{"specifications[" + longKey + "]": CodeTooLong}},
+		{"identifiers are checked", http.MethodPost, "/products", `{"name": "Laptop", "sku": "bad sku", "gtin": "4006381333932"}`, map[string]string{"sku": CodeInvalidFormat, "gtin": CodeInvalidFormat}},
+		{"wrong types name the field", http.MethodPost, "/products", `{"name": "Laptop", "rating": "high"}`, map[string]string{"rating": CodeInvalidType}},
+		{"prices are decimals", http.MethodPost, "/products", `{"name": "Laptop", "price": "cheap"}`, map[string]string{"price": CodeInvalidType}},
+		{"currencies are ISO 4217 codes", http.MethodPost, "/products", `{"name": "Laptop", "price": {"amount": "10", "currency": "XYZ"}}`, map[string]string{"price.currency": CodeInvalidFormat}},
+		{"prices fit the minor units", http.MethodPost, "/products", `{"name": "Laptop", "price": {"amount": "1.5", "currency": "JPY"}}`, map[string]string{"price.amount": CodeInvalidFormat}},
+		{"update applies the same rules", http.MethodPut, "/products/1", `{"name": "Laptop", "price": -1}`, map[string]string{"price.amount": CodeTooSmall}},
+		{"patch validates the result", http.MethodPatch, "/products/1", `{"name": ""}`, map[string]string{"name": CodeRequired}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockProductRepository)
+			mockRepo.On("LoadProducts").Return([]models.Product{{ID: 1, Name: "Laptop"}}, nil)
+
+			r := setupTestRouter(mockRepo)
+			req, _ := http.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			var body struct {
+				Error  string       `json:"error"`
+				Fields []FieldError `json:"fields"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &body)
+			assert.Equal(t, ErrValidation.Message, body.Error)
+
+			codes := make(map[string]string)
+			for _, f := range body.Fields {
+				codes[f.Field] = f.Code
+			}
+			assert.Equal(t, tt.wantFields, codes)
+			mockRepo.AssertNotCalled(t, "SaveProducts", mock.Anything)
+		})
+	}
+}
+
+func TestBulkProductsReportsFieldErrors(t *testing.T) {
+	mockRepo := new(MockProductRepository)
+	mockRepo.On("LoadProducts").Return([]models.Product{}, nil)
+	mockRepo.On("GetNextID", mock.Anything).Return(1)
+
+	r := setupTestRouter(mockRepo)
+	body := `{"operations": [{"op": "create", "product": {"name": "Mouse"}}, {"op": "create", "product": {"price": -5}}]}`
+	req, _ := http.NewRequest(http.MethodPost, "/products/bulk", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var resp struct {
+		Results []BulkResult `json:"results"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.Equal(t, http.StatusBadRequest, resp.Results[1].Status)
+	assert.Len(t, resp.Results[1].Fields, 2)
+	mockRepo.AssertNotCalled(t, "SaveProducts", mock.Anything)
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// moderateReviewRequest is the body of ModerateReview
+type moderateReviewRequest struct {
+	Status string `json:"status" binding:"required,oneof=pending approved rejected"`
+}
+
+// WithReviews enables the review endpoints, keeping the reviews in store
+func (h *ProductHandler) WithReviews(reviews *repositories.ReviewStore) *ProductHandler {
+	h.reviews = reviews
+	return h
+}
+
+// ListReviews lists the reviews of a product, newest first. Only approved
+// reviews are listed unless ?status= asks for pending, rejected or all.
+func (h *ProductHandler) ListReviews(c *gin.Context) {
+	id, herr := h.liveProductID(c)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+
+	status := c.DefaultQuery("status", models.ReviewApproved)
+	switch status {
+	case models.ReviewApproved, models.ReviewPending, models.ReviewRejected, "all":
+	default:
+		HandleError(c, ErrInvalidReviewStatus)
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 0 {
+		HandleError(c, ErrInvalidLimitParameter)
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		HandleError(c, ErrInvalidOffsetParameter)
+		return
+	}
+
+	reviews, err := h.reviews.List(id)
+	if err != nil {
+		HandleError(c, storageError(err, ErrFailedToLoad))
+		return
+	}
+
+	listed := make([]models.Review, 0, len(reviews))
+	for _, r := range reviews {
+		if status == "all" || r.Status == status {
+			listed = append(listed, r)
+		}
+	}
+	if offset > len(listed) {
+		offset = len(listed)
+	}
+	listed = listed[offset:]
+	if limit < len(listed) {
+		listed = listed[:limit]
+	}
+
+	c.JSON(http.StatusOK, listed)
+}
+
+// CreateReview adds a review to a product. It waits for moderation before
+// it is listed and counts towards the rating.
+func (h *ProductHandler) CreateReview(c *gin.Context) {
+	id, herr := h.liveProductID(c)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+
+	var review models.Review
+	if !bindValid(c, &review) {
+		return
+	}
+
+	created, err := h.reviews.Create(id, review)
+	if err != nil {
+		HandleError(c, storageError(err, ErrFailedToSave))
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// ModerateReview sets the status of a review and updates the rating of its
+// product
+func (h *ProductHandler) ModerateReview(c *gin.Context) {
+	id, reviewID, herr := parseReviewParams(c)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+
+	var req moderateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		HandleError(c, ErrInvalidReviewStatus)
+		return
+	}
+
+	review, err := h.reviews.Moderate(id, reviewID, req.Status)
+	if err != nil {
+		HandleError(c, reviewError(err))
+		return
+	}
+	if err := h.refreshRatings(id); err != nil {
+		HandleError(c, storageError(err, ErrFailedToSave))
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// DeleteReview removes a review and updates the rating of its product
+func (h *ProductHandler) DeleteReview(c *gin.Context) {
+	id, reviewID, herr := parseReviewParams(c)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+
+	if err := h.reviews.Delete(id, reviewID); err != nil {
+		HandleError(c, reviewError(err))
+		return
+	}
+	if err := h.refreshRatings(id); err != nil {
+		HandleError(c, storageError(err, ErrFailedToSave))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// refreshRatings recomputes the rating of a product from its approved
+// reviews. The reviews are read inside the update, so concurrent moderations
+// cannot store a stale rating.
+func (h *ProductHandler) refreshRatings(id int) error {
+	return h.repo.Update(func(tx *repositories.ProductTx) error {
+		reviews, err := h.reviews.List(id)
+		if err != nil {
+			return err
+		}
+		tx.SetRatings(id, models.SummarizeReviews(reviews))
+		return nil
+	})
+}
+
+// liveProductID reads the product ID of the URL and checks that the product
+// exists and is not in the trash
+func (h *ProductHandler) liveProductID(c *gin.Context) (int, *Error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return 0, ErrInvalidID
+	}
+
+	products, err := h.repo.LoadProducts()
+	if err != nil {
+		return 0, storageError(err, ErrFailedToLoad)
+	}
+	for _, p := range products {
+		if p.ID == id && !p.IsDeleted() {
+			return id, nil
+		}
+	}
+	return 0, ErrNotFound
+}
+
+func parseReviewParams(c *gin.Context) (id, reviewID int, herr *Error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return 0, 0, ErrInvalidID
+	}
+
+	reviewID, err = strconv.Atoi(c.Param("review_id"))
+	if err != nil {
+		return 0, 0, ErrInvalidID
+	}
+
+	return id, reviewID, nil
+}
+
+func reviewError(err error) *Error {
+	if errors.Is(err, repositories.ErrReviewNotFound) {
+		return ErrReviewNotFound
+	}
+	return storageError(err, ErrFailedToSave)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProductBySKU retrieves a product by its SKU
+func (h *ProductHandler) GetProductBySKU(c *gin.Context) {
+	products, err := h.queryProducts(repositories.ProductFilter{SKU: c.Param("sku"), Limit: 1})
+	if err != nil {
+		HandleError(c, storageError(err, ErrFailedToLoad))
+		return
+	}
+	if len(products) == 0 {
+		HandleError(c, ErrNotFound)
+		return
+	}
+
+	stored := products[0]
+	if herr := h.presentProducts(c, products); herr != nil {
+		HandleError(c, herr)
+		return
+	}
+	setETag(c, stored, products[0])
+	c.Header("Accept-Patch", acceptPatch)
+	c.JSON(http.StatusOK, products[0])
+}
+
+// UpsertProductBySKU replaces the product with the SKU of the URL, or creates
+// it when no product has that SKU yet, so that sync jobs can send the same
+// request any number of times. A trashed product with the SKU is brought back.
+func (h *ProductHandler) UpsertProductBySKU(c *gin.Context) {
+	var product models.Product
+	if !bindProduct(c, &product) {
+		return
+	}
+	product.SKU = c.Param("sku")
+	product.DeletedAt = nil
+	if verr := validateProduct(product); verr != nil {
+		handleValidationError(c, verr)
+		return
+	}
+	created := false
+	err := h.update(c, repositories.RevisionUpdate, func(tx *repositories.ProductTx) error {
+		if err := h.checkBrand(product); err != nil {
+			return err
+		}
+		i := tx.IndexBySKU(product.SKU)
+		if i < 0 {
+			// If-Match can never match a product that does not exist
+			if c.GetHeader("If-Match") != "" {
+				return ErrPreconditionFailed
+			}
+			product = tx.Insert(product)
+			created = true
+			return nil
+		}
+
+		current := tx.Products[i]
+		if herr := h.checkIfMatch(c, current); herr != nil {
+			return herr
+		}
+		product.ID = current.ID
+		product, _ = tx.Replace(product)
+		return nil
+	})
+	if err != nil {
+		respondUpdateError(c, err)
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+
+	c.Header("ETag", product.ETag())
+	c.JSON(status, product)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"item-comparison-ai-api/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SnapshotHandler exposes catalog backups to administrators
+type SnapshotHandler struct {
+	snapshots *repositories.SnapshotManager
+}
+
+// NewSnapshotHandler creates a new SnapshotHandler
+func NewSnapshotHandler(snapshots *repositories.SnapshotManager) *SnapshotHandler {
+	return &SnapshotHandler{snapshots: snapshots}
+}
+
+// createSnapshotRequest is the optional body of CreateSnapshot
+type createSnapshotRequest struct {
+	Name string `json:"name"`
+}
+
+// CreateSnapshot takes a named snapshot of the catalog
+func (h *SnapshotHandler) CreateSnapshot(c *gin.Context) {
+	var req createSnapshotRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			HandleError(c, ErrBindJSON)
+			return
+		}
+	}
+
+	info, err := h.snapshots.Create(req.Name)
+	if err != nil {
+		HandleError(c, snapshotError(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, info)
+}
+
+// ListSnapshots lists the stored snapshots, newest first
+func (h *SnapshotHandler) ListSnapshots(c *gin.Context) {
+	snapshots, err := h.snapshots.List()
+	if err != nil {
+		HandleError(c, storageError(err, ErrFailedToLoad))
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshots)
+}
+
+// DownloadSnapshot sends a stored snapshot as a file
+func (h *SnapshotHandler) DownloadSnapshot(c *gin.Context) {
+	name := c.Param("name")
+	data, err := h.snapshots.Download(name)
+	if err != nil {
+		HandleError(c, snapshotError(err))
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+name+`.snapshot.json"`)
+	c.Data(http.StatusOK, "application/json", data)
+}
+
+// RestoreSnapshot replaces the catalog with a stored snapshot
+func (h *SnapshotHandler) RestoreSnapshot(c *gin.Context) {
+	info, err := h.snapshots.Restore(c.Param("name"))
+	if err != nil {
+		HandleError(c, snapshotError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// snapshotError maps snapshot manager errors to responses
+func snapshotError(err error) *Error {
+	switch {
+	case errors.Is(err, repositories.ErrSnapshotNotFound):
+		return ErrSnapshotNotFound
+	case errors.Is(err, repositories.ErrSnapshotExists):
+		return ErrSnapshotExists
+	case errors.Is(err, repositories.ErrInvalidSnapshotName):
+		return ErrInvalidSnapshotName
+	default:
+		return storageError(err, ErrFailedToSave)
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"item-comparison-ai-api/internal/i18n"
+	"item-comparison-ai-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TranslationReport lists the products missing translations into a locale
+type TranslationReport struct {
+	Locale string `json:"locale"`
+	// Products counts the live products and Complete those fully translated
+	Products int                  `json:"products"`
+	Complete int                  `json:"complete"`
+	Missing  []MissingTranslation `json:"missing"`
+}
+
+// MissingTranslation names the untranslated fields of a product
+type MissingTranslation struct {
+	ID     int      `json:"id"`
+	Name   string   `json:"name"`
+	Fields []string `json:"fields"`
+}
+
+// WithLocales enables localized reads with ?locale= and Accept-Language,
+// resolving translations into the given locales
+func (h *ProductHandler) WithLocales(locales *i18n.Locales) *ProductHandler {
+	h.locales = locales
+	return h
+}
+
+// localize replaces the texts of products in place with their translations
+// into the locale asked for with ?locale=, or else Accept-Language. Texts
+// without a translation fall back along the chain of the request, ending
+// with the default locale. Reads that ask for no locale are left as stored.
+func (h *ProductHandler) localize(c *gin.Context, products []models.Product) *Error {
+	chain, herr := h.localeChain(c)
+	if herr != nil || chain == nil {
+		return herr
+	}
+
+	for i := range products {
+		products[i] = products[i].Localize(chain, h.locales.Default)
+	}
+	return nil
+}
+
+// localeChain returns the lookup order of translations for the request, or
+// nil when it asks for no locale
+func (h *ProductHandler) localeChain(c *gin.Context) ([]string, *Error) {
+	if h.locales == nil {
+		return nil, nil
+	}
+	c.Header("Vary", "Accept-Language")
+
+	var preferred []string
+	if value, ok := c.GetQuery("locale"); ok {
+		tag, valid := i18n.Normalize(value)
+		if !valid || !h.locales.Supports(tag) {
+			return nil, ErrUnsupportedLocale.withDetail("use one of " + strings.Join(h.locales.Supported, ", "))
+		}
+		preferred = []string{tag}
+	} else if header := c.GetHeader("Accept-Language"); header != "" {
+		preferred = i18n.ParseAcceptLanguage(header)
+	} else {
+		return nil, nil
+	}
+
+	chain := h.locales.Chain(preferred)
+	c.Header("Content-Language", chain[0])
+	return chain, nil
+}
+
+// MissingTranslations reports, for every translated locale or the one given
+// with ?locale=, the live products whose name, description or specification
+// labels are not translated into it
+func (h *ProductHandler) MissingTranslations(c *gin.Context) {
+	locales := h.locales.Translated()
+	if value, ok := c.GetQuery("locale"); ok {
+		tag, valid := i18n.Normalize(value)
+		// A regional tag reports on the supported locale it resolves to
+		if valid {
+			tag = h.locales.Chain([]string{tag})[0]
+		}
+		if !valid || tag == h.locales.Default {
+			HandleError(c, ErrUnsupportedLocale.withDetail("use one of "+strings.Join(locales, ", ")))
+			return
+		}
+		locales = []string{tag}
+	}
+
+	products, err := h.repo.LoadProducts()
+	if err != nil {
+		HandleError(c, storageError(err, ErrFailedToLoad))
+		return
+	}
+
+	reports := make([]TranslationReport, 0, len(locales))
+	for _, locale := range locales {
+		report := TranslationReport{Locale: locale, Missing: make([]MissingTranslation, 0)}
+		for _, p := range products {
+			if p.IsDeleted() {
+				continue
+			}
+			report.Products++
+			if fields := p.MissingTranslations(locale); len(fields) > 0 {
+				report.Missing = append(report.Missing, MissingTranslation{ID: p.ID, Name: p.Name, Fields: fields})
+			} else {
+				report.Complete++
+			}
+		}
+		reports = append(reports, report)
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
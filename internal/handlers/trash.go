@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListTrash lists the deleted products, with the same query parameters as
+// GetAllProducts
+func (h *ProductHandler) ListTrash(c *gin.Context) {
+	filter, herr := parseProductFilter(c)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+	filter.Deleted = repositories.OnlyDeleted
+
+	products, herr := h.listProducts(filter)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+
+	c.JSON(http.StatusOK, products)
+}
+
+// RestoreProduct takes a product back out of the trash
+func (h *ProductHandler) RestoreProduct(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		HandleError(c, ErrInvalidID)
+		return
+	}
+
+	var restored models.Product
+	err = h.update(c, repositories.RevisionRestore, func(tx *repositories.ProductTx) error {
+		p, found := tx.Get(id)
+		if !found {
+			return ErrNotFound
+		}
+		if !p.IsDeleted() {
+			return ErrNotInTrash
+		}
+		if herr := h.checkIfMatch(c, p); herr != nil {
+			return herr
+		}
+
+		p.DeletedAt = nil
+		restored, _ = tx.Replace(p)
+		return nil
+	})
+	if err != nil {
+		HandleError(c, updateError(err))
+		return
+	}
+
+	c.Header("ETag", restored.ETag())
+	c.JSON(http.StatusOK, restored)
+}
+
+// WithPurgeRecords makes purges also drop the records products have in
+// other stores
+func (h *ProductHandler) WithPurgeRecords(records ...repositories.ProductRecords) *ProductHandler {
+	h.purgeRecords = records
+	return h
+}
+
+// PurgeTrash permanently removes the products in the trash. With
+// older_than=<duration> only products deleted longer ago than that are purged.
+func (h *ProductHandler) PurgeTrash(c *gin.Context) {
+	var cutoff time.Time
+	if olderThan := c.Query("older_than"); olderThan != "" {
+		d, err := time.ParseDuration(olderThan)
+		if err != nil || d < 0 {
+			HandleError(c, ErrInvalidOlderThan)
+			return
+		}
+		cutoff = time.Now().Add(-d)
+	}
+
+	purged, err := repositories.PurgeTrash(h.repo, cutoff, h.purgeRecords...)
+	if err != nil {
+		HandleError(c, storageError(err, ErrFailedToSave))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"purged": purged})
+}
+
+// PurgeProduct permanently removes a single product from the trash
+func (h *ProductHandler) PurgeProduct(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		HandleError(c, ErrInvalidID)
+		return
+	}
+
+	purged, err := repositories.PurgeProducts(h.repo, func(p models.Product) bool {
+		return p.ID == id && p.IsDeleted()
+	}, h.purgeRecords...)
+	if err != nil {
+		HandleError(c, updateError(err))
+		return
+	}
+	if len(purged) == 0 {
+		HandleError(c, ErrNotInTrash)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"item-comparison-ai-api/internal/i18n"
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/money"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Field error codes
+const (
+	CodeRequired      = "required"
+	CodeTooSmall      = "too_small"
+	CodeTooLarge      = "too_large"
+	CodeTooShort      = "too_short"
+	CodeTooLong       = "too_long"
+	CodeInvalidFormat = "invalid_format"
+	CodeInvalidType   = "invalid_type"
+	CodeInvalid       = "invalid"
+)
+
+// FieldError describes one invalid field of a request. Field is the JSON
+// name, with specifications written as specifications[<key>].
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a product
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+// Error lets an update function return a ValidationError
+func (e *ValidationError) Error() string {
+	return ErrValidation.Message
+}
+
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+		v.RegisterValidation("url_or_path", validURLOrPath)
+		v.RegisterValidation("sku", validSKU)
+		v.RegisterValidation("gtin", func(fl validator.FieldLevel) bool {
+			return models.ValidGTIN(fl.Field().String())
+		})
+		v.RegisterValidation("slug", func(fl validator.FieldLevel) bool {
+			return models.ValidSlug(fl.Field().String())
+		})
+		v.RegisterValidation("locale", func(fl validator.FieldLevel) bool {
+			_, ok := i18n.Normalize(fl.Field().String())
+			return ok
+		})
+		v.RegisterStructValidation(validateMoney, money.Money{})
+	}
+}
+
+// validateMoney checks a price: a known currency, no negative amount and no
+// more decimals than the currency has. An unset price is valid.
+func validateMoney(sl validator.StructLevel) {
+	m := sl.Current().Interface().(money.Money)
+	if m.IsZero() {
+		return
+	}
+
+	currency := m.Currency
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+	if !money.ValidCurrency(currency) {
+		sl.ReportError(m.Currency, "currency", "Currency", "iso4217", "")
+		return
+	}
+	if m.Amount.Sign() < 0 {
+		sl.ReportError(m.Amount, "amount", "Amount", "gte", "0")
+	}
+	if places := money.MinorUnits(currency); m.Amount.Places() > places {
+		sl.ReportError(m.Amount, "amount", "Amount", "places", strconv.Itoa(places))
+	}
+}
+
+// maxSKULength bounds SKUs, which also appear in URLs
+const maxSKULength = 64
+
+// validSKU accepts up to 64 letters, digits, dots, dashes and underscores
+func validSKU(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	if value == "" || len(value) > maxSKULength {
+		return false
+	}
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// jsonFieldName makes validation errors use the JSON field names
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// validURLOrPath accepts absolute http(s) URLs and absolute paths
+func validURLOrPath(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	if strings.ContainsAny(value, " \t\r\n") {
+		return false
+	}
+	if strings.HasPrefix(value, "/") {
+		return !strings.HasPrefix(value, "//")
+	}
+
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// validateProduct checks p against the rules declared on models.Product
+func validateProduct(p models.Product) *ValidationError {
+	err := binding.Validator.ValidateStruct(p)
+	if err == nil {
+		return nil
+	}
+	return validationError(err)
+}
+
+// bindProduct decodes and validates the product in the request body and
+// answers the request when it is invalid
+func bindProduct(c *gin.Context, p *models.Product) bool {
+	return bindValid(c, p)
+}
+
+// bindValid decodes and validates the JSON request body into v and answers
+// the request when it is invalid
+func bindValid(c *gin.Context, v interface{}) bool {
+	err := c.ShouldBindJSON(v)
+	if err == nil {
+		return true
+	}
+
+	if verr := validationError(err); verr != nil {
+		handleValidationError(c, verr)
+	} else {
+		HandleError(c, ErrBindJSON)
+	}
+	return false
+}
+
+// validationError converts validator and JSON type errors into a
+// ValidationError; it returns nil for other errors
+func validationError(err error) *ValidationError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make([]FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			fields = append(fields, describeFieldError(fe))
+		}
+		return &ValidationError{Fields: fields}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		field := typeErr.Field
+		if key, ok := strings.CutPrefix(field, "specifications."); ok {
+			field = "specifications[" + key + "]"
+		}
+		return &ValidationError{Fields: []FieldError{{
+			Field:   field,
+			Code:    CodeInvalidType,
+			Message: fmt.Sprintf("must be of type %s, got %s", typeErr.Type, typeErr.Value),
+		}}}
+	}
+
+	// Price is the only decimal of a product
+	if errors.Is(err, money.ErrInvalidDecimal) {
+		return &ValidationError{Fields: []FieldError{{
+			Field:   "price",
+			Code:    CodeInvalidType,
+			Message: "must be a decimal number",
+		}}}
+	}
+
+	return nil
+}
+
+func describeFieldError(fe validator.FieldError) FieldError {
+	// Namespaces look like "Product.specifications[RAM]"
+	field := fe.Namespace()
+	if i := strings.Index(field, "."); i >= 0 {
+		field = field[i+1:]
+	}
+
+	// Errors of a specification key carry the key itself as value
+	subject := "value"
+	if strings.HasSuffix(field, "]") {
+		key := field[strings.Index(field, "[")+1 : len(field)-1]
+		if s, ok := fe.Value().(string); ok && s == key {
+			subject = "key"
+		}
+	}
+
+	isString := fe.Kind() == reflect.String
+	switch {
+	case fe.Tag() == "required":
+		return FieldError{field, CodeRequired, "is required"}
+	case fe.Tag() == "required_without":
+		return FieldError{field, CodeRequired, "is required, except for variants"}
+	case fe.Tag() == "url_or_path":
+		return FieldError{field, CodeInvalidFormat, "must be an absolute http(s) URL or a path starting with /"}
+	case fe.Tag() == "sku":
+		return FieldError{field, CodeInvalidFormat, "must be at most 64 letters, digits, dots, dashes or underscores"}
+	case fe.Tag() == "iso4217":
+		return FieldError{field, CodeInvalidFormat, "must be an ISO 4217 currency code"}
+	case fe.Tag() == "places":
+		return FieldError{field, CodeInvalidFormat, "must have at most " + fe.Param() + " decimal places in this currency"}
+	case fe.Tag() == "locale":
+		return FieldError{field, CodeInvalidFormat, "must be a language tag such as de or pt-BR"}
+	case fe.Tag() == "slug":
+		return FieldError{field, CodeInvalidFormat, "must be at most 64 lower case letters and digits separated by single dashes"}
+	case fe.Tag() == "iso3166_1_alpha2":
+		return FieldError{field, CodeInvalidFormat, "must be an ISO 3166-1 alpha-2 country code"}
+	case fe.Tag() == "gtin":
+		return FieldError{field, CodeInvalidFormat, "must be a GTIN-8, 12, 13 or 14 with a valid check digit"}
+	case (fe.Tag() == "min" || fe.Tag() == "gte") && isString:
+		return FieldError{field, CodeTooShort, fmt.Sprintf("%s must be at least %s characters", subject, fe.Param())}
+	case (fe.Tag() == "max" || fe.Tag() == "lte") && isString:
+		return FieldError{field, CodeTooLong, fmt.Sprintf("%s must be at most %s characters", subject, fe.Param())}
+	case fe.Tag() == "min" || fe.Tag() == "gte":
+		return FieldError{field, CodeTooSmall, "must be at least " + fe.Param()}
+	case fe.Tag() == "max" || fe.Tag() == "lte":
+		return FieldError{field, CodeTooLarge, "must be at most " + fe.Param()}
+	default:
+		return FieldError{field, CodeInvalid, "failed the " + fe.Tag() + " rule"}
+	}
+}
+
+// handleValidationError sends the field errors of an invalid product
+func handleValidationError(c *gin.Context, verr *ValidationError) {
+	c.JSON(ErrValidation.Code, gin.H{
+		"error":  ErrValidation.Message,
+		"fields": verr.Fields,
+	})
+}
+
+// respondUpdateError answers a request whose repository update failed
+func respondUpdateError(c *gin.Context, err error) {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		handleValidationError(c, verr)
+		return
+	}
+	HandleError(c, updateError(err))
+}
+
+// errorDetails splits an error of an update function into the parts that
+// bulk and import results report
+func errorDetails(err error) (status int, message string, fields []FieldError) {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		return ErrValidation.Code, ErrValidation.Message, verr.Fields
+	}
+	herr := updateError(err)
+	return herr.Code, herr.Message, nil
+}
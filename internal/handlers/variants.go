@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/money"
+	"item-comparison-ai-api/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCompareProducts caps how many products one comparison shows
+const maxCompareProducts = 50
+
+// CollapsedProduct is a listed product together with the number of its live
+// variants, which the collapsed listing leaves out
+type CollapsedProduct struct {
+	models.Product
+	VariantCount int `json:"variant_count"`
+}
+
+// Comparison shows products side by side. Differences names the fields whose
+// values are not the same for every product, with specifications written as
+// specifications.<key>; Axes are the variant axes of a variant comparison.
+// Lowest30Days is the lowest price of each product in the last 30 days, by
+// product ID, when prices are tracked.
+type Comparison struct {
+	Products     []models.Product    `json:"products"`
+	Differences  []string            `json:"differences"`
+	Axes         []string            `json:"axes,omitempty"`
+	Lowest30Days map[int]money.Money `json:"lowest_30_days,omitempty"`
+	// Brands groups the products by brand when any of them has one
+	Brands []BrandGroup `json:"brands,omitempty"`
+}
+
+// collapseVariants adds the variant count to every product of a listing
+func (h *ProductHandler) collapseVariants(products []models.Product) ([]CollapsedProduct, error) {
+	counts := make(map[int]int)
+	hasAxes := false
+	for _, p := range products {
+		hasAxes = hasAxes || len(p.VariantAxes) > 0
+	}
+	if hasAxes {
+		all, err := h.repo.LoadProducts()
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range all {
+			if p.IsVariant() && !p.IsDeleted() {
+				counts[p.ParentID]++
+			}
+		}
+	}
+
+	collapsed := make([]CollapsedProduct, len(products))
+	for i, p := range products {
+		collapsed[i] = CollapsedProduct{Product: p, VariantCount: counts[p.ID]}
+	}
+	return collapsed, nil
+}
+
+// ListVariants lists the live variants of a product, ordered by ID
+func (h *ProductHandler) ListVariants(c *gin.Context) {
+	parent, variants, herr := h.loadVariants(c)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+
+	if herr := h.presentProducts(c, variants); herr != nil {
+		HandleError(c, herr)
+		return
+	}
+	c.Header("ETag", parent.ETag())
+	c.JSON(http.StatusOK, variants)
+}
+
+// CompareVariants compares the live variants of a product against each other
+func (h *ProductHandler) CompareVariants(c *gin.Context) {
+	parent, variants, herr := h.loadVariants(c)
+	if herr != nil {
+		HandleError(c, herr)
+		return
+	}
+
+	if herr := h.presentProducts(c, variants); herr != nil {
+		HandleError(c, herr)
+		return
+	}
+	comparison := compareProducts(variants)
+	comparison.Axes = parent.VariantAxes
+	if herr := h.addLowestPrices(c, &comparison); herr != nil {
+		HandleError(c, herr)
+		return
+	}
+	if herr := h.addBrandGroups(&comparison); herr != nil {
+		HandleError(c, herr)
+		return
+	}
+	c.JSON(http.StatusOK, comparison)
+}
+
+// loadVariants reads the parent of the URL and its live variants
+func (h *ProductHandler) loadVariants(c *gin.Context) (models.Product, []models.Product, *Error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return models.Product{}, nil, ErrInvalidID
+	}
+
+	products, err := h.repo.LoadProducts()
+	if err != nil {
+		return models.Product{}, nil, storageError(err, ErrFailedToLoad)
+	}
+
+	var (
+		parent   models.Product
+		found    bool
+		variants = make([]models.Product, 0)
+	)
+	for _, p := range products {
+		switch {
+		case p.IsDeleted():
+		case p.ID == id:
+			parent, found = p, true
+		case p.ParentID == id:
+			variants = append(variants, p)
+		}
+	}
+	if !found {
+		return models.Product{}, nil, ErrNotFound
+	}
+	sort.Slice(variants, func(i, j int) bool { return variants[i].ID < variants[j].ID })
+
+	return parent, variants, nil
+}
+
+// CompareProducts compares the products given as ?ids=1,2,3, in that order
+func (h *ProductHandler) CompareProducts(c *gin.Context) {
+	var ids []int
+	for _, field := range strings.Split(c.Query("ids"), ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			HandleError(c, ErrInvalidCompareIDs)
+			return
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) < 2 || len(ids) > maxCompareProducts {
+		HandleError(c, ErrInvalidCompareIDs)
+		return
+	}
+
+	products, err := h.repo.LoadProducts()
+	if err != nil {
+		HandleError(c, storageError(err, ErrFailedToLoad))
+		return
+	}
+
+	byID := make(map[int]models.Product, len(products))
+	for _, p := range products {
+		if !p.IsDeleted() {
+			byID[p.ID] = p
+		}
+	}
+
+	compared := make([]models.Product, 0, len(ids))
+	for _, id := range ids {
+		p, ok := byID[id]
+		if !ok {
+			HandleError(c, ErrNotFound.withDetail("product "+strconv.Itoa(id)))
+			return
+		}
+		compared = append(compared, p)
+	}
+	if herr := h.presentProducts(c, compared); herr != nil {
+		HandleError(c, herr)
+		return
+	}
+
+	comparison := compareProducts(compared)
+	if herr := h.addLowestPrices(c, &comparison); herr != nil {
+		HandleError(c, herr)
+		return
+	}
+	if herr := h.addBrandGroups(&comparison); herr != nil {
+		HandleError(c, herr)
+		return
+	}
+	c.JSON(http.StatusOK, comparison)
+}
+
+// compareProducts lists the fields that differ between the products.
+// Identifiers always differ and are left out.
+func compareProducts(products []models.Product) Comparison {
+	differing := make(map[string]bool)
+	for i := 1; i < len(products); i++ {
+		for _, change := range repositories.DiffProducts(&products[0], &products[i]) {
+			differing[change.Field] = true
+		}
+	}
+	for _, field := range []string{"id", "sku", "gtin"} {
+		delete(differing, field)
+	}
+
+	differences := make([]string, 0, len(differing))
+	for field := range differing {
+		differences = append(differences, field)
+	}
+	sort.Strings(differences)
+
+	return Comparison{Products: products, Differences: differences}
+}
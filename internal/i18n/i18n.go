@@ -0,0 +1,141 @@
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Normalize checks that tag is a language tag such as "de" or "pt-BR" and
+// returns it in lower case, the form locales are compared and stored in
+func Normalize(tag string) (string, bool) {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	subtags := strings.Split(tag, "-")
+	if len(subtags[0]) < 2 || len(subtags[0]) > 8 {
+		return "", false
+	}
+	for i, subtag := range subtags {
+		if subtag == "" || len(subtag) > 8 {
+			return "", false
+		}
+		for _, r := range subtag {
+			isLetter := r >= 'a' && r <= 'z'
+			isDigit := r >= '0' && r <= '9'
+			if !isLetter && (i == 0 || !isDigit) {
+				return "", false
+			}
+		}
+	}
+	return tag, true
+}
+
+// Language returns the primary language of a normalized tag, "pt" for
+// "pt-br"
+func Language(tag string) string {
+	language, _, _ := strings.Cut(tag, "-")
+	return language
+}
+
+// ParseAcceptLanguage returns the normalized tags of an Accept-Language
+// header, most preferred first. Wildcards, malformed tags and tags with
+// q=0 are left out.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var ranges []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		normalized, ok := Normalize(tag)
+		if !ok || q <= 0 {
+			continue
+		}
+		ranges = append(ranges, weighted{normalized, q})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	tags := make([]string, len(ranges))
+	for i, r := range ranges {
+		tags[i] = r.tag
+	}
+	return tags
+}
+
+// Locales are the locales the catalog is translated into. The untranslated
+// product fields are in the default locale.
+type Locales struct {
+	Default   string
+	Supported []string
+}
+
+// NewLocales normalizes the locales, dropping malformed ones. The default
+// locale is always supported.
+func NewLocales(defaultLocale string, supported []string) Locales {
+	l := Locales{Default: "en"}
+	if tag, ok := Normalize(defaultLocale); ok {
+		l.Default = tag
+	}
+
+	l.Supported = []string{l.Default}
+	for _, s := range supported {
+		if tag, ok := Normalize(s); ok && !l.contains(tag) {
+			l.Supported = append(l.Supported, tag)
+		}
+	}
+	return l
+}
+
+// Translated returns the supported locales other than the default, the
+// ones products need translations for
+func (l Locales) Translated() []string {
+	return l.Supported[1:]
+}
+
+// Supports reports whether a normalized tag, or its language, is supported
+func (l Locales) Supports(tag string) bool {
+	return l.contains(tag) || l.contains(Language(tag))
+}
+
+// Chain returns the order translations are looked up in for the preferred
+// tags: every supported tag and then its language, ending with the default
+// locale
+func (l Locales) Chain(preferred []string) []string {
+	var chain []string
+	add := func(tag string) {
+		if !l.contains(tag) {
+			return
+		}
+		for _, c := range chain {
+			if c == tag {
+				return
+			}
+		}
+		chain = append(chain, tag)
+	}
+
+	for _, tag := range preferred {
+		add(tag)
+		add(Language(tag))
+	}
+	add(l.Default)
+	return chain
+}
+
+func (l Locales) contains(tag string) bool {
+	for _, s := range l.Supported {
+		if s == tag {
+			return true
+		}
+	}
+	return false
+}
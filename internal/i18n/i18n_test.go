@@ -0,0 +1,47 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"de", "de", true},
+		{"pt-BR", "pt-br", true},
+		{"zh_Hant_TW", "zh-hant-tw", true},
+		{"es-419", "es-419", true},
+		{"d", "", false},
+		{"de-", "", false},
+		{"1de", "", false},
+		{"*", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Normalize(tt.in)
+		assert.Equal(t, tt.ok, ok, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tags := ParseAcceptLanguage("fr;q=0.5, de-CH, de;q=0.9, *;q=0.1, en;q=0, x;q=1")
+	assert.Equal(t, []string{"de-ch", "de", "fr"}, tags)
+	assert.Empty(t, ParseAcceptLanguage(""))
+}
+
+func TestLocales_Chain(t *testing.T) {
+	locales := NewLocales("EN", []string{"en", "de", "fr", "not a tag"})
+	assert.Equal(t, []string{"en", "de", "fr"}, locales.Supported)
+	assert.Equal(t, []string{"de", "fr"}, locales.Translated())
+
+	assert.Equal(t, []string{"de", "fr", "en"}, locales.Chain([]string{"de-ch", "it", "fr"}))
+	assert.Equal(t, []string{"en"}, locales.Chain(nil))
+	assert.True(t, locales.Supports("de-at"))
+	assert.False(t, locales.Supports("it"))
+}
@@ -0,0 +1,140 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	// Registers the GIF decoder with image.Decode
+	_ "image/gif"
+)
+
+var (
+	// ErrUnsupportedType is returned for content that is not a JPEG, PNG or
+	// GIF image
+	ErrUnsupportedType = errors.New("unsupported image type")
+	// ErrTooManyPixels is returned by Decode for images larger than allowed
+	ErrTooManyPixels = errors.New("image has too many pixels")
+)
+
+// extensions maps the accepted content types to the file extension images of
+// that type are stored with
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Sniff detects the content type of an image from its first bytes, ignoring
+// whatever type the client declared, and returns it with its file extension
+func Sniff(data []byte) (contentType, ext string, err error) {
+	contentType = http.DetectContentType(data)
+	ext, ok := extensions[contentType]
+	if !ok {
+		return "", "", ErrUnsupportedType
+	}
+	return contentType, ext, nil
+}
+
+// Decode decodes an image. The dimensions are checked before the pixels are
+// decoded, so a small file cannot expand into a huge bitmap.
+func Decode(data []byte, maxPixels int) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, errors.New("image is empty")
+	}
+	if config.Width > maxPixels/config.Height {
+		return nil, ErrTooManyPixels
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// ThumbnailType returns the content type and extension thumbnails of an
+// image of the given type are encoded with: JPEG stays JPEG, everything else
+// becomes PNG so transparency is kept
+func ThumbnailType(contentType string) (string, string) {
+	if contentType == "image/jpeg" {
+		return "image/jpeg", ".jpg"
+	}
+	return "image/png", ".png"
+}
+
+// Encode writes img in the given thumbnail content type
+func Encode(w io.Writer, img image.Image, contentType string) error {
+	if contentType == "image/jpeg" {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	}
+	return png.Encode(w, img)
+}
+
+// Fit returns the size of a width×height image scaled down to fit in a
+// box×box square, keeping its aspect ratio. Images that already fit keep
+// their size.
+func Fit(width, height, box int) (int, int) {
+	if width <= box && height <= box {
+		return width, height
+	}
+	if width >= height {
+		return box, max(1, (height*box+width/2)/width)
+	}
+	return max(1, (width*box+height/2)/height), box
+}
+
+// RGBA returns img as an RGBA bitmap, converting it unless it already is
+// one. Uploads are converted once and every thumbnail is scaled from the
+// result.
+func RGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// Thumbnail scales src down to fit in a box×box square. Every thumbnail
+// pixel averages the block of source pixels it covers, which keeps detail
+// without the aliasing of nearest-neighbour sampling. A source that already
+// fits is returned as is.
+func Thumbnail(src *image.RGBA, box int) *image.RGBA {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	tw, th := Fit(w, h, box)
+	if tw == w && th == h {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := y*h/th, (y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := x*w/tw, (x+1)*w/tw
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[src.PixOffset(b.Min.X, b.Min.Y+sy):]
+				for sx := x0; sx < x1; sx++ {
+					for i, v := range row[sx*4 : sx*4+4] {
+						sum[i] += int(v)
+					}
+				}
+			}
+			n := (x1 - x0) * (y1 - y0)
+			out := dst.Pix[y*dst.Stride+x*4:]
+			for i, s := range sum {
+				out[i] = uint8((s + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
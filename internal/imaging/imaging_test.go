@@ -0,0 +1,85 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSniff(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))))
+
+	contentType, ext, err := Sniff(buf.Bytes())
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+	assert.Equal(t, ".png", ext)
+
+	_, _, err = Sniff([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"))
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+}
+
+func TestDecode_RejectsTooManyPixels(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 100, 100))))
+
+	_, err := Decode(buf.Bytes(), 100*99)
+	assert.ErrorIs(t, err, ErrTooManyPixels)
+	img, err := Decode(buf.Bytes(), 100*100)
+	assert.NoError(t, err)
+	assert.Equal(t, 100, img.Bounds().Dx())
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		w, h, box    int
+		wantW, wantH int
+	}{
+		{800, 400, 160, 160, 80},
+		{400, 800, 160, 80, 160},
+		{100, 50, 160, 100, 50},
+		{1000, 1, 160, 160, 1},
+	}
+	for _, tt := range tests {
+		w, h := Fit(tt.w, tt.h, tt.box)
+		assert.Equal(t, tt.wantW, w, "%dx%d", tt.w, tt.h)
+		assert.Equal(t, tt.wantH, h, "%dx%d", tt.w, tt.h)
+	}
+}
+
+func TestThumbnail_AveragesPixels(t *testing.T) {
+	// Alternating black and white columns average to grey
+	src := image.NewRGBA(image.Rect(10, 10, 14, 12))
+	for x := 10; x < 14; x++ {
+		for y := 10; y < 12; y++ {
+			if x%2 == 0 {
+				src.Set(x, y, color.White)
+			} else {
+				src.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	thumb := Thumbnail(src, 2)
+	assert.Equal(t, image.Rect(0, 0, 2, 1), thumb.Bounds())
+	assert.Equal(t, color.RGBA{128, 128, 128, 255}, thumb.RGBAAt(0, 0))
+	assert.Equal(t, color.RGBA{128, 128, 128, 255}, thumb.RGBAAt(1, 0))
+}
+
+func TestRGBA(t *testing.T) {
+	rgba := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	assert.Same(t, rgba, RGBA(rgba))
+
+	gray := image.NewGray(image.Rect(5, 5, 8, 7))
+	gray.SetGray(5, 5, color.Gray{Y: 200})
+	converted := RGBA(gray)
+	assert.Equal(t, image.Rect(0, 0, 3, 2), converted.Bounds())
+	assert.Equal(t, color.RGBA{200, 200, 200, 255}, converted.RGBAAt(0, 0))
+
+	// A source that fits is shared by the thumbnails, not copied
+	assert.Same(t, converted, Thumbnail(converted, 160))
+}
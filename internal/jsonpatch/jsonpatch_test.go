@@ -0,0 +1,84 @@
+package jsonpatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	// Examples from RFC 7396, appendix A
+	cases := []struct{ doc, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+
+	for _, c := range cases {
+		got, err := MergePatch([]byte(c.doc), []byte(c.patch))
+		assert.NoError(t, err)
+		assert.JSONEq(t, c.want, string(got), "patch %s", c.patch)
+	}
+}
+
+func TestApply(t *testing.T) {
+	// Examples from RFC 6902, appendix A
+	cases := []struct{ doc, patch, want string }{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"foo":null}`, `[{"op":"test","path":"/foo","value":null}]`, `{"foo":null}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+		{`{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`},
+	}
+
+	for _, c := range cases {
+		got, err := Apply([]byte(c.doc), []byte(c.patch))
+		assert.NoError(t, err, "patch %s", c.patch)
+		assert.JSONEq(t, c.want, string(got), "patch %s", c.patch)
+	}
+}
+
+func TestApply_Errors(t *testing.T) {
+	doc := []byte(`{"foo":"bar","list":[1]}`)
+
+	_, err := Apply(doc, []byte(`[{"op":"test","path":"/foo","value":"baz"}]`))
+	assert.ErrorIs(t, err, ErrTestFailed)
+
+	_, err = Apply(doc, []byte(`[{"op":"remove","path":"/missing"}]`))
+	assert.ErrorIs(t, err, ErrPathNotFound)
+	_, err = Apply(doc, []byte(`[{"op":"replace","path":"/list/1","value":2}]`))
+	assert.ErrorIs(t, err, ErrPathNotFound)
+	_, err = Apply(doc, []byte(`[{"op":"add","path":"/missing/child","value":1}]`))
+	assert.ErrorIs(t, err, ErrPathNotFound)
+
+	for _, patch := range []string{
+		`[{"op":"add","path":"/baz"}]`,
+		`[{"op":"jump","path":"/foo"}]`,
+		`[{"op":"add","path":"foo","value":1}]`,
+		`[{"op":"add","path":"/list/01","value":1}]`,
+		`[{"op":"move","from":"/list","path":"/list/0"}]`,
+		`{"op":"add"}`,
+	} {
+		_, err := Apply(doc, []byte(patch))
+		assert.Error(t, err, patch)
+	}
+
+	// A failing operation leaves nothing half applied for the caller
+	_, err = Apply(doc, []byte(`[{"op":"replace","path":"/foo","value":"x"},{"op":"test","path":"/foo","value":"bar"}]`))
+	assert.ErrorIs(t, err, ErrTestFailed)
+}
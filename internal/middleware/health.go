@@ -10,7 +10,7 @@ import (
 )
 
 // Health ....
-func Health(db *database.Database, c *config.AppConfig) gin.HandlerFunc {
+func Health(db database.FileStore, c *config.AppConfig) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		if err := db.CheckLiveness(c.DatabasePath); err != nil {
 			ctx.JSON(http.StatusInternalServerError, map[string]interface{}{
@@ -0,0 +1,137 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"item-comparison-ai-api/internal/database"
+	h "item-comparison-ai-api/internal/handlers"
+	"item-comparison-ai-api/internal/repositories"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// IdempotencyKeyHeader carries the client chosen key of a request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from the store
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// responseRecorder keeps a copy of the response body while writing it
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes requests sent with an Idempotency-Key safe to retry. The
+// first request with a key runs and its response is stored; a retry with the
+// same key and body gets that response back instead of running again. Reusing
+// a key for a different request answers 422, and a retry arriving while the
+// first request still runs answers 409. A key whose request panicked is freed
+// at once, and one whose process died frees itself after a minute. Requests without the header are
+// passed through.
+func Idempotency(store *repositories.IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			h.HandleError(c, h.ErrInvalidIdempotencyKey)
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			h.HandleError(c, h.ErrBindJSON)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := requestHash(c.Request.Method, c.FullPath(), body)
+		existing, reserved, err := store.Reserve(key, hash)
+		if err != nil {
+			logrus.WithError(err).Error("failed to reserve idempotency key")
+			if errors.Is(err, database.ErrLockTimeout) {
+				h.HandleError(c, h.ErrStorageBusy)
+			} else {
+				h.HandleError(c, h.ErrFailedToSave)
+			}
+			c.Abort()
+			return
+		}
+
+		if !reserved {
+			switch {
+			case existing.Hash != hash:
+				h.HandleError(c, h.ErrIdempotencyKeyReused)
+			case !existing.Completed:
+				h.HandleError(c, h.ErrIdempotencyKeyInUse)
+			default:
+				c.Header(IdempotentReplayedHeader, "true")
+				if existing.ETag != "" {
+					c.Header("ETag", existing.ETag)
+				}
+				c.Data(existing.Status, existing.ContentType, existing.Body)
+			}
+			c.Abort()
+			return
+		}
+
+		// A panicking handler never completes the key; free it before the
+		// panic reaches the recovery middleware so the client can retry
+		defer func() {
+			if p := recover(); p != nil {
+				if err := store.Release(key); err != nil {
+					logrus.WithError(err).WithField("key", key).Error("failed to release idempotency key")
+				}
+				panic(p)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Server errors are not stored so that the client can retry them
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			err = store.Release(key)
+		} else {
+			header := recorder.Header()
+			err = store.Complete(key, status, header.Get("Content-Type"), header.Get("ETag"), recorder.body.Bytes())
+		}
+		if err != nil {
+			logrus.WithError(err).WithField("key", key).Error("failed to store idempotent response")
+		}
+	}
+}
+
+// requestHash fingerprints the route and body of a request
+func requestHash(method, route string, body []byte) string {
+	sum := sha256.New()
+	sum.Write([]byte(method + " " + route + "\n"))
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
@@ -0,0 +1,162 @@
+// Package migrations owns the on-disk format of the product catalog. Data
+// files carry a version number and are upgraded step by step on load, so
+// older files keep working when models.Product changes.
+package migrations
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"item-comparison-ai-api/internal/models"
+)
+
+// LegacyVersion is the implicit version of a bare JSON array of products
+const LegacyVersion = 1
+
+// ErrUnsupportedVersion is returned for files written by a newer release
+var ErrUnsupportedVersion = errors.New("unsupported data file version")
+
+// Document is the versioned data file layout
+type Document struct {
+	Version int `json:"version"`
+	// LastID is the highest product ID ever assigned, purged products
+	// included, so that their IDs are never handed out again
+	LastID   int              `json:"last_id,omitempty"`
+	Products []models.Product `json:"products"`
+}
+
+// Step upgrades a decoded document from version From to From+1. Documents
+// are handled as generic JSON values so a step never depends on the current
+// shape of models.Product.
+type Step struct {
+	From        int
+	Description string
+	Migrate     func(doc map[string]interface{}) error
+}
+
+var steps = map[int]Step{}
+
+// Register adds a migration step. Steps must form a contiguous chain starting
+// at LegacyVersion.
+func Register(step Step) {
+	if _, dup := steps[step.From]; dup {
+		panic(fmt.Sprintf("migrations: step from version %d registered twice", step.From))
+	}
+	steps[step.From] = step
+}
+
+// CurrentVersion is the version written by Encode
+func CurrentVersion() int {
+	return LegacyVersion + len(steps)
+}
+
+// Steps returns the registered steps in order
+func Steps() []Step {
+	result := make([]Step, 0, len(steps))
+	for _, step := range steps {
+		result = append(result, step)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].From < result[j].From })
+	return result
+}
+
+// Upgrade parses a data file of any known version and migrates it to the
+// current one. It returns the upgraded generic document and the version the
+// file was written in.
+func Upgrade(data []byte) (map[string]interface{}, int, error) {
+	doc, version, err := parse(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	current := CurrentVersion()
+	if version > current {
+		return nil, version, fmt.Errorf("%w: file is version %d, this build supports up to %d", ErrUnsupportedVersion, version, current)
+	}
+
+	for v := version; v < current; v++ {
+		step, ok := steps[v]
+		if !ok {
+			return nil, version, fmt.Errorf("migrations: no step from version %d", v)
+		}
+		if err := step.Migrate(doc); err != nil {
+			return nil, version, fmt.Errorf("migrations: %d -> %d (%s): %w", v, v+1, step.Description, err)
+		}
+		doc["version"] = float64(v + 1)
+	}
+
+	return doc, version, nil
+}
+
+// parse decodes the raw file into a generic document and detects its version
+func parse(data []byte) (map[string]interface{}, int, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return map[string]interface{}{"version": float64(CurrentVersion()), "products": []interface{}{}}, CurrentVersion(), nil
+	}
+
+	if trimmed[0] == '[' {
+		var products []interface{}
+		if err := json.Unmarshal(trimmed, &products); err != nil {
+			return nil, 0, err
+		}
+		return map[string]interface{}{"version": float64(LegacyVersion), "products": products}, LegacyVersion, nil
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(trimmed, &doc); err != nil {
+		return nil, 0, err
+	}
+	version, ok := doc["version"].(float64)
+	if !ok || version < LegacyVersion || version != float64(int(version)) {
+		return nil, 0, errors.New("migrations: data file has no valid version")
+	}
+
+	return doc, int(version), nil
+}
+
+// Decode reads a data file of any supported version into products
+func Decode(data []byte) ([]models.Product, error) {
+	doc, err := DecodeDocument(data)
+	return doc.Products, err
+}
+
+// DecodeDocument reads a data file of any supported version
+func DecodeDocument(data []byte) (Document, error) {
+	doc, _, err := Upgrade(data)
+	if err != nil {
+		return Document{}, err
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return Document{}, err
+	}
+
+	var decoded Document
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return Document{}, err
+	}
+	if decoded.Products == nil {
+		decoded.Products = make([]models.Product, 0)
+	}
+
+	return decoded, nil
+}
+
+// Encode writes products as a document of the current version
+func Encode(products []models.Product) ([]byte, error) {
+	return EncodeDocument(Document{Products: products})
+}
+
+// EncodeDocument writes doc in the current version
+func EncodeDocument(doc Document) ([]byte, error) {
+	doc.Version = CurrentVersion()
+	if doc.Products == nil {
+		doc.Products = make([]models.Product, 0)
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"encoding/json"
+	"testing"
+
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/money"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode_LegacyArray(t *testing.T) {
+	products, err := Decode([]byte(`[{"id":1,"name":"Laptop"}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Product{{ID: 1, Name: "Laptop", Price: money.New(money.Decimal{}, "USD")}}, products)
+}
+
+func TestDecode_FloatPricesBecomeUSD(t *testing.T) {
+	products, err := Decode([]byte(`{"version":2,"products":[{"id":1,"name":"Laptop","price":1200.5},{"id":2,"name":"Mouse","price":0.1}]}`))
+	assert.NoError(t, err)
+	if assert.Len(t, products, 2) {
+		assert.Equal(t, money.New(money.MustParseDecimal("1200.5"), "USD"), products[0].Price)
+		assert.Equal(t, money.New(money.MustParseDecimal("0.1"), "USD"), products[1].Price)
+	}
+}
+
+func TestDecode_EmptyFile(t *testing.T) {
+	products, err := Decode(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, products)
+}
+
+func TestDecode_RefusesNewerVersion(t *testing.T) {
+	_, err := Decode([]byte(`{"version":999,"products":[]}`))
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+}
+
+func TestDecode_RequiresVersion(t *testing.T) {
+	_, err := Decode([]byte(`{"products":[]}`))
+	assert.Error(t, err)
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	products := []models.Product{{ID: 1, Name: "Laptop", Specifications: map[string]string{"RAM": "16GB"}}}
+
+	data, err := Encode(products)
+	assert.NoError(t, err)
+
+	var doc map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, float64(CurrentVersion()), doc["version"])
+
+	decoded, err := Decode(data)
+	assert.NoError(t, err)
+	assert.Equal(t, products, decoded)
+}
+
+func TestSteps_FormContiguousChain(t *testing.T) {
+	for i, step := range Steps() {
+		assert.Equal(t, LegacyVersion+i, step.From)
+	}
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Every change to the persisted shape of models.Product adds a step here.
+func init() {
+	Register(Step{
+		From:        1,
+		Description: "wrap the bare product array in a versioned document",
+		// parse already lifted the legacy array into the "products" key, so
+		// only the version bump done by Upgrade is left.
+		Migrate: func(doc map[string]interface{}) error { return nil },
+	})
+	Register(Step{
+		From:        2,
+		Description: "turn the float price into an amount and currency",
+		// Prices had no currency before, they were all US dollars
+		Migrate: func(doc map[string]interface{}) error {
+			products, _ := doc["products"].([]interface{})
+			for i, item := range products {
+				product, ok := item.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("product at index %d is not an object", i)
+				}
+				switch price := product["price"].(type) {
+				case float64:
+					product["price"] = map[string]interface{}{"amount": strconv.FormatFloat(price, 'f', -1, 64), "currency": "USD"}
+				case nil:
+					product["price"] = map[string]interface{}{"amount": "0", "currency": "USD"}
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"strings"
+)
+
+// Brand is the manufacturer or label products are sold under
+type Brand struct {
+	ID   int    `json:"id"`
+	Name string `json:"name" binding:"required,max=100"`
+	// Slug identifies the brand in URLs; it is derived from the name when
+	// left empty
+	Slug string `json:"slug" binding:"omitempty,slug"`
+	// Country is the ISO 3166-1 alpha-2 code of the brand's home country
+	Country string `json:"country,omitempty" binding:"omitempty,iso3166_1_alpha2"`
+	LogoURL string `json:"logo_url,omitempty" binding:"omitempty,url_or_path"`
+}
+
+// MaxSlugLength bounds brand slugs
+const MaxSlugLength = 64
+
+// Slugify derives a slug from a name: lower case ASCII letters and digits,
+// with every other run of characters turned into one dash
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(r)
+			if b.Len() >= MaxSlugLength {
+				break
+			}
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// ValidSlug reports whether s is a slug as Slugify makes them
+func ValidSlug(s string) bool {
+	return s != "" && len(s) <= MaxSlugLength && Slugify(s) == s
+}
@@ -0,0 +1,37 @@
+package models
+
+import "strings"
+
+// ValidGTIN reports whether s is a GTIN-8, GTIN-12 (UPC-A), GTIN-13 (EAN-13)
+// or GTIN-14 with a correct check digit
+func ValidGTIN(s string) bool {
+	switch len(s) {
+	case 8, 12, 13, 14:
+	default:
+		return false
+	}
+
+	sum := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		digit := int(c - '0')
+		// Counting from the check digit, every second digit weighs 3
+		if (len(s)-1-i)%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+// NormalizeGTIN pads a GTIN to 14 digits, so that the same item written as
+// UPC-A and as EAN-13 compares equal
+func NormalizeGTIN(s string) string {
+	if len(s) >= 14 {
+		return s
+	}
+	return strings.Repeat("0", 14-len(s)) + s
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Image is a picture of a product uploaded through the image endpoints
+type Image struct {
+	ID          string `json:"id"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	Size        int64  `json:"size"`
+	// Thumbnails are scaled down copies, smallest first
+	Thumbnails []Thumbnail `json:"thumbnails"`
+	UploadedAt time.Time   `json:"uploaded_at"`
+}
+
+// Thumbnail is a scaled down copy of an image
+type Thumbnail struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// WithImages sets the image gallery of the product. The primary image,
+// ImageURL, becomes the first image of the gallery when it was empty or
+// pointed to an image that is no longer in it.
+func (p Product) WithImages(images []Image) Product {
+	primary := p.ImageURL == ""
+	for _, img := range p.Images {
+		if img.URL == p.ImageURL {
+			primary = true
+		}
+	}
+	for _, img := range images {
+		if img.URL == p.ImageURL {
+			primary = false
+		}
+	}
+	if primary {
+		p.ImageURL = ""
+		if len(images) > 0 {
+			p.ImageURL = images[0].URL
+		}
+	}
+
+	p.Images = images
+	return p
+}
@@ -1,13 +1,62 @@
 package models
 
-// Product represents the model for a product
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"item-comparison-ai-api/internal/money"
+)
+
+// Product represents the model for a product. The binding tags declare the
+// validation rules applied to every product written through the API.
 type Product struct {
 	ID             int               `json:"id"`
-	Name           string            `json:"name"`
-	ImageURL       string            `json:"image_url"`
+	SKU            string            `json:"sku,omitempty" binding:"omitempty,sku"`
+	GTIN           string            `json:"gtin,omitempty" binding:"omitempty,gtin"`
+	Name           string            `json:"name" binding:"required_without=ParentID"`
+	ImageURL       string            `json:"image_url" binding:"omitempty,url_or_path"`
 	Description    string            `json:"description"`
-	Price          float64           `json:"price"`
+	Price          money.Money       `json:"price"`
 	Rating         float64           `json:"rating"`
-	Specifications map[string]string `json:"specifications"`
+	Specifications map[string]string `json:"specifications" binding:"dive,keys,min=1,max=64,endkeys,max=256"`
 	Category       string            `json:"category"`
+	// BrandID links the product to a brand; zero leaves it unbranded
+	BrandID int `json:"brand_id,omitempty" binding:"gte=0"`
+	// Rating, ReviewCount and RatingDistribution, the number of approved
+	// reviews by stars, are computed from the reviews; values sent by
+	// clients are ignored
+	ReviewCount        int         `json:"review_count"`
+	RatingDistribution map[int]int `json:"rating_distribution,omitempty"`
+	// Images is the gallery uploaded through the image endpoints; values
+	// sent by clients are ignored
+	Images []Image `json:"images,omitempty"`
+	// Translations holds the name, description and specification labels
+	// by locale; the fields above are in the default locale
+	Translations map[string]Translation `json:"translations,omitempty" binding:"dive,keys,locale,endkeys"`
+	// SpecLabels are the specification labels of a localized read; they
+	// are never stored
+	SpecLabels map[string]string `json:"spec_labels,omitempty"`
+	// ParentID makes the product a variant of the product with that ID
+	ParentID int `json:"parent_id,omitempty" binding:"gte=0"`
+	// VariantAxes are the specification keys the variants of a parent
+	// differ by, e.g. Color and Storage
+	VariantAxes []string `json:"variant_axes,omitempty" binding:"omitempty,dive,min=1,max=64"`
+	// DeletedAt is set while the product is in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// IsDeleted reports whether the product is in the trash
+func (p Product) IsDeleted() bool {
+	return p.DeletedAt != nil
+}
+
+// ETag returns a strong entity tag derived from the product content. Any
+// change to a field, including a single specification, yields a new tag.
+func (p Product) ETag() string {
+	// encoding/json sorts map keys, so equal products always encode equally
+	data, _ := json.Marshal(p)
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
 }
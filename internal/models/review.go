@@ -0,0 +1,74 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Review statuses. New reviews wait for moderation; only approved ones are
+// shown and count towards the rating of their product.
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// Review is a customer review of a product
+type Review struct {
+	ID        int       `json:"id"`
+	ProductID int       `json:"product_id"`
+	Rating    int       `json:"rating" binding:"required,min=1,max=5"`
+	Title     string    `json:"title" binding:"max=200"`
+	Body      string    `json:"body" binding:"max=5000"`
+	Author    string    `json:"author" binding:"required,max=100"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	// ModeratedAt is set once the review was approved or rejected
+	ModeratedAt *time.Time `json:"moderated_at,omitempty"`
+}
+
+// RatingSummary is what a product shows of its approved reviews: the
+// average rating, the number of reviews and how many gave each number of
+// stars
+type RatingSummary struct {
+	Rating       float64
+	ReviewCount  int
+	Distribution map[int]int
+}
+
+// SummarizeReviews computes the rating summary of the approved reviews,
+// with the average rounded to two decimals
+func SummarizeReviews(reviews []Review) RatingSummary {
+	var (
+		summary RatingSummary
+		total   int
+	)
+	for _, r := range reviews {
+		if r.Status != ReviewApproved {
+			continue
+		}
+		if summary.Distribution == nil {
+			summary.Distribution = map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}
+		}
+		summary.Distribution[r.Rating]++
+		summary.ReviewCount++
+		total += r.Rating
+	}
+	if summary.ReviewCount > 0 {
+		summary.Rating = math.Round(float64(total)/float64(summary.ReviewCount)*100) / 100
+	}
+	return summary
+}
+
+// Ratings returns the fields of the product computed from its reviews
+func (p Product) Ratings() RatingSummary {
+	return RatingSummary{Rating: p.Rating, ReviewCount: p.ReviewCount, Distribution: p.RatingDistribution}
+}
+
+// WithRatings sets the fields of the product computed from its reviews
+func (p Product) WithRatings(s RatingSummary) Product {
+	p.Rating = s.Rating
+	p.ReviewCount = s.ReviewCount
+	p.RatingDistribution = s.Distribution
+	return p
+}
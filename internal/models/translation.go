@@ -0,0 +1,166 @@
+package models
+
+import "sort"
+
+// Translation holds the texts of a product in one locale. Empty fields fall
+// back to the next locale of the lookup chain.
+type Translation struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	// SpecLabels are the display labels of specification keys
+	SpecLabels map[string]string `json:"spec_labels,omitempty" binding:"dive,keys,min=1,max=64,endkeys,max=256"`
+}
+
+// IsEmpty reports whether the translation has no text
+func (t Translation) IsEmpty() bool {
+	return t.Name == "" && t.Description == "" && len(t.SpecLabels) == 0
+}
+
+// Localize returns the product with its name and description taken from the
+// first locale of chain that translates them, and SpecLabels set for every
+// specification. The untranslated fields are in defaultLocale, so the lookup
+// stops there. A chain starting with defaultLocale translates nothing and
+// returns the product as stored.
+func (p Product) Localize(chain []string, defaultLocale string) Product {
+	if len(chain) == 0 || chain[0] == defaultLocale {
+		return p
+	}
+
+	text := func(get func(Translation) string, fallback string) string {
+		for _, locale := range chain {
+			if locale == defaultLocale {
+				break
+			}
+			if s := get(p.Translations[locale]); s != "" {
+				return s
+			}
+		}
+		return fallback
+	}
+
+	p.Name = text(func(t Translation) string { return t.Name }, p.Name)
+	p.Description = text(func(t Translation) string { return t.Description }, p.Description)
+	if len(p.Specifications) > 0 {
+		p.SpecLabels = make(map[string]string, len(p.Specifications))
+		for key := range p.Specifications {
+			p.SpecLabels[key] = text(func(t Translation) string { return t.SpecLabels[key] }, key)
+		}
+	}
+	return p
+}
+
+// MissingTranslations lists the fields of the product that have no
+// translation into locale: name, description and spec_labels[<key>] for
+// every specification. Empty fields need no translation.
+func (p Product) MissingTranslations(locale string) []string {
+	t := p.Translations[locale]
+
+	var missing []string
+	if p.Name != "" && t.Name == "" {
+		missing = append(missing, "name")
+	}
+	if p.Description != "" && t.Description == "" {
+		missing = append(missing, "description")
+	}
+
+	keys := make([]string, 0, len(p.Specifications))
+	for key := range p.Specifications {
+		if t.SpecLabels[key] == "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		missing = append(missing, "spec_labels["+key+"]")
+	}
+	return missing
+}
+
+// inheritTranslations fills the translations of a variant from its parent:
+// names and descriptions only where the variant inherited the untranslated
+// field too, and spec labels it does not set itself
+func inheritTranslations(own, parent map[string]Translation, inheritName, inheritDescription bool) map[string]Translation {
+	if len(parent) == 0 {
+		return own
+	}
+
+	merged := make(map[string]Translation, len(parent)+len(own))
+	for locale, t := range own {
+		merged[locale] = t
+	}
+	for locale, pt := range parent {
+		t := merged[locale]
+		if inheritName && t.Name == "" {
+			t.Name = pt.Name
+		}
+		if inheritDescription && t.Description == "" {
+			t.Description = pt.Description
+		}
+		if len(pt.SpecLabels) > 0 {
+			labels := make(map[string]string, len(pt.SpecLabels)+len(t.SpecLabels))
+			for k, v := range pt.SpecLabels {
+				labels[k] = v
+			}
+			for k, v := range t.SpecLabels {
+				labels[k] = v
+			}
+			t.SpecLabels = labels
+		}
+		if !t.IsEmpty() {
+			merged[locale] = t
+		}
+	}
+	return merged
+}
+
+// followTranslations applies a change of the parent's translations from
+// before to after to the texts the variant still shares with before. Names
+// and descriptions only follow when the variant shares the untranslated
+// field too.
+func followTranslations(own, before, after map[string]Translation, followName, followDescription bool) map[string]Translation {
+	locales := make(map[string]bool)
+	for _, m := range []map[string]Translation{own, before, after} {
+		for locale := range m {
+			locales[locale] = true
+		}
+	}
+
+	followed := make(map[string]Translation, len(locales))
+	for locale := range locales {
+		t, tb, ta := own[locale], before[locale], after[locale]
+		if followName && t.Name == tb.Name {
+			t.Name = ta.Name
+		}
+		if followDescription && t.Description == tb.Description {
+			t.Description = ta.Description
+		}
+
+		labels := make(map[string]string, len(t.SpecLabels))
+		for k, v := range t.SpecLabels {
+			if old, shared := tb.SpecLabels[k]; !shared || old != v {
+				labels[k] = v
+			} else if label, ok := ta.SpecLabels[k]; ok {
+				labels[k] = label
+			}
+		}
+		for k, v := range ta.SpecLabels {
+			if _, ok := labels[k]; !ok {
+				if _, had := tb.SpecLabels[k]; !had {
+					labels[k] = v
+				}
+			}
+		}
+		t.SpecLabels = nil
+		if len(labels) > 0 {
+			t.SpecLabels = labels
+		}
+
+		if !t.IsEmpty() {
+			followed[locale] = t
+		}
+	}
+	if len(followed) == 0 {
+		return nil
+	}
+	return followed
+}
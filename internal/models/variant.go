@@ -0,0 +1,105 @@
+package models
+
+import "strings"
+
+// IsVariant reports whether the product is a variant of another product
+func (p Product) IsVariant() bool {
+	return p.ParentID != 0
+}
+
+// VariantKey joins the values of the given axes, identifying a variant among
+// its siblings
+func (p Product) VariantKey(axes []string) string {
+	values := make([]string, len(axes))
+	for i, axis := range axes {
+		values[i] = p.Specifications[axis]
+	}
+	return strings.Join(values, "\x00")
+}
+
+// InheritFrom fills the fields a variant leaves empty from its parent.
+// Specifications and translations are merged, the variant's own values
+// winning. Ratings are not shared, every variant has its own reviews.
+func (p Product) InheritFrom(parent Product) Product {
+	p.Translations = inheritTranslations(p.Translations, parent.Translations, p.Name == "", p.Description == "")
+	if p.Name == "" {
+		p.Name = parent.Name
+	}
+	if p.ImageURL == "" {
+		p.ImageURL = parent.ImageURL
+	}
+	if p.Description == "" {
+		p.Description = parent.Description
+	}
+	if p.Price.IsZero() {
+		p.Price = parent.Price
+	}
+	if p.Category == "" {
+		p.Category = parent.Category
+	}
+	if p.BrandID == 0 {
+		p.BrandID = parent.BrandID
+	}
+
+	if len(parent.Specifications) > 0 {
+		specs := make(map[string]string, len(parent.Specifications)+len(p.Specifications))
+		for k, v := range parent.Specifications {
+			specs[k] = v
+		}
+		for k, v := range p.Specifications {
+			specs[k] = v
+		}
+		p.Specifications = specs
+	}
+
+	return p
+}
+
+// FollowParent applies a change of the parent from before to after to the
+// fields the variant still shares with before. Fields the variant overrides
+// are left alone.
+func (p Product) FollowParent(before, after Product) Product {
+	p.Translations = followTranslations(p.Translations, before.Translations, after.Translations, p.Name == before.Name, p.Description == before.Description)
+	if p.Name == before.Name {
+		p.Name = after.Name
+	}
+	if p.ImageURL == before.ImageURL {
+		p.ImageURL = after.ImageURL
+	}
+	if p.Description == before.Description {
+		p.Description = after.Description
+	}
+	if p.Price == before.Price {
+		p.Price = after.Price
+	}
+	if p.Category == before.Category {
+		p.Category = after.Category
+	}
+	if p.BrandID == before.BrandID {
+		p.BrandID = after.BrandID
+	}
+
+	specs := make(map[string]string, len(p.Specifications))
+	for k, v := range p.Specifications {
+		old, shared := before.Specifications[k]
+		if !shared || old != v {
+			specs[k] = v
+			continue
+		}
+		if value, ok := after.Specifications[k]; ok {
+			specs[k] = value
+		}
+	}
+	for k, v := range after.Specifications {
+		if _, ok := specs[k]; !ok {
+			if _, had := before.Specifications[k]; !had {
+				specs[k] = v
+			}
+		}
+	}
+	if len(specs) > 0 || p.Specifications != nil {
+		p.Specifications = specs
+	}
+
+	return p
+}
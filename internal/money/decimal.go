@@ -0,0 +1,209 @@
+// Package money holds exact decimal amounts, ISO 4217 currencies and the
+// conversion of prices between currencies.
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidDecimal is returned for text that is not a decimal number or
+// does not fit in 18 significant digits
+var ErrInvalidDecimal = errors.New("invalid decimal")
+
+// Decimal is an exact decimal number coef × 10^exp. It is normalized, so
+// equal numbers compare equal with == and reflect.DeepEqual.
+type Decimal struct {
+	coef int64
+	exp  int32
+}
+
+// NewDecimal returns coef × 10^exp
+func NewDecimal(coef int64, exp int32) Decimal {
+	return Decimal{coef: coef, exp: exp}.normalize()
+}
+
+// ParseDecimal reads a decimal such as "1200", "-0.5" or "1.25e3"
+func ParseDecimal(s string) (Decimal, error) {
+	mantissa, exponent := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil || e > 64 || e < -64 {
+			return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+		}
+		mantissa, exponent = s[:i], e
+	}
+
+	intPart, fracPart, _ := strings.Cut(mantissa, ".")
+	digits := strings.TrimLeft(intPart, "+-") + fracPart
+	if digits == "" || strings.Trim(digits, "0123456789") != "" || strings.Count(intPart, "-")+strings.Count(intPart, "+") > 1 {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+	}
+	coef, err := strconv.ParseInt(strings.TrimLeft(intPart, "+")+fracPart, 10, 64)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+	}
+
+	return NewDecimal(coef, int32(exponent-len(fracPart))), nil
+}
+
+// MustParseDecimal is ParseDecimal for constants; it panics on bad input
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// normalize strips trailing zeros and scales positive exponents into the
+// coefficient where it fits
+func (d Decimal) normalize() Decimal {
+	if d.coef == 0 {
+		return Decimal{}
+	}
+	for d.exp < 0 && d.coef%10 == 0 {
+		d.coef /= 10
+		d.exp++
+	}
+	for d.exp > 0 && d.coef <= math.MaxInt64/10 && d.coef >= math.MinInt64/10 {
+		d.coef *= 10
+		d.exp--
+	}
+	return d
+}
+
+// IsZero reports whether d is 0
+func (d Decimal) IsZero() bool {
+	return d.coef == 0
+}
+
+// Sign returns -1, 0 or 1
+func (d Decimal) Sign() int {
+	switch {
+	case d.coef < 0:
+		return -1
+	case d.coef > 0:
+		return 1
+	}
+	return 0
+}
+
+// Places is the number of digits after the decimal point
+func (d Decimal) Places() int {
+	if d.exp >= 0 {
+		return 0
+	}
+	return int(-d.exp)
+}
+
+// Cmp compares d and other, returning -1, 0 or 1
+func (d Decimal) Cmp(other Decimal) int {
+	return d.Rat().Cmp(other.Rat())
+}
+
+// Add returns d + other
+func (d Decimal) Add(other Decimal) Decimal {
+	places := d.Places()
+	if other.Places() > places {
+		places = other.Places()
+	}
+	sum, err := FromRat(new(big.Rat).Add(d.Rat(), other.Rat()), places)
+	if err != nil {
+		panic(err)
+	}
+	return sum
+}
+
+// Rat returns d as an exact rational
+func (d Decimal) Rat() *big.Rat {
+	r := new(big.Rat).SetInt64(d.coef)
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(d.exp))), nil)
+	if d.exp < 0 {
+		return r.Quo(r, new(big.Rat).SetInt(scale))
+	}
+	return r.Mul(r, new(big.Rat).SetInt(scale))
+}
+
+// FromRat rounds r half away from zero to the given number of places
+func FromRat(r *big.Rat, places int) (Decimal, error) {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(scale))
+
+	num, den := scaled.Num(), scaled.Denom()
+	quo, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(den) >= 0 {
+		quo.Add(quo, big.NewInt(int64(num.Sign())))
+	}
+	if !quo.IsInt64() {
+		return Decimal{}, fmt.Errorf("%w: %s does not fit", ErrInvalidDecimal, r.FloatString(places))
+	}
+
+	return NewDecimal(quo.Int64(), int32(-places)), nil
+}
+
+// Float64 returns the nearest float, for sorting and approximate storage
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// String formats d without exponent, e.g. "1200" or "-0.05"
+func (d Decimal) String() string {
+	if d.exp >= 0 {
+		return d.Rat().FloatString(0)
+	}
+	return d.Rat().FloatString(d.Places())
+}
+
+// StringFixed formats d with exactly the given number of places
+func (d Decimal) StringFixed(places int) string {
+	if places < d.Places() {
+		rounded, err := FromRat(d.Rat(), places)
+		if err == nil {
+			d = rounded
+		}
+	}
+	return d.Rat().FloatString(places)
+}
+
+// MarshalJSON writes d as a JSON string so clients never read it as a float
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON accepts a JSON string or number
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*d = Decimal{}
+		return nil
+	}
+
+	text := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := ParseDecimal(text)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func abs(n int32) int32 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,63 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// Money is an amount in an ISO 4217 currency. Conversion is only set on
+// prices converted for a response; the catalog never stores it.
+type Money struct {
+	Amount     Decimal     `json:"amount"`
+	Currency   string      `json:"currency"`
+	Conversion *Conversion `json:"conversion,omitempty"`
+}
+
+// Conversion states where a converted amount comes from: the original price
+// and the exchange rate applied, as of RateUpdatedAt
+type Conversion struct {
+	From          Money     `json:"from"`
+	Rate          Decimal   `json:"rate"`
+	RateUpdatedAt time.Time `json:"rate_updated_at"`
+}
+
+// New returns amount in currency
+func New(amount Decimal, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// IsZero reports whether m is unset, which is different from a price of 0
+// in some currency
+func (m Money) IsZero() bool {
+	return m.Amount.IsZero() && m.Currency == ""
+}
+
+// String formats m like "1200.00 USD"
+func (m Money) String() string {
+	return m.Amount.StringFixed(MinorUnits(m.Currency)) + " " + m.Currency
+}
+
+// UnmarshalJSON reads {"amount": "1200.00", "currency": "USD"}. A bare
+// number or string is an amount in DefaultCurrency, so clients written
+// before prices had a currency keep working.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] != '{' && !bytes.Equal(data, []byte("null")) {
+		var amount Decimal
+		if err := amount.UnmarshalJSON(data); err != nil {
+			return err
+		}
+		*m = Money{Amount: amount, Currency: DefaultCurrency}
+		return nil
+	}
+
+	// The alias has no UnmarshalJSON, so this does not recurse
+	type plain Money
+	var fields plain
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*m = Money(fields)
+	return nil
+}
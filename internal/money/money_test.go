@@ -0,0 +1,106 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"1200", "1200"},
+		{"1200.50", "1200.5"},
+		{"-0.05", "-0.05"},
+		{"+3", "3"},
+		{"1.25e3", "1250"},
+		{"5e-3", "0.005"},
+		{".5", "0.5"},
+	}
+	for _, tt := range tests {
+		d, err := ParseDecimal(tt.in)
+		assert.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, d.String(), tt.in)
+	}
+
+	for _, bad := range []string{"", "abc", "1.2.3", "--1", "1e", "1e999", "99999999999999999999"} {
+		_, err := ParseDecimal(bad)
+		assert.ErrorIs(t, err, ErrInvalidDecimal, bad)
+	}
+}
+
+func TestDecimal_EqualValuesCompareEqual(t *testing.T) {
+	assert.Equal(t, MustParseDecimal("1.50"), MustParseDecimal("1.5"))
+	assert.Equal(t, MustParseDecimal("0"), Decimal{})
+	assert.Equal(t, 0, MustParseDecimal("10").Cmp(MustParseDecimal("1e1")))
+	assert.Equal(t, -1, MustParseDecimal("9.99").Cmp(MustParseDecimal("10")))
+	assert.Equal(t, MustParseDecimal("1.3"), MustParseDecimal("1.25").Add(MustParseDecimal("0.05")))
+}
+
+func TestDecimal_StringFixedRoundsHalfAwayFromZero(t *testing.T) {
+	assert.Equal(t, "2.50", MustParseDecimal("2.5").StringFixed(2))
+	assert.Equal(t, "1.01", MustParseDecimal("1.005").StringFixed(2))
+	assert.Equal(t, "-1.01", MustParseDecimal("-1.005").StringFixed(2))
+	assert.Equal(t, "3", MustParseDecimal("2.5").StringFixed(0))
+}
+
+func TestDecimal_JSON(t *testing.T) {
+	data, err := json.Marshal(MustParseDecimal("0.1"))
+	assert.NoError(t, err)
+	assert.Equal(t, `"0.1"`, string(data))
+
+	var d Decimal
+	assert.NoError(t, json.Unmarshal([]byte(`0.1`), &d))
+	assert.Equal(t, MustParseDecimal("0.1"), d)
+	assert.NoError(t, json.Unmarshal([]byte(`"12.34"`), &d))
+	assert.Equal(t, MustParseDecimal("12.34"), d)
+	assert.Error(t, json.Unmarshal([]byte(`"cheap"`), &d))
+}
+
+func TestMoney_UnmarshalJSON(t *testing.T) {
+	var m Money
+	assert.NoError(t, json.Unmarshal([]byte(`25.5`), &m))
+	assert.Equal(t, New(MustParseDecimal("25.5"), "USD"), m)
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount": "100", "currency": "EUR"}`), &m))
+	assert.Equal(t, New(MustParseDecimal("100"), "EUR"), m)
+	assert.Equal(t, "100.00 EUR", m.String())
+}
+
+func TestRates_Convert(t *testing.T) {
+	updated := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	rates := Rates{
+		Base:      "USD",
+		UpdatedAt: updated,
+		Rates:     map[string]Decimal{"EUR": MustParseDecimal("0.92"), "JPY": MustParseDecimal("150")},
+	}
+	assert.NoError(t, rates.Validate())
+
+	eur, err := rates.Convert(New(MustParseDecimal("10"), "USD"), "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, MustParseDecimal("9.2"), eur.Amount)
+	assert.Equal(t, "EUR", eur.Currency)
+	assert.Equal(t, &Conversion{From: New(MustParseDecimal("10"), "USD"), Rate: MustParseDecimal("0.92"), RateUpdatedAt: updated}, eur.Conversion)
+
+	// Cross rates go through the base and round to the minor units
+	jpy, err := rates.Convert(New(MustParseDecimal("9.99"), "EUR"), "JPY")
+	assert.NoError(t, err)
+	assert.Equal(t, MustParseDecimal("1629"), jpy.Amount)
+	assert.Equal(t, MustParseDecimal("163.04347826"), jpy.Conversion.Rate)
+
+	same, err := rates.Convert(New(MustParseDecimal("10"), "EUR"), "EUR")
+	assert.NoError(t, err)
+	assert.Nil(t, same.Conversion)
+
+	_, err = rates.Convert(New(MustParseDecimal("10"), "USD"), "GBP")
+	assert.ErrorIs(t, err, ErrNoRate)
+	_, err = rates.Convert(New(MustParseDecimal("10"), "USD"), "XYZ")
+	assert.ErrorIs(t, err, ErrUnknownCurrency)
+
+	rates.Rates["GBP"] = Decimal{}
+	assert.Error(t, rates.Validate())
+}
@@ -0,0 +1,95 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// ratePlaces is the precision of the cross rates reported with conversions
+const ratePlaces = 8
+
+var (
+	// ErrUnknownCurrency is returned for codes that are not ISO 4217 currencies
+	ErrUnknownCurrency = errors.New("unknown currency")
+	// ErrNoRate is returned when the table has no rate for a currency
+	ErrNoRate = errors.New("no exchange rate")
+)
+
+// Rates is an exchange-rate table: one unit of Base is worth Rates[c] units
+// of currency c
+type Rates struct {
+	Base      string             `json:"base"`
+	UpdatedAt time.Time          `json:"updated_at"`
+	Rates     map[string]Decimal `json:"rates"`
+}
+
+// Validate checks that every currency is known and every rate positive
+func (r Rates) Validate() error {
+	if !ValidCurrency(r.Base) {
+		return fmt.Errorf("%w: base %q", ErrUnknownCurrency, r.Base)
+	}
+	for code, rate := range r.Rates {
+		if !ValidCurrency(code) {
+			return fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+		}
+		if rate.Sign() <= 0 {
+			return fmt.Errorf("rate of %s must be positive", code)
+		}
+	}
+	return nil
+}
+
+// rate returns the value of one unit of Base in code
+func (r Rates) rate(code string) (*big.Rat, error) {
+	if code == r.Base {
+		return big.NewRat(1, 1), nil
+	}
+	rate, ok := r.Rates[code]
+	if !ok || rate.Sign() <= 0 {
+		return nil, fmt.Errorf("%w for %s", ErrNoRate, code)
+	}
+	return rate.Rat(), nil
+}
+
+// Convert expresses m in currency to, rounded to the minor units of to. The
+// result records the original price and the rate used; converting to the
+// currency m already has returns m unchanged.
+func (r Rates) Convert(m Money, to string) (Money, error) {
+	if !ValidCurrency(to) {
+		return Money{}, fmt.Errorf("%w: %q", ErrUnknownCurrency, to)
+	}
+	if m.Currency == to {
+		return m, nil
+	}
+
+	fromRate, err := r.rate(m.Currency)
+	if err != nil {
+		return Money{}, err
+	}
+	toRate, err := r.rate(to)
+	if err != nil {
+		return Money{}, err
+	}
+
+	cross := new(big.Rat).Quo(toRate, fromRate)
+	amount, err := FromRat(new(big.Rat).Mul(m.Amount.Rat(), cross), MinorUnits(to))
+	if err != nil {
+		return Money{}, err
+	}
+	rate, err := FromRat(cross, ratePlaces)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{
+		Amount:   amount,
+		Currency: to,
+		Conversion: &Conversion{
+			From:          Money{Amount: m.Amount, Currency: m.Currency},
+			Rate:          rate,
+			RateUpdatedAt: r.UpdatedAt,
+		},
+	}, nil
+}
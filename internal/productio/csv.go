@@ -0,0 +1,358 @@
+package productio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"item-comparison-ai-api/internal/i18n"
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/money"
+)
+
+// specPrefix marks the columns holding specifications, e.g. "spec.RAM"
+const specPrefix = "spec."
+
+// Translation columns are named by field and locale: "name.de",
+// "description.de" and "spec_label.de.RAM" for the label of a specification
+const (
+	namePrefix        = "name."
+	descriptionPrefix = "description."
+	specLabelPrefix   = "spec_label."
+)
+
+// csvColumns are the product fields in the order they are exported
+var csvColumns = []string{"id", "sku", "gtin", "name", "image_url", "description", "price", "currency", "rating", "category", "brand_id", "parent_id", "variant_axes"}
+
+// axisSeparator joins the variant axes of a parent in a single cell
+const axisSeparator = "|"
+
+// ReadCSV decodes a CSV file whose header names product fields and spec.*
+// columns. An unknown column fails the whole file; a bad value only fails its
+// row.
+func ReadCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return []Row{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("csv header: %w", err)
+	}
+	for i, column := range header {
+		column = strings.TrimSpace(column)
+		if i == 0 {
+			column = strings.TrimPrefix(column, "\ufeff") // spreadsheet byte order mark
+		}
+		if !isCSVColumn(column) {
+			return nil, fmt.Errorf("csv header: unknown column %q", column)
+		}
+		header[i] = column
+	}
+
+	rows := make([]Row, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			rows = append(rows, Row{Line: parseErr.StartLine, Err: parseErr.Err})
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := Row{Line: line}
+		if len(record) != len(header) {
+			row.Err = fmt.Errorf("expected %d columns, got %d", len(header), len(record))
+		} else {
+			row.Product, row.HasID, row.Err = decodeCSVRecord(header, record)
+		}
+		rows = append(rows, row)
+	}
+}
+
+func isCSVColumn(column string) bool {
+	if strings.HasPrefix(column, specPrefix) {
+		return len(column) > len(specPrefix)
+	}
+	if _, _, _, ok := translationColumn(column); ok {
+		return true
+	}
+	for _, c := range csvColumns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeCSVRecord(header, record []string) (p models.Product, hasID bool, err error) {
+	for i, column := range header {
+		value := record[i]
+		switch column {
+		case "id":
+			if value == "" {
+				continue
+			}
+			if p.ID, err = strconv.Atoi(value); err != nil {
+				return p, false, fmt.Errorf("id: %q is not an integer", value)
+			}
+			hasID = true
+		case "sku":
+			p.SKU = value
+		case "gtin":
+			p.GTIN = value
+		case "name":
+			p.Name = value
+		case "image_url":
+			p.ImageURL = value
+		case "description":
+			p.Description = value
+		case "price":
+			if value == "" {
+				continue
+			}
+			if p.Price.Amount, err = money.ParseDecimal(value); err != nil {
+				return p, false, fmt.Errorf("price: %q is not a number", value)
+			}
+		case "currency":
+			p.Price.Currency = value
+		case "rating":
+			if p.Rating, err = parseCSVFloat(value); err != nil {
+				return p, false, fmt.Errorf("rating: %q is not a number", value)
+			}
+		case "category":
+			p.Category = value
+		case "brand_id":
+			if value == "" {
+				continue
+			}
+			if p.BrandID, err = strconv.Atoi(value); err != nil {
+				return p, false, fmt.Errorf("brand_id: %q is not an integer", value)
+			}
+		case "parent_id":
+			if value == "" {
+				continue
+			}
+			if p.ParentID, err = strconv.Atoi(value); err != nil {
+				return p, false, fmt.Errorf("parent_id: %q is not an integer", value)
+			}
+		case "variant_axes":
+			if value != "" {
+				p.VariantAxes = strings.Split(value, axisSeparator)
+			}
+		default:
+			// Empty cells mean the product lacks that specification or
+			// translation
+			if value == "" {
+				continue
+			}
+			if locale, field, key, ok := translationColumn(column); ok {
+				setTranslation(&p, locale, field, key, value)
+				continue
+			}
+			if p.Specifications == nil {
+				p.Specifications = make(map[string]string)
+			}
+			p.Specifications[strings.TrimPrefix(column, specPrefix)] = value
+		}
+	}
+	return p, hasID, nil
+}
+
+// translationColumn splits the name of a translation column into its locale,
+// the translated field and, for spec labels, the specification key
+func translationColumn(column string) (locale, field, key string, ok bool) {
+	for _, prefix := range []string{namePrefix, descriptionPrefix, specLabelPrefix} {
+		rest, found := strings.CutPrefix(column, prefix)
+		if !found {
+			continue
+		}
+		field = strings.TrimSuffix(prefix, ".")
+		if prefix == specLabelPrefix {
+			if rest, key, found = strings.Cut(rest, "."); !found || key == "" {
+				return "", "", "", false
+			}
+		}
+		locale, ok = i18n.Normalize(rest)
+		return locale, field, key, ok
+	}
+	return "", "", "", false
+}
+
+func setTranslation(p *models.Product, locale, field, key, value string) {
+	if p.Translations == nil {
+		p.Translations = make(map[string]models.Translation)
+	}
+	t := p.Translations[locale]
+	switch field {
+	case "name":
+		t.Name = value
+	case "description":
+		t.Description = value
+	default:
+		if t.SpecLabels == nil {
+			t.SpecLabels = make(map[string]string)
+		}
+		t.SpecLabels[key] = value
+	}
+	p.Translations[locale] = t
+}
+
+func parseCSVFloat(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// CSVWriter writes products as CSV rows, one spec.* column per specification
+// key and one column per translation column given to NewCSVWriter
+type CSVWriter struct {
+	w                  *csv.Writer
+	specKeys           []string
+	translationColumns []string
+}
+
+// NewCSVWriter writes the header to w right away
+func NewCSVWriter(w io.Writer, specKeys, translationColumns []string) (*CSVWriter, error) {
+	keys := append([]string(nil), specKeys...)
+	sort.Strings(keys)
+
+	header := append([]string(nil), csvColumns...)
+	for _, k := range keys {
+		header = append(header, specPrefix+k)
+	}
+	header = append(header, translationColumns...)
+
+	writer := &CSVWriter{w: csv.NewWriter(w), specKeys: keys, translationColumns: translationColumns}
+	if err := writer.w.Write(header); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+// Write appends the row of p
+func (w *CSVWriter) Write(p models.Product) error {
+	parentID, brandID := "", ""
+	if p.IsVariant() {
+		parentID = strconv.Itoa(p.ParentID)
+	}
+	if p.BrandID != 0 {
+		brandID = strconv.Itoa(p.BrandID)
+	}
+	record := []string{
+		strconv.Itoa(p.ID),
+		p.SKU,
+		p.GTIN,
+		p.Name,
+		p.ImageURL,
+		p.Description,
+		p.Price.Amount.String(),
+		p.Price.Currency,
+		strconv.FormatFloat(p.Rating, 'f', -1, 64),
+		p.Category,
+		brandID,
+		parentID,
+		strings.Join(p.VariantAxes, axisSeparator),
+	}
+	for _, k := range w.specKeys {
+		record = append(record, p.Specifications[k])
+	}
+	for _, column := range w.translationColumns {
+		locale, field, key, _ := translationColumn(column)
+		t := p.Translations[locale]
+		switch field {
+		case "name":
+			record = append(record, t.Name)
+		case "description":
+			record = append(record, t.Description)
+		default:
+			record = append(record, t.SpecLabels[key])
+		}
+	}
+	return w.w.Write(record)
+}
+
+// Flush sends buffered rows to the underlying writer
+func (w *CSVWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// SpecKeys collects the specification keys used by products
+func SpecKeys(products []models.Product) []string {
+	seen := make(map[string]bool)
+	keys := make([]string, 0)
+	for _, p := range products {
+		for k := range p.Specifications {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// TranslationColumns lists the translation columns needed for the
+// translations of products, grouped by locale
+func TranslationColumns(products []models.Product) []string {
+	type fields struct {
+		name, description bool
+		labels            map[string]bool
+	}
+	byLocale := make(map[string]*fields)
+	for _, p := range products {
+		for locale, t := range p.Translations {
+			f := byLocale[locale]
+			if f == nil {
+				f = &fields{labels: make(map[string]bool)}
+				byLocale[locale] = f
+			}
+			f.name = f.name || t.Name != ""
+			f.description = f.description || t.Description != ""
+			for k := range t.SpecLabels {
+				f.labels[k] = true
+			}
+		}
+	}
+
+	locales := make([]string, 0, len(byLocale))
+	for locale := range byLocale {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	columns := make([]string, 0)
+	for _, locale := range locales {
+		f := byLocale[locale]
+		if f.name {
+			columns = append(columns, namePrefix+locale)
+		}
+		if f.description {
+			columns = append(columns, descriptionPrefix+locale)
+		}
+		keys := make([]string, 0, len(f.labels))
+		for k := range f.labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			columns = append(columns, specLabelPrefix+locale+"."+k)
+		}
+	}
+	return columns
+}
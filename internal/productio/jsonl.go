@@ -0,0 +1,53 @@
+package productio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"item-comparison-ai-api/internal/models"
+)
+
+// maxJSONLLine bounds the size of a single JSON Lines record
+const maxJSONLLine = 1024 * 1024
+
+// ReadJSONL decodes one product per line. Blank lines are skipped and a line
+// that is not a product object only fails its row.
+func ReadJSONL(r io.Reader) ([]Row, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLLine)
+
+	rows := make([]Row, 0)
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		row := Row{Line: line}
+		var fields map[string]json.RawMessage
+		if row.Err = json.Unmarshal(raw, &fields); row.Err == nil {
+			_, row.HasID = fields["id"]
+			row.Err = json.Unmarshal(raw, &row.Product)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, scanner.Err()
+}
+
+// JSONLWriter writes products as JSON Lines
+type JSONLWriter struct {
+	enc *json.Encoder
+}
+
+// NewJSONLWriter writes to w
+func NewJSONLWriter(w io.Writer) *JSONLWriter {
+	return &JSONLWriter{enc: json.NewEncoder(w)}
+}
+
+// Write appends the line of p
+func (w *JSONLWriter) Write(p models.Product) error {
+	return w.enc.Encode(p)
+}
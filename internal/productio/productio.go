@@ -0,0 +1,49 @@
+// Package productio reads and writes products in the CSV and JSON Lines
+// formats used for spreadsheet imports and exports
+package productio
+
+import (
+	"errors"
+	"io"
+
+	"item-comparison-ai-api/internal/models"
+)
+
+// Formats
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+)
+
+// ErrUnsupportedFormat is returned for a format other than csv or jsonl
+var ErrUnsupportedFormat = errors.New("unsupported format, use csv or jsonl")
+
+// Row is a decoded record of an import file. Line is the 1-based line of the
+// record in the file; Err is set when the record could not be decoded.
+type Row struct {
+	Line    int
+	Product models.Product
+	// HasID reports whether the record carried an ID
+	HasID bool
+	Err   error
+}
+
+// ContentType returns the MIME type of format
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv"
+	}
+	return "application/x-ndjson"
+}
+
+// Read decodes an import file in the given format
+func Read(format string, r io.Reader) ([]Row, error) {
+	switch format {
+	case FormatCSV:
+		return ReadCSV(r)
+	case FormatJSONL:
+		return ReadJSONL(r)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
@@ -0,0 +1,95 @@
+package productio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/money"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadCSV(t *testing.T) {
+	data := "id,name,price,category,spec.RAM,spec.Storage\n" +
+		"1,Laptop,1200.5,Electronics,16GB,512GB SSD\n" +
+		",Mouse,25,Accessories,,\n" +
+		"x,Broken,10,Accessories,,\n" +
+		"4,Cheap,abc,Accessories,,\n" +
+		"5,Short\n"
+
+	rows, err := ReadCSV(strings.NewReader(data))
+	assert.NoError(t, err)
+	if !assert.Len(t, rows, 5) {
+		return
+	}
+
+	assert.NoError(t, rows[0].Err)
+	assert.True(t, rows[0].HasID)
+	assert.Equal(t, models.Product{
+		ID: 1, Name: "Laptop", Price: money.Money{Amount: money.MustParseDecimal("1200.5")}, Category: "Electronics",
+		Specifications: map[string]string{"RAM": "16GB", "Storage": "512GB SSD"},
+	}, rows[0].Product)
+
+	assert.NoError(t, rows[1].Err)
+	assert.False(t, rows[1].HasID)
+	assert.Nil(t, rows[1].Product.Specifications)
+
+	assert.Equal(t, 4, rows[2].Line)
+	assert.EqualError(t, rows[2].Err, `id: "x" is not an integer`)
+	assert.EqualError(t, rows[3].Err, `price: "abc" is not a number`)
+	assert.EqualError(t, rows[4].Err, "expected 6 columns, got 2")
+}
+
+func TestReadCSV_RejectsUnknownColumns(t *testing.T) {
+	_, err := ReadCSV(strings.NewReader("id,colour\n1,red\n"))
+	assert.EqualError(t, err, `csv header: unknown column "colour"`)
+}
+
+func TestCSVWriter_RoundTrip(t *testing.T) {
+	products := []models.Product{
+		{ID: 1, SKU: "LAP-15", GTIN: "4006381333931", Name: "Laptop, 15\"", Price: money.New(money.MustParseDecimal("1200"), "USD"), Rating: 4.5, Category: "Electronics", BrandID: 7, Specifications: map[string]string{"RAM": "16GB"},
+			Translations: map[string]models.Translation{"de": {Name: "Laptop, 15 Zoll", SpecLabels: map[string]string{"RAM": "Arbeitsspeicher"}}}},
+		{ID: 2, Name: "Phone", Price: money.New(money.MustParseDecimal("800"), "USD"), Category: "Electronics", Specifications: map[string]string{"Camera": "108MP"}, VariantAxes: []string{"Color", "Storage"}},
+		{ID: 3, Name: "Phone", Price: money.New(money.MustParseDecimal("900"), "USD"), Category: "Electronics", Specifications: map[string]string{"Camera": "108MP"}, ParentID: 2},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewCSVWriter(&buf, SpecKeys(products), TranslationColumns(products))
+	assert.NoError(t, err)
+	for _, p := range products {
+		assert.NoError(t, w.Write(p))
+	}
+	assert.NoError(t, w.Flush())
+
+	assert.True(t, strings.HasPrefix(buf.String(), "id,sku,gtin,name,image_url,description,price,currency,rating,category,brand_id,parent_id,variant_axes,spec.Camera,spec.RAM,name.de,spec_label.de.RAM\n"))
+
+	rows, err := ReadCSV(&buf)
+	assert.NoError(t, err)
+	if assert.Len(t, rows, 3) {
+		assert.Equal(t, products[0], rows[0].Product)
+		assert.Equal(t, products[1], rows[1].Product)
+		assert.Equal(t, products[2], rows[2].Product)
+	}
+}
+
+func TestReadJSONL(t *testing.T) {
+	data := `{"id": 1, "name": "Laptop", "specifications": {"RAM": "16GB"}}
+
+{"name": "Mouse"}
+{"name": 5}
+`
+	rows, err := ReadJSONL(strings.NewReader(data))
+	assert.NoError(t, err)
+	if !assert.Len(t, rows, 3) {
+		return
+	}
+
+	assert.True(t, rows[0].HasID)
+	assert.Equal(t, "16GB", rows[0].Product.Specifications["RAM"])
+	assert.False(t, rows[1].HasID)
+	assert.Equal(t, 3, rows[1].Line)
+	assert.Error(t, rows[2].Err)
+	assert.Equal(t, 4, rows[2].Line)
+}
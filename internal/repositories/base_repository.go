@@ -1,7 +1,6 @@
 package repositories
 
 import (
-	"encoding/json"
 	"item-comparison-ai-api/config"
 	"item-comparison-ai-api/internal/database"
 	"os"
@@ -10,25 +9,43 @@ import (
 
 type BaseRepositoryInterface interface {
 	Load() ([]byte, error)
-	Save([]interface{}) error
+	Write([]byte) error
+	// WithLock runs fn while holding the write lock of the data file, so a
+	// load-modify-save sequence inside fn cannot interleave with other writers.
+	// fn must read and write the file through locked, which works under the
+	// held lock.
+	WithLock(fn func(locked BaseRepositoryInterface) error) error
 }
 
-// Client is the implementation of the DB interface
+// Client is the implementation of the DB interface. Each Client reads and
+// writes a single file.
 type Client struct {
 	FileStore database.FileStore
-	config    *config.AppConfig
+	path      string
 }
 
-var (
-	mu sync.Mutex // Mutex to protect access to the data file
-)
+// fileMutexes protect one data file within the process
+type fileMutexes struct {
+	mu   sync.Mutex // Mutex to protect access to the data file
+	txMu sync.Mutex // Mutex held across a whole read-modify-write of the data file
+}
 
-// Load reads  from the data.json file
+// fileLocks holds the fileMutexes of every path, so Clients of the same file
+// share them while Clients of different files do not block each other
+var fileLocks sync.Map
+
+func (c *Client) locks() *fileMutexes {
+	l, _ := fileLocks.LoadOrStore(c.path, &fileMutexes{})
+	return l.(*fileMutexes)
+}
+
+// Load reads the content of the data file
 func (c *Client) Load() ([]byte, error) {
-	mu.Lock()
-	defer mu.Unlock()
+	l := c.locks()
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
-	data, err := c.FileStore.Read(c.config.DatabasePath)
+	data, err := c.FileStore.Read(c.path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, nil // Return empty slice if file doesn't exist
@@ -39,22 +56,52 @@ func (c *Client) Load() ([]byte, error) {
 	return data, nil
 }
 
-// Save writes  to the data.json file
-func (c *Client) Save(model []interface{}) error {
-	mu.Lock()
-	defer mu.Unlock()
+// Write stores already encoded content in the data file
+func (c *Client) Write(data []byte) error {
+	l := c.locks()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return c.FileStore.Write(c.path, data, 0644)
+}
+
+// WithLock runs fn as the only writer of the data file. When the FileStore
+// supports it, the file lock is held for the whole of fn as well, which keeps
+// other processes out of the read-modify-write. fn is given a Client that
+// reads and writes through the held lock; c itself keeps waiting for it.
+func (c *Client) WithLock(fn func(locked BaseRepositoryInterface) error) error {
+	return c.withLock(func(locked *Client) error { return fn(locked) })
+}
+
+func (c *Client) withLock(fn func(locked *Client) error) error {
+	l := c.locks()
+	l.txMu.Lock()
+	defer l.txMu.Unlock()
 
-	data, err := json.MarshalIndent(model, "", "  ")
+	locker, ok := c.FileStore.(database.Locker)
+	if !ok {
+		return fn(c)
+	}
+
+	lock, err := locker.Lock(c.path)
 	if err != nil {
 		return err
 	}
+	defer lock.Unlock()
 
-	return c.FileStore.Write(c.config.DatabasePath, data, 0644)
+	return fn(&Client{FileStore: lock, path: c.path})
 }
 
+// NewBaseRepository creates a Client for the catalog data file
 func NewBaseRepository(fileStore database.FileStore, conf *config.AppConfig) *Client {
+	return NewFileClient(fileStore, conf.DatabasePath)
+}
+
+// NewFileClient creates a Client for the file at path, for repositories that
+// keep their records apart from the catalog
+func NewFileClient(fileStore database.FileStore, path string) *Client {
 	return &Client{
 		FileStore: fileStore,
-		config:    conf,
+		path:      path,
 	}
 }
@@ -0,0 +1,124 @@
+package repositories
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/models"
+)
+
+var (
+	// ErrBrandNotFound is returned when no brand has the requested ID or
+	// slug
+	ErrBrandNotFound = errors.New("brand not found")
+	// ErrDuplicateSlug is returned when a brand would take the slug of
+	// another brand
+	ErrDuplicateSlug = errors.New("brand slug already in use")
+)
+
+// BrandStore keeps the brands in their own file
+type BrandStore struct {
+	records *Repository[models.Brand]
+}
+
+// NewBrandStore stores the brands in the file at path
+func NewBrandStore(fileStore database.FileStore, path string) *BrandStore {
+	return &BrandStore{
+		records: NewRepository[models.Brand](
+			NewFileClient(fileStore, path),
+			JSONCodec[models.Brand]{},
+			func(b models.Brand) int { return b.ID },
+			func(b *models.Brand, id int) { b.ID = id },
+		),
+	}
+}
+
+// List returns every brand, ordered by name
+func (s *BrandStore) List() ([]models.Brand, error) {
+	brands, err := s.records.Load()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(brands, func(i, j int) bool { return brands[i].Name < brands[j].Name })
+	return brands, nil
+}
+
+// Get returns the brand with the given ID
+func (s *BrandStore) Get(id int) (models.Brand, error) {
+	brand, err := s.records.Get(id)
+	if errors.Is(err, ErrRecordNotFound) {
+		return models.Brand{}, ErrBrandNotFound
+	}
+	return brand, err
+}
+
+// BySlug returns the brand with the given slug
+func (s *BrandStore) BySlug(slug string) (models.Brand, error) {
+	brands, err := s.records.Load()
+	if err != nil {
+		return models.Brand{}, err
+	}
+	for _, b := range brands {
+		if b.Slug == slug {
+			return b, nil
+		}
+	}
+	return models.Brand{}, ErrBrandNotFound
+}
+
+// Create stores a new brand. Without a slug one is derived from the name.
+func (s *BrandStore) Create(brand models.Brand) (models.Brand, error) {
+	err := s.records.Modify(func(brands []models.Brand) ([]models.Brand, error) {
+		brand.ID = s.records.NextID(brands)
+		if err := assignSlug(brands, &brand); err != nil {
+			return nil, err
+		}
+		return append(brands, brand), nil
+	})
+	return brand, err
+}
+
+// Replace overwrites the brand with the given ID
+func (s *BrandStore) Replace(id int, brand models.Brand) (models.Brand, error) {
+	err := s.records.Modify(func(brands []models.Brand) ([]models.Brand, error) {
+		i := s.records.index(brands, id)
+		if i < 0 {
+			return nil, ErrBrandNotFound
+		}
+		brand.ID = id
+		if err := assignSlug(brands, &brand); err != nil {
+			return nil, err
+		}
+		brands[i] = brand
+		return brands, nil
+	})
+	return brand, err
+}
+
+// Delete removes the brand with the given ID
+func (s *BrandStore) Delete(id int) error {
+	err := s.records.Delete(id)
+	if errors.Is(err, ErrRecordNotFound) {
+		return ErrBrandNotFound
+	}
+	return err
+}
+
+// assignSlug derives the slug of brand from its name when it has none and
+// checks that no other brand uses it
+func assignSlug(brands []models.Brand, brand *models.Brand) error {
+	if brand.Slug == "" {
+		brand.Slug = models.Slugify(brand.Name)
+		if brand.Slug == "" {
+			brand.Slug = "brand-" + strconv.Itoa(brand.ID)
+		}
+	}
+	for _, other := range brands {
+		if other.ID != brand.ID && other.Slug == brand.Slug {
+			return ErrDuplicateSlug
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"path/filepath"
+	"testing"
+
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBrandStore_Slugs(t *testing.T) {
+	brands := NewBrandStore(&database.Database{}, filepath.Join(t.TempDir(), "brands"))
+
+	acme, err := brands.Create(models.Brand{Name: "Acme Audio & Co."})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, acme.ID)
+	assert.Equal(t, "acme-audio-co", acme.Slug)
+
+	unnamed, err := brands.Create(models.Brand{Name: "ソニー"})
+	assert.NoError(t, err)
+	assert.Equal(t, "brand-2", unnamed.Slug)
+
+	_, err = brands.Create(models.Brand{Name: "Acme", Slug: "acme-audio-co"})
+	assert.ErrorIs(t, err, ErrDuplicateSlug)
+
+	// A brand keeps its own slug when replaced
+	renamed, err := brands.Replace(acme.ID, models.Brand{Name: "Acme", Slug: "acme-audio-co", Country: "US"})
+	assert.NoError(t, err)
+	assert.Equal(t, "US", renamed.Country)
+
+	found, err := brands.BySlug("acme-audio-co")
+	assert.NoError(t, err)
+	assert.Equal(t, acme.ID, found.ID)
+
+	listed, err := brands.List()
+	assert.NoError(t, err)
+	if assert.Len(t, listed, 2) {
+		assert.Equal(t, "Acme", listed[0].Name)
+	}
+
+	assert.NoError(t, brands.Delete(acme.ID))
+	_, err = brands.Get(acme.ID)
+	assert.ErrorIs(t, err, ErrBrandNotFound)
+	_, err = brands.BySlug("acme-audio-co")
+	assert.ErrorIs(t, err, ErrBrandNotFound)
+	assert.ErrorIs(t, brands.Delete(acme.ID), ErrBrandNotFound)
+	_, err = brands.Replace(acme.ID, models.Brand{Name: "Acme"})
+	assert.ErrorIs(t, err, ErrBrandNotFound)
+}
+
+func TestSlugify(t *testing.T) {
+	assert.Equal(t, "bang-olufsen", models.Slugify("  Bang & Olufsen "))
+	assert.Equal(t, "3m", models.Slugify("3M"))
+	assert.True(t, models.ValidSlug("bang-olufsen"))
+	assert.False(t, models.ValidSlug("Bang-Olufsen"))
+	assert.False(t, models.ValidSlug("bang--olufsen"))
+	assert.False(t, models.ValidSlug("-bang"))
+}
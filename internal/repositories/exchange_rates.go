@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"encoding/json"
+
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/money"
+)
+
+// ExchangeRateStore keeps the exchange-rate table in its own JSON file
+type ExchangeRateStore struct {
+	client *Client
+}
+
+// NewExchangeRateStore stores the exchange-rate table in the file at path
+func NewExchangeRateStore(fileStore database.FileStore, path string) *ExchangeRateStore {
+	return &ExchangeRateStore{client: NewFileClient(fileStore, path)}
+}
+
+// Load returns the current table. Without a file the table has no rates, so
+// only prices already in the requested currency can be shown.
+func (s *ExchangeRateStore) Load() (money.Rates, error) {
+	rates := money.Rates{Base: money.DefaultCurrency}
+
+	data, err := s.client.Load()
+	if err != nil || len(data) == 0 {
+		return rates, err
+	}
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return money.Rates{}, err
+	}
+	return rates, nil
+}
+
+// Save replaces the table after checking it
+func (s *ExchangeRateStore) Save(rates money.Rates) error {
+	if err := rates.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(rates, "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.client.WithLock(func(locked BaseRepositoryInterface) error {
+		return locked.Write(data)
+	})
+}
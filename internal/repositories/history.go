@@ -0,0 +1,213 @@
+package repositories
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/models"
+)
+
+// ErrRevisionNotFound is returned when a product has no revision with the
+// requested number
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// Revision actions
+const (
+	RevisionCreate   = "create"
+	RevisionUpdate   = "update"
+	RevisionPatch    = "patch"
+	RevisionDelete   = "delete"
+	RevisionRestore  = "restore"
+	RevisionRollback = "rollback"
+)
+
+// FieldChange is a single changed field of a revision. Specifications are
+// compared key by key and reported as "specifications.<key>".
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// Revision records one change of a product. Product is the state right after
+// the change, which is what a rollback to this revision brings back.
+type Revision struct {
+	ID        int             `json:"id"`
+	ProductID int             `json:"product_id"`
+	Rev       int             `json:"rev"`
+	Action    string          `json:"action"`
+	Actor     string          `json:"actor,omitempty"`
+	At        time.Time       `json:"at"`
+	Changes   []FieldChange   `json:"changes"`
+	Product   *models.Product `json:"product"`
+}
+
+// ProductHistory keeps the revisions of every product in its own file
+type ProductHistory struct {
+	records *Repository[Revision]
+}
+
+// NewProductHistory stores the revision history in the file at path
+func NewProductHistory(fileStore database.FileStore, path string) *ProductHistory {
+	return &ProductHistory{
+		records: NewRepository[Revision](
+			NewFileClient(fileStore, path),
+			JSONCodec[Revision]{},
+			func(r Revision) int { return r.ID },
+			func(r *Revision, id int) { r.ID = id },
+		),
+	}
+}
+
+// Change is a product mutation to record; Before is nil for a creation
+type Change struct {
+	Action string
+	Before *models.Product
+	After  *models.Product
+}
+
+// Record stores a revision of the product going from before to after; before
+// is nil for a creation. Changes that leave every field as it was are not
+// recorded and return ok false.
+func (h *ProductHistory) Record(action, actor string, before, after *models.Product) (rev Revision, ok bool, err error) {
+	revisions, err := h.RecordAll(actor, []Change{{Action: action, Before: before, After: after}})
+	if err != nil || len(revisions) == 0 {
+		return Revision{}, false, err
+	}
+	return revisions[0], true, nil
+}
+
+// RecordAll stores the revisions of several changes with a single write,
+// skipping the ones that change nothing
+func (h *ProductHistory) RecordAll(actor string, changes []Change) ([]Revision, error) {
+	recorded := make([]Revision, 0, len(changes))
+	err := h.records.Modify(func(revisions []Revision) ([]Revision, error) {
+		recorded = recorded[:0]
+		nextID := h.records.NextID(revisions)
+		lastRev := make(map[int]int)
+		for _, r := range revisions {
+			lastRev[r.ProductID] = max(lastRev[r.ProductID], r.Rev)
+		}
+
+		now := time.Now().UTC()
+		for _, change := range changes {
+			diff := DiffProducts(change.Before, change.After)
+			if len(diff) == 0 {
+				continue
+			}
+
+			productID := change.After.ID
+			lastRev[productID]++
+			rev := Revision{
+				ID:        nextID,
+				ProductID: productID,
+				Rev:       lastRev[productID],
+				Action:    change.Action,
+				Actor:     actor,
+				At:        now,
+				Changes:   diff,
+				Product:   change.After,
+			}
+			nextID++
+			revisions = append(revisions, rev)
+			recorded = append(recorded, rev)
+		}
+		return revisions, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return recorded, nil
+}
+
+// Purge removes the revisions of purged products
+func (h *ProductHistory) Purge(products []models.Product) error {
+	ids := productIDSet(products)
+	return h.records.DeleteWhere(func(r Revision) bool { return ids[r.ProductID] })
+}
+
+// List returns the revisions of a product, oldest first
+func (h *ProductHistory) List(productID int) ([]Revision, error) {
+	revisions, err := h.records.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Revision, 0)
+	for _, r := range revisions {
+		if r.ProductID == productID {
+			result = append(result, r)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Rev < result[j].Rev })
+
+	return result, nil
+}
+
+// Get returns revision rev of a product
+func (h *ProductHistory) Get(productID, rev int) (Revision, error) {
+	revisions, err := h.List(productID)
+	if err != nil {
+		return Revision{}, err
+	}
+
+	for _, r := range revisions {
+		if r.Rev == rev {
+			return r, nil
+		}
+	}
+
+	return Revision{}, ErrRevisionNotFound
+}
+
+// DiffProducts lists the fields that differ between before and after, sorted
+// by field name. A nil product has no fields.
+func DiffProducts(before, after *models.Product) []FieldChange {
+	oldFields := productFields(before)
+	newFields := productFields(after)
+
+	names := make([]string, 0, len(oldFields)+len(newFields))
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	changes := make([]FieldChange, 0)
+	for _, name := range names {
+		if !reflect.DeepEqual(oldFields[name], newFields[name]) {
+			changes = append(changes, FieldChange{Field: name, Old: oldFields[name], New: newFields[name]})
+		}
+	}
+
+	return changes
+}
+
+// productFields flattens a product into its JSON fields, with one entry per
+// specification
+func productFields(p *models.Product) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if p == nil {
+		return fields
+	}
+
+	data, _ := json.Marshal(p)
+	json.Unmarshal(data, &fields)
+
+	specs, _ := fields["specifications"].(map[string]interface{})
+	delete(fields, "specifications")
+	for k, v := range specs {
+		fields["specifications."+k] = v
+	}
+
+	return fields
+}
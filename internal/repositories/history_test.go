@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"path/filepath"
+	"testing"
+
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffProducts_ComparesSpecificationsByKey(t *testing.T) {
+	before := &models.Product{ID: 1, Name: "Laptop", Price: usd("1200"), Specifications: map[string]string{"RAM": "16GB", "Storage": "512GB"}}
+	after := &models.Product{ID: 1, Name: "Laptop", Price: usd("1100"), Specifications: map[string]string{"RAM": "32GB", "GPU": "RTX"}}
+
+	assert.Equal(t, []FieldChange{
+		{Field: "price", Old: map[string]interface{}{"amount": "1200", "currency": "USD"}, New: map[string]interface{}{"amount": "1100", "currency": "USD"}},
+		{Field: "specifications.GPU", Old: nil, New: "RTX"},
+		{Field: "specifications.RAM", Old: "16GB", New: "32GB"},
+		{Field: "specifications.Storage", Old: "512GB", New: nil},
+	}, DiffProducts(before, after))
+
+	assert.Empty(t, DiffProducts(before, before))
+}
+
+func TestProductHistory_RecordsNumberedRevisions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json.history")
+	history := NewProductHistory(&database.Database{}, path)
+
+	v1 := &models.Product{ID: 7, Name: "Laptop", Price: usd("1200")}
+	v2 := &models.Product{ID: 7, Name: "Laptop", Price: usd("999")}
+	other := &models.Product{ID: 8, Name: "Mouse"}
+
+	rev, ok, err := history.Record(RevisionCreate, "alice", nil, v1)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, rev.Rev)
+
+	_, _, err = history.Record(RevisionCreate, "", nil, other)
+	assert.NoError(t, err)
+
+	rev, ok, err = history.Record(RevisionUpdate, "bob", v1, v2)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, rev.Rev)
+	assert.Equal(t, []FieldChange{{Field: "price", Old: map[string]interface{}{"amount": "1200", "currency": "USD"}, New: map[string]interface{}{"amount": "999", "currency": "USD"}}}, rev.Changes)
+
+	// Saving the same state again records nothing
+	_, ok, err = history.Record(RevisionUpdate, "bob", v2, v2)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// A new instance reads the persisted history
+	revisions, err := NewProductHistory(&database.Database{}, path).List(7)
+	assert.NoError(t, err)
+	if assert.Len(t, revisions, 2) {
+		assert.Equal(t, "alice", revisions[0].Actor)
+		assert.Equal(t, RevisionUpdate, revisions[1].Action)
+		assert.Equal(t, usd("999"), revisions[1].Product.Price)
+	}
+
+	_, err = history.Get(7, 3)
+	assert.ErrorIs(t, err, ErrRevisionNotFound)
+}
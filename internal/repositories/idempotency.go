@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"time"
+
+	"item-comparison-ai-api/internal/database"
+)
+
+// IdempotencyRecord remembers a request sent with an Idempotency-Key and,
+// once it completed, its response. Hash fingerprints the request so that a
+// key reused for another request can be told apart from a retry.
+type IdempotencyRecord struct {
+	ID          int       `json:"id"`
+	Key         string    `json:"key"`
+	Hash        string    `json:"hash"`
+	Completed   bool      `json:"completed"`
+	Status      int       `json:"status,omitempty"`
+	ContentType string    `json:"content_type,omitempty"`
+	ETag        string    `json:"etag,omitempty"`
+	Body        []byte    `json:"body,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// reservationTTL bounds how long a key stays in progress. A request that
+// never completes, because the process crashed, frees its key after that
+// time instead of the full TTL.
+const reservationTTL = time.Minute
+
+// IdempotencyStore keeps idempotency records in their own file for TTL after
+// they were created
+type IdempotencyStore struct {
+	records *Repository[IdempotencyRecord]
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+// NewIdempotencyStore stores the idempotency records in the file at path
+func NewIdempotencyStore(fileStore database.FileStore, path string, ttl time.Duration) *IdempotencyStore {
+	return &IdempotencyStore{
+		records: NewRepository[IdempotencyRecord](
+			NewFileClient(fileStore, path),
+			JSONCodec[IdempotencyRecord]{},
+			func(r IdempotencyRecord) int { return r.ID },
+			func(r *IdempotencyRecord, id int) { r.ID = id },
+		),
+		ttl: ttl,
+		now: time.Now,
+	}
+}
+
+// Reserve claims key for a request with the given hash. When the key is
+// already taken it returns the existing record, which may still be in
+// progress, and reserved false. Expired records are dropped on the way.
+func (s *IdempotencyStore) Reserve(key, hash string) (existing IdempotencyRecord, reserved bool, err error) {
+	err = s.records.Modify(func(records []IdempotencyRecord) ([]IdempotencyRecord, error) {
+		now := s.now().UTC()
+		live := records[:0]
+		for _, r := range records {
+			if r.ExpiresAt.After(now) {
+				live = append(live, r)
+			}
+		}
+
+		for _, r := range live {
+			if r.Key == key {
+				existing = r
+				return live, nil
+			}
+		}
+
+		reserved = true
+		return append(live, IdempotencyRecord{
+			ID:        s.records.NextID(live),
+			Key:       key,
+			Hash:      hash,
+			CreatedAt: now,
+			ExpiresAt: now.Add(min(s.ttl, reservationTTL)),
+		}), nil
+	})
+	return existing, reserved, err
+}
+
+// Complete stores the response of the request that reserved key and keeps
+// it for the TTL
+func (s *IdempotencyStore) Complete(key string, status int, contentType, etag string, body []byte) error {
+	return s.records.Modify(func(records []IdempotencyRecord) ([]IdempotencyRecord, error) {
+		for i := range records {
+			if records[i].Key == key {
+				records[i].Completed = true
+				records[i].Status = status
+				records[i].ContentType = contentType
+				records[i].ETag = etag
+				records[i].Body = body
+				records[i].ExpiresAt = records[i].CreatedAt.Add(s.ttl)
+			}
+		}
+		return records, nil
+	})
+}
+
+// Release frees key so that the request can be retried, for requests that
+// failed without an answer worth replaying
+func (s *IdempotencyStore) Release(key string) error {
+	return s.records.Modify(func(records []IdempotencyRecord) ([]IdempotencyRecord, error) {
+		kept := records[:0]
+		for _, r := range records {
+			if r.Key != key {
+				kept = append(kept, r)
+			}
+		}
+		return kept, nil
+	})
+}
@@ -0,0 +1,79 @@
+package repositories
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"item-comparison-ai-api/internal/database"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotencyStore_ReservesOncePerKey(t *testing.T) {
+	store := NewIdempotencyStore(&database.Database{}, filepath.Join(t.TempDir(), "data.json.idempotency"), time.Hour)
+
+	_, reserved, err := store.Reserve("abc", "hash-1")
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+
+	existing, reserved, err := store.Reserve("abc", "hash-1")
+	assert.NoError(t, err)
+	assert.False(t, reserved)
+	assert.False(t, existing.Completed)
+
+	assert.NoError(t, store.Complete("abc", 201, "application/json", `"tag"`, []byte(`{"id":4}`)))
+	existing, _, err = store.Reserve("abc", "hash-2")
+	assert.NoError(t, err)
+	assert.True(t, existing.Completed)
+	assert.Equal(t, "hash-1", existing.Hash)
+	assert.Equal(t, 201, existing.Status)
+	assert.Equal(t, `{"id":4}`, string(existing.Body))
+	assert.Equal(t, `"tag"`, existing.ETag)
+
+	assert.NoError(t, store.Release("abc"))
+	_, reserved, err = store.Reserve("abc", "hash-2")
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+}
+
+func TestIdempotencyStore_ExpiresRecordsAfterTTL(t *testing.T) {
+	store := NewIdempotencyStore(&database.Database{}, filepath.Join(t.TempDir(), "data.json.idempotency"), time.Hour)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	_, reserved, _ := store.Reserve("abc", "hash-1")
+	assert.True(t, reserved)
+
+	now = now.Add(2 * time.Hour)
+	_, reserved, err := store.Reserve("abc", "hash-2")
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+
+	records, err := store.records.Load()
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+}
+
+func TestIdempotencyStore_ReservationsExpireEarly(t *testing.T) {
+	store := NewIdempotencyStore(&database.Database{}, filepath.Join(t.TempDir(), "data.json.idempotency"), time.Hour)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	_, reserved, _ := store.Reserve("crashed", "hash-1")
+	assert.True(t, reserved)
+	_, reserved, _ = store.Reserve("done", "hash-1")
+	assert.True(t, reserved)
+	assert.NoError(t, store.Complete("done", 201, "application/json", "", nil))
+
+	// A key left in progress is free again long before the TTL, while a
+	// completed response is still replayed
+	now = now.Add(2 * reservationTTL)
+	_, reserved, err := store.Reserve("crashed", "hash-1")
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+	existing, reserved, err := store.Reserve("done", "hash-1")
+	assert.NoError(t, err)
+	assert.False(t, reserved)
+	assert.True(t, existing.Completed)
+}
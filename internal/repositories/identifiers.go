@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"item-comparison-ai-api/internal/models"
+)
+
+// ErrDuplicateIdentifier is matched by every DuplicateIdentifierError
+var ErrDuplicateIdentifier = errors.New("duplicate product identifier")
+
+// DuplicateIdentifierError is returned when a save would give two products
+// the same SKU or GTIN. Trashed products keep their identifiers.
+type DuplicateIdentifierError struct {
+	Field string
+	Value string
+	// ID is the product already holding the identifier
+	ID int
+}
+
+func (e *DuplicateIdentifierError) Error() string {
+	return fmt.Sprintf("%s %s is already used by product %d", e.Field, e.Value, e.ID)
+}
+
+// Is makes errors.Is(err, ErrDuplicateIdentifier) match
+func (e *DuplicateIdentifierError) Is(target error) bool {
+	return target == ErrDuplicateIdentifier
+}
+
+// identifierIndex maps the SKUs and normalized GTINs of a catalog to the
+// products holding them
+type identifierIndex struct {
+	skus  map[string]int
+	gtins map[string]int
+}
+
+func newIdentifierIndex() identifierIndex {
+	return identifierIndex{skus: make(map[string]int), gtins: make(map[string]int)}
+}
+
+// add records the identifiers of p, failing when another product holds one
+func (idx identifierIndex) add(p models.Product) error {
+	if err := idx.check(p); err != nil {
+		return err
+	}
+	if p.SKU != "" {
+		idx.skus[p.SKU] = p.ID
+	}
+	if p.GTIN != "" {
+		idx.gtins[models.NormalizeGTIN(p.GTIN)] = p.ID
+	}
+	return nil
+}
+
+func (idx identifierIndex) check(p models.Product) error {
+	if id, ok := idx.skus[p.SKU]; ok && p.SKU != "" && id != p.ID {
+		return &DuplicateIdentifierError{Field: "sku", Value: p.SKU, ID: id}
+	}
+	if id, ok := idx.gtins[models.NormalizeGTIN(p.GTIN)]; ok && p.GTIN != "" && id != p.ID {
+		return &DuplicateIdentifierError{Field: "gtin", Value: p.GTIN, ID: id}
+	}
+	return nil
+}
+
+// checkIdentifiers rejects catalogs in which two products share a SKU or GTIN
+func checkIdentifiers(products []models.Product) error {
+	idx := newIdentifierIndex()
+	for _, p := range products {
+		if err := idx.add(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
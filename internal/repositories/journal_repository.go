@@ -0,0 +1,321 @@
+package repositories
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"item-comparison-ai-api/config"
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/migrations"
+	"item-comparison-ai-api/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Journal operations
+const (
+	journalOpPut    = "put"
+	journalOpDelete = "delete"
+)
+
+// journalRecord is a single line of the write-ahead journal
+type journalRecord struct {
+	Seq     uint64          `json:"seq"`
+	Op      string          `json:"op"`
+	ID      int             `json:"id"`
+	Product *models.Product `json:"product,omitempty"`
+	At      time.Time       `json:"at"`
+}
+
+// JournalRepository keeps the catalog in memory, appends every mutation to a
+// journal file and periodically folds the journal into the snapshot file.
+// Records are idempotent upserts/deletes keyed by ID, so replaying a journal
+// that was already partially compacted yields the same state.
+type JournalRepository struct {
+	fileStore    database.FileStore
+	snapshotPath string
+	journalPath  string
+	threshold    int
+
+	mu       sync.Mutex
+	products []models.Product
+	mark     idMark
+	seq      uint64
+	pending  int
+
+	compactNow chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewJournalRepository rebuilds the catalog from the snapshot plus journal and
+// starts the background compaction loop
+func NewJournalRepository(fileStore database.FileStore, conf *config.AppConfig) (*JournalRepository, error) {
+	r := &JournalRepository{
+		fileStore:    fileStore,
+		snapshotPath: conf.DatabasePath,
+		journalPath:  conf.JournalPath,
+		threshold:    conf.JournalCompactThreshold,
+		compactNow:   make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+
+	if err := r.recover(); err != nil {
+		return nil, err
+	}
+
+	go r.compactLoop(conf.JournalCompactInterval)
+
+	return r, nil
+}
+
+// recover loads the last snapshot and replays the journal on top of it
+func (r *JournalRepository) recover() error {
+	snapshot, err := r.fileStore.Read(r.snapshotPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	doc, err := migrations.DecodeDocument(snapshot)
+	if err != nil {
+		return fmt.Errorf("journal: invalid snapshot %s: %w", r.snapshotPath, err)
+	}
+	products := doc.Products
+	r.mark.raise(doc.LastID, products)
+
+	journal, err := r.fileStore.Read(r.journalPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(journal))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		var rec journalRecord
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			// A torn final line is what a crash during append leaves behind;
+			// anything before it was fully written and is still trusted.
+			if !scanner.Scan() {
+				logrus.WithError(err).Warnf("journal: ignoring truncated record at line %d", line)
+				break
+			}
+			return fmt.Errorf("journal: corrupt record at line %d: %w", line, err)
+		}
+
+		products = applyJournalRecord(products, rec)
+		r.mark.raise(rec.ID, nil)
+		r.seq = rec.Seq
+		r.pending++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	r.products = products
+	return nil
+}
+
+func applyJournalRecord(products []models.Product, rec journalRecord) []models.Product {
+	for i, p := range products {
+		if p.ID != rec.ID {
+			continue
+		}
+		if rec.Op == journalOpDelete {
+			return append(products[:i], products[i+1:]...)
+		}
+		products[i] = *rec.Product
+		return products
+	}
+
+	if rec.Op == journalOpPut {
+		products = append(products, *rec.Product)
+	}
+	return products
+}
+
+// LoadProducts returns a copy of the in-memory catalog
+func (r *JournalRepository) LoadProducts() ([]models.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return cloneProducts(r.products), nil
+}
+
+// SaveProducts journals the difference between the current catalog and the
+// given one, then makes the given one current
+func (r *JournalRepository) SaveProducts(products []models.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.save(products)
+}
+
+// Update runs fn on a copy of the catalog and journals the result under the
+// repository lock
+func (r *JournalRepository) Update(fn func(tx *ProductTx) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx := NewProductTx(cloneProducts(r.products), r.GetNextID)
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return r.save(tx.Products)
+}
+
+func (r *JournalRepository) save(products []models.Product) error {
+	if err := checkCatalog(products); err != nil {
+		return err
+	}
+
+	records := r.diff(products)
+	if len(records) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, rec := range records {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	// All records of a save go out in a single append so a save is journaled
+	// as a unit.
+	if err := r.fileStore.Append(r.journalPath, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	r.seq = records[len(records)-1].Seq
+	r.pending += len(records)
+	r.products = cloneProducts(products)
+	r.mark.raise(0, products)
+
+	if r.threshold > 0 && r.pending >= r.threshold {
+		select {
+		case r.compactNow <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// diff builds the journal records that turn the current catalog into next
+func (r *JournalRepository) diff(next []models.Product) []journalRecord {
+	now := time.Now().UTC()
+	seq := r.seq
+
+	current := make(map[int]models.Product, len(r.products))
+	for _, p := range r.products {
+		current[p.ID] = p
+	}
+
+	var records []journalRecord
+	seen := make(map[int]bool, len(next))
+	for _, p := range next {
+		seen[p.ID] = true
+		if old, ok := current[p.ID]; ok && reflect.DeepEqual(old, p) {
+			continue
+		}
+		seq++
+		product := p
+		records = append(records, journalRecord{Seq: seq, Op: journalOpPut, ID: p.ID, Product: &product, At: now})
+	}
+
+	for _, p := range r.products {
+		if seen[p.ID] {
+			continue
+		}
+		seq++
+		records = append(records, journalRecord{Seq: seq, Op: journalOpDelete, ID: p.ID, At: now})
+	}
+
+	return records
+}
+
+// GetNextID calculates the next available ID for a new product, above every
+// ID assigned so far. Journal records of purged products keep their IDs
+// counted until compaction stores the mark in the snapshot.
+func (r *JournalRepository) GetNextID(products []models.Product) int {
+	return r.mark.next(products)
+}
+
+// Compact writes the current catalog as the new snapshot and truncates the
+// journal. The snapshot is written first, so a crash in between only leaves
+// records that replay to the same state.
+func (r *JournalRepository) Compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.pending == 0 {
+		return nil
+	}
+
+	data, err := migrations.EncodeDocument(migrations.Document{LastID: r.mark.raise(0, r.products), Products: r.products})
+	if err != nil {
+		return err
+	}
+	if err := r.fileStore.Write(r.snapshotPath, data, 0644); err != nil {
+		return err
+	}
+	if err := r.fileStore.Write(r.journalPath, nil, 0644); err != nil {
+		return err
+	}
+
+	r.pending = 0
+	return nil
+}
+
+func (r *JournalRepository) compactLoop(interval time.Duration) {
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-tick:
+		case <-r.compactNow:
+		}
+
+		if err := r.Compact(); err != nil {
+			logrus.WithError(err).Error("journal: compaction failed")
+		}
+	}
+}
+
+// Close stops background compaction and folds any pending records
+func (r *JournalRepository) Close() error {
+	r.closeOnce.Do(func() { close(r.done) })
+	return r.Compact()
+}
+
+func cloneProducts(products []models.Product) []models.Product {
+	result := make([]models.Product, len(products))
+	for i, p := range products {
+		p.Specifications = maps.Clone(p.Specifications)
+		result[i] = p
+	}
+	return result
+}
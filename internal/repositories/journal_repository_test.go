@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"item-comparison-ai-api/config"
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newJournalTestConfig(t *testing.T) *config.AppConfig {
+	dir := t.TempDir()
+	return &config.AppConfig{
+		DatabasePath: filepath.Join(dir, "data.json"),
+		JournalPath:  filepath.Join(dir, "data.json.journal"),
+		// Disable the ticker so tests decide when compaction happens
+		JournalCompactInterval: 0,
+	}
+}
+
+func TestJournalRepository_ReplaysJournalOnStartup(t *testing.T) {
+	conf := newJournalTestConfig(t)
+	db := &database.Database{}
+
+	repo, err := NewJournalRepository(db, conf)
+	assert.NoError(t, err)
+
+	products := []models.Product{
+		{ID: 1, Name: "Laptop", Specifications: map[string]string{"RAM": "16GB"}},
+		{ID: 2, Name: "Smartphone"},
+	}
+	assert.NoError(t, repo.SaveProducts(products))
+
+	products[0].Name = "Updated Laptop"
+	products = products[:1]
+	assert.NoError(t, repo.SaveProducts(products))
+
+	// Nothing was compacted yet, so the state only exists in the journal
+	_, err = os.Stat(conf.DatabasePath)
+	assert.True(t, os.IsNotExist(err))
+
+	reopened, err := NewJournalRepository(db, conf)
+	assert.NoError(t, err)
+
+	loaded, err := reopened.LoadProducts()
+	assert.NoError(t, err)
+	assert.Len(t, loaded, 1)
+	assert.Equal(t, "Updated Laptop", loaded[0].Name)
+	assert.Equal(t, "16GB", loaded[0].Specifications["RAM"])
+}
+
+func TestJournalRepository_Compact(t *testing.T) {
+	conf := newJournalTestConfig(t)
+	db := &database.Database{}
+
+	repo, err := NewJournalRepository(db, conf)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.SaveProducts([]models.Product{{ID: 1, Name: "Laptop"}}))
+
+	assert.NoError(t, repo.Compact())
+
+	journal, err := os.ReadFile(conf.JournalPath)
+	assert.NoError(t, err)
+	assert.Empty(t, journal)
+
+	reopened, err := NewJournalRepository(db, conf)
+	assert.NoError(t, err)
+	loaded, err := reopened.LoadProducts()
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Product{{ID: 1, Name: "Laptop"}}, loaded)
+}
+
+func TestJournalRepository_CompactsInBackgroundAtThreshold(t *testing.T) {
+	conf := newJournalTestConfig(t)
+	conf.JournalCompactThreshold = 2
+
+	repo, err := NewJournalRepository(&database.Database{}, conf)
+	assert.NoError(t, err)
+	defer repo.Close()
+
+	assert.NoError(t, repo.SaveProducts([]models.Product{{ID: 1, Name: "Laptop"}, {ID: 2, Name: "Smartphone"}}))
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(conf.DatabasePath)
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestJournalRepository_IgnoresTruncatedTail(t *testing.T) {
+	conf := newJournalTestConfig(t)
+	db := &database.Database{}
+
+	repo, err := NewJournalRepository(db, conf)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.SaveProducts([]models.Product{{ID: 1, Name: "Laptop"}}))
+
+	// Simulate a crash in the middle of an append
+	assert.NoError(t, db.Append(conf.JournalPath, []byte(`{"seq":2,"op":"put","id":2,"prod`), 0644))
+
+	reopened, err := NewJournalRepository(db, conf)
+	assert.NoError(t, err)
+	loaded, err := reopened.LoadProducts()
+	assert.NoError(t, err)
+	assert.Len(t, loaded, 1)
+}
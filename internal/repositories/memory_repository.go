@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"os"
+	"sync"
+
+	"item-comparison-ai-api/config"
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/migrations"
+	"item-comparison-ai-api/internal/models"
+)
+
+// memoryRepository keeps the catalog in process memory only. It is seeded
+// from a JSON data file when one is configured and never writes back.
+type memoryRepository struct {
+	mu       sync.Mutex
+	products []models.Product
+	mark     idMark
+}
+
+// NewMemoryRepository creates a non-persistent ProductRepository seeded from
+// conf.MemorySeedPath
+func NewMemoryRepository(fileStore database.FileStore, conf *config.AppConfig) (ProductRepository, error) {
+	repo := &memoryRepository{products: make([]models.Product, 0)}
+	if conf.MemorySeedPath == "" {
+		return repo, nil
+	}
+
+	data, err := fileStore.Read(conf.MemorySeedPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return repo, nil
+		}
+		return nil, err
+	}
+	doc, err := migrations.DecodeDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	repo.products = doc.Products
+	repo.mark.raise(doc.LastID, doc.Products)
+
+	return repo, nil
+}
+
+// LoadProducts returns a copy of the in-memory catalog
+func (r *memoryRepository) LoadProducts() ([]models.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return cloneProducts(r.products), nil
+}
+
+// SaveProducts replaces the in-memory catalog
+func (r *memoryRepository) SaveProducts(products []models.Product) error {
+	if err := checkCatalog(products); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.products = cloneProducts(products)
+	r.mark.raise(0, products)
+	return nil
+}
+
+// Update runs fn on a copy of the catalog and keeps the result
+func (r *memoryRepository) Update(fn func(tx *ProductTx) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx := NewProductTx(cloneProducts(r.products), r.GetNextID)
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := checkCatalog(tx.Products); err != nil {
+		return err
+	}
+
+	r.products = cloneProducts(tx.Products)
+	r.mark.raise(0, tx.Products)
+	return nil
+}
+
+// GetNextID calculates the next available ID for a new product, above every
+// ID assigned so far
+func (r *memoryRepository) GetNextID(products []models.Product) int {
+	return r.mark.next(products)
+}
@@ -0,0 +1,259 @@
+package repositories
+
+import (
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/money"
+)
+
+// PriceBaseline is the action of the point recording the price a product
+// had before the first change seen of it, such as a product of a seeded
+// catalog
+const PriceBaseline = "baseline"
+
+// PricePoint is the price a product had from At until its next point
+type PricePoint struct {
+	ID        int         `json:"id"`
+	ProductID int         `json:"product_id"`
+	Price     money.Money `json:"price"`
+	Action    string      `json:"action"`
+	At        time.Time   `json:"at"`
+}
+
+// PriceStats summarizes a price series. Only the points in the currency of
+// the latest one count, so that amounts are never mixed.
+type PriceStats struct {
+	Min     money.Money `json:"min"`
+	Max     money.Money `json:"max"`
+	Average money.Money `json:"average"`
+}
+
+// PriceDrop is a price change to a lower amount in the same currency.
+// Percent is the drop relative to From, rounded to two places.
+type PriceDrop struct {
+	ProductID int         `json:"product_id"`
+	From      money.Money `json:"from"`
+	To        money.Money `json:"to"`
+	Percent   float64     `json:"percent"`
+	At        time.Time   `json:"at"`
+}
+
+// PriceHistory keeps the price points of every product in its own file
+type PriceHistory struct {
+	records *Repository[PricePoint]
+	now     func() time.Time
+}
+
+// NewPriceHistory stores the price history in the file at path
+func NewPriceHistory(fileStore database.FileStore, path string) *PriceHistory {
+	return &PriceHistory{
+		records: NewRepository[PricePoint](
+			NewFileClient(fileStore, path),
+			JSONCodec[PricePoint]{},
+			func(p PricePoint) int { return p.ID },
+			func(p *PricePoint, id int) { p.ID = id },
+		),
+		now: time.Now,
+	}
+}
+
+// RecordAll stores a point for every change that sets a price: creations and
+// changes of the amount or currency. Other changes are skipped. A change of
+// a product without points also stores its price before the change, as a
+// PriceBaseline point, so that the change shows up as a drop or a rise.
+func (h *PriceHistory) RecordAll(changes []Change) error {
+	return h.records.Modify(func(points []PricePoint) ([]PricePoint, error) {
+		tracked := make(map[int]bool)
+		for _, p := range points {
+			tracked[p.ProductID] = true
+		}
+
+		nextID := h.records.NextID(points)
+		now := h.now().UTC()
+		add := func(productID int, price money.Money, action string) {
+			price.Conversion = nil
+			points = append(points, PricePoint{
+				ID:        nextID,
+				ProductID: productID,
+				Price:     price,
+				Action:    action,
+				At:        now,
+			})
+			tracked[productID] = true
+			nextID++
+		}
+
+		for _, change := range changes {
+			if change.After == nil {
+				continue
+			}
+			if change.Before != nil && samePrice(change.Before.Price, change.After.Price) {
+				continue
+			}
+			if change.Before != nil && !tracked[change.After.ID] {
+				add(change.After.ID, change.Before.Price, PriceBaseline)
+			}
+			add(change.After.ID, change.After.Price, change.Action)
+		}
+		return points, nil
+	})
+}
+
+// Purge removes the price points of purged products
+func (h *PriceHistory) Purge(products []models.Product) error {
+	ids := productIDSet(products)
+	return h.records.DeleteWhere(func(p PricePoint) bool { return ids[p.ProductID] })
+}
+
+// Series returns the points of a product, oldest first
+func (h *PriceHistory) Series(productID int) ([]PricePoint, error) {
+	series, err := h.SeriesOf([]int{productID})
+	return series[productID], err
+}
+
+// SeriesOf returns the points of several products, oldest first, by product
+// ID
+func (h *PriceHistory) SeriesOf(productIDs []int) (map[int][]PricePoint, error) {
+	points, err := h.records.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	series := make(map[int][]PricePoint, len(productIDs))
+	for _, id := range productIDs {
+		series[id] = make([]PricePoint, 0)
+	}
+	for _, p := range points {
+		if s, ok := series[p.ProductID]; ok {
+			series[p.ProductID] = append(s, p)
+		}
+	}
+	for _, s := range series {
+		sortPoints(s)
+	}
+	return series, nil
+}
+
+// Drops lists the price drops since the given time of at least minPercent,
+// newest first
+func (h *PriceHistory) Drops(since time.Time, minPercent float64) ([]PriceDrop, error) {
+	points, err := h.records.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	series := make(map[int][]PricePoint)
+	for _, p := range points {
+		series[p.ProductID] = append(series[p.ProductID], p)
+	}
+
+	drops := make([]PriceDrop, 0)
+	for _, s := range series {
+		sortPoints(s)
+		for i := 1; i < len(s); i++ {
+			from, to := s[i-1].Price, s[i].Price
+			if s[i].At.Before(since) || from.Currency != to.Currency || from.Amount.Sign() <= 0 || to.Amount.Cmp(from.Amount) >= 0 {
+				continue
+			}
+
+			ratio := new(big.Rat).Quo(new(big.Rat).Sub(from.Amount.Rat(), to.Amount.Rat()), from.Amount.Rat())
+			percent, _ := new(big.Rat).Mul(ratio, big.NewRat(100, 1)).Float64()
+			percent = math.Round(percent*100) / 100
+			if percent < minPercent {
+				continue
+			}
+
+			drops = append(drops, PriceDrop{ProductID: s[i].ProductID, From: from, To: to, Percent: percent, At: s[i].At})
+		}
+	}
+
+	sort.Slice(drops, func(i, j int) bool {
+		if !drops[i].At.Equal(drops[j].At) {
+			return drops[i].At.After(drops[j].At)
+		}
+		return drops[i].ProductID < drops[j].ProductID
+	})
+	return drops, nil
+}
+
+// Stats computes the lowest, highest and average price of a series. It
+// reports false for an empty series.
+func Stats(points []PricePoint) (PriceStats, bool) {
+	current := latestCurrency(points)
+	if current == "" {
+		return PriceStats{}, false
+	}
+
+	var stats PriceStats
+	sum, n := new(big.Rat), 0
+	for _, p := range points {
+		if p.Price.Currency != current {
+			continue
+		}
+		if n == 0 || p.Price.Amount.Cmp(stats.Min.Amount) < 0 {
+			stats.Min = p.Price
+		}
+		if n == 0 || p.Price.Amount.Cmp(stats.Max.Amount) > 0 {
+			stats.Max = p.Price
+		}
+		sum.Add(sum, p.Price.Amount.Rat())
+		n++
+	}
+
+	average, err := money.FromRat(sum.Quo(sum, big.NewRat(int64(n), 1)), money.MinorUnits(current))
+	if err != nil {
+		return PriceStats{}, false
+	}
+	stats.Average = money.New(average, current)
+	return stats, true
+}
+
+// LowestSince returns the lowest price a series had at any time since the
+// given time, counting the price already in effect then. It reports false
+// when the series has no price in the latest currency for that period.
+func LowestSince(points []PricePoint, since time.Time) (money.Money, bool) {
+	current := latestCurrency(points)
+
+	var (
+		lowest money.Money
+		found  bool
+	)
+	for i, p := range points {
+		// A point still counts while the next one is after since
+		if i+1 < len(points) && !points[i+1].At.After(since) {
+			continue
+		}
+		if p.Price.Currency != current {
+			continue
+		}
+		if !found || p.Price.Amount.Cmp(lowest.Amount) < 0 {
+			lowest, found = p.Price, true
+		}
+	}
+	return lowest, found
+}
+
+func latestCurrency(points []PricePoint) string {
+	if len(points) == 0 {
+		return ""
+	}
+	return points[len(points)-1].Price.Currency
+}
+
+func samePrice(a, b money.Money) bool {
+	return a.Currency == b.Currency && a.Amount == b.Amount
+}
+
+func sortPoints(points []PricePoint) {
+	sort.SliceStable(points, func(i, j int) bool {
+		if !points[i].At.Equal(points[j].At) {
+			return points[i].At.Before(points[j].At)
+		}
+		return points[i].ID < points[j].ID
+	})
+}
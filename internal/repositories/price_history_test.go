@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriceHistory_RecordsPriceChangesOnly(t *testing.T) {
+	prices := NewPriceHistory(&database.Database{}, filepath.Join(t.TempDir(), "prices"))
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	prices.now = func() time.Time { return day }
+
+	v1 := &models.Product{ID: 1, Name: "Laptop", Price: usd("1200")}
+	renamed := &models.Product{ID: 1, Name: "Laptop Pro", Price: usd("1200")}
+	v2 := &models.Product{ID: 1, Name: "Laptop Pro", Price: usd("999")}
+
+	assert.NoError(t, prices.RecordAll([]Change{{Action: RevisionCreate, After: v1}}))
+	day = day.Add(24 * time.Hour)
+	assert.NoError(t, prices.RecordAll([]Change{
+		{Action: RevisionUpdate, Before: v1, After: renamed},
+		{Action: RevisionDelete, Before: renamed, After: renamed},
+	}))
+	day = day.Add(24 * time.Hour)
+	assert.NoError(t, prices.RecordAll([]Change{{Action: RevisionPatch, Before: renamed, After: v2}}))
+
+	points, err := prices.Series(1)
+	assert.NoError(t, err)
+	if assert.Len(t, points, 2) {
+		assert.Equal(t, usd("1200"), points[0].Price)
+		assert.Equal(t, RevisionCreate, points[0].Action)
+		assert.Equal(t, usd("999"), points[1].Price)
+		assert.Equal(t, time.Date(2026, 10, 3, 0, 0, 0, 0, time.UTC), points[1].At)
+	}
+
+	empty, err := prices.Series(2)
+	assert.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func TestPriceHistory_RecordsBaselineOfUntrackedProducts(t *testing.T) {
+	prices := NewPriceHistory(&database.Database{}, filepath.Join(t.TempDir(), "prices"))
+
+	seeded := &models.Product{ID: 1, Name: "Laptop", Price: usd("1200")}
+	reduced := &models.Product{ID: 1, Name: "Laptop", Price: usd("1000")}
+	raised := &models.Product{ID: 1, Name: "Laptop", Price: usd("1100")}
+	assert.NoError(t, prices.RecordAll([]Change{{Action: RevisionUpdate, Before: seeded, After: reduced}}))
+	assert.NoError(t, prices.RecordAll([]Change{{Action: RevisionUpdate, Before: reduced, After: raised}}))
+
+	points, err := prices.Series(1)
+	assert.NoError(t, err)
+	if assert.Len(t, points, 3) {
+		assert.Equal(t, PriceBaseline, points[0].Action)
+		assert.Equal(t, usd("1200"), points[0].Price)
+		assert.Equal(t, usd("1000"), points[1].Price)
+		assert.Equal(t, usd("1100"), points[2].Price)
+	}
+
+	drops, err := prices.Drops(time.Time{}, 0)
+	assert.NoError(t, err)
+	if assert.Len(t, drops, 1) {
+		assert.Equal(t, usd("1200"), drops[0].From)
+	}
+}
+
+func TestPriceHistory_Drops(t *testing.T) {
+	prices := NewPriceHistory(&database.Database{}, filepath.Join(t.TempDir(), "prices"))
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	prices.now = func() time.Time { return day }
+
+	set := func(id int, before, after string) {
+		change := Change{Action: RevisionUpdate, After: &models.Product{ID: id, Price: usd(after)}}
+		if before != "" {
+			change.Before = &models.Product{ID: id, Price: usd(before)}
+		}
+		assert.NoError(t, prices.RecordAll([]Change{change}))
+	}
+	set(1, "", "100")
+	set(2, "", "50")
+	day = day.Add(24 * time.Hour)
+	set(1, "100", "95")
+	day = day.Add(24 * time.Hour)
+	set(1, "95", "80")
+	set(2, "50", "60")
+
+	drops, err := prices.Drops(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC), 0)
+	assert.NoError(t, err)
+	if assert.Len(t, drops, 2) {
+		assert.Equal(t, usd("95"), drops[0].From)
+		assert.Equal(t, usd("80"), drops[0].To)
+		assert.Equal(t, 15.79, drops[0].Percent)
+		assert.Equal(t, 5.0, drops[1].Percent)
+	}
+
+	drops, err = prices.Drops(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC), 10)
+	assert.NoError(t, err)
+	assert.Len(t, drops, 1)
+
+	drops, err = prices.Drops(time.Date(2026, 10, 2, 12, 0, 0, 0, time.UTC), 0)
+	assert.NoError(t, err)
+	assert.Len(t, drops, 1)
+}
+
+func TestPriceStats(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC) }
+	points := []PricePoint{
+		{Price: usd("100"), At: day(1)},
+		{Price: usd("80"), At: day(5)},
+		{Price: usd("90"), At: day(10)},
+		{Price: usd("95.5"), At: day(20)},
+	}
+
+	stats, ok := Stats(points)
+	assert.True(t, ok)
+	assert.Equal(t, usd("80"), stats.Min)
+	assert.Equal(t, usd("100"), stats.Max)
+	assert.Equal(t, usd("91.38"), stats.Average)
+
+	// The price in effect when the window starts counts
+	lowest, ok := LowestSince(points, day(7))
+	assert.True(t, ok)
+	assert.Equal(t, usd("80"), lowest)
+	lowest, _ = LowestSince(points, day(12))
+	assert.Equal(t, usd("90"), lowest)
+	lowest, _ = LowestSince(points, day(25))
+	assert.Equal(t, usd("95.5"), lowest)
+
+	_, ok = Stats(nil)
+	assert.False(t, ok)
+}
@@ -0,0 +1,146 @@
+package repositories
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"strings"
+
+	"item-comparison-ai-api/internal/models"
+)
+
+// ErrInvalidSortField is returned when a filter sorts by an unknown field
+var ErrInvalidSortField = errors.New("invalid sort field")
+
+// DeletedFilter selects products by their soft-delete state
+type DeletedFilter int
+
+const (
+	// ExcludeDeleted keeps only live products; it is the zero value so
+	// listings never show the trash by accident
+	ExcludeDeleted DeletedFilter = iota
+	// OnlyDeleted keeps only the products in the trash
+	OnlyDeleted
+	// IncludeDeleted keeps every product
+	IncludeDeleted
+)
+
+// ProductFilter describes which slice of the catalog a listing wants
+type ProductFilter struct {
+	Category string
+	SKU      string
+	// BrandID keeps only the products of that brand
+	BrandID int
+	// ParentID keeps only the variants of that product
+	ParentID int
+	// ExcludeVariants keeps only the products that are not variants, which
+	// collapses every variant into its parent
+	ExcludeVariants bool
+	// Specs matches products whose specifications contain every key/value pair
+	Specs map[string]string
+	// Sort is a product field name, prefixed with "-" for descending order
+	Sort string
+	// Limit caps the number of results; a negative limit means no cap
+	Limit   int
+	Offset  int
+	Deleted DeletedFilter
+}
+
+// ProductQuerier is implemented by repositories able to evaluate a
+// ProductFilter themselves instead of returning the whole catalog
+type ProductQuerier interface {
+	QueryProducts(filter ProductFilter) ([]models.Product, error)
+}
+
+// productSortFields lists the fields a listing can be sorted by. Prices
+// compare by amount alone, which only orders products of one currency;
+// handlers sort mixed catalogs by converted prices themselves.
+var productSortFields = map[string]func(a, b models.Product) int{
+	"id":       func(a, b models.Product) int { return cmp.Compare(a.ID, b.ID) },
+	"name":     func(a, b models.Product) int { return strings.Compare(a.Name, b.Name) },
+	"price":    func(a, b models.Product) int { return a.Price.Amount.Cmp(b.Price.Amount) },
+	"rating":   func(a, b models.Product) int { return cmp.Compare(a.Rating, b.Rating) },
+	"category": func(a, b models.Product) int { return strings.Compare(a.Category, b.Category) },
+}
+
+// ParseSort splits a sort expression into field and direction
+func ParseSort(sort string) (field string, desc bool, err error) {
+	field = strings.TrimPrefix(sort, "-")
+	desc = strings.HasPrefix(sort, "-")
+	if _, ok := productSortFields[field]; !ok {
+		return "", false, ErrInvalidSortField
+	}
+	return field, desc, nil
+}
+
+// Matches reports whether a product satisfies the filter predicates
+func (f ProductFilter) Matches(p models.Product) bool {
+	switch f.Deleted {
+	case ExcludeDeleted:
+		if p.IsDeleted() {
+			return false
+		}
+	case OnlyDeleted:
+		if !p.IsDeleted() {
+			return false
+		}
+	}
+	if f.Category != "" && p.Category != f.Category {
+		return false
+	}
+	if f.SKU != "" && p.SKU != f.SKU {
+		return false
+	}
+	if f.BrandID != 0 && p.BrandID != f.BrandID {
+		return false
+	}
+	if f.ParentID != 0 && p.ParentID != f.ParentID {
+		return false
+	}
+	if f.ExcludeVariants && p.IsVariant() {
+		return false
+	}
+	for k, v := range f.Specs {
+		if p.Specifications[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterProducts applies a ProductFilter in memory, for repositories that
+// do not implement ProductQuerier
+func FilterProducts(products []models.Product, filter ProductFilter) ([]models.Product, error) {
+	result := make([]models.Product, 0, len(products))
+	for _, p := range products {
+		if filter.Matches(p) {
+			result = append(result, p)
+		}
+	}
+
+	if filter.Sort != "" {
+		field, desc, err := ParseSort(filter.Sort)
+		if err != nil {
+			return nil, err
+		}
+		compare := productSortFields[field]
+		slices.SortStableFunc(result, func(a, b models.Product) int {
+			if desc {
+				return compare(b, a)
+			}
+			return compare(a, b)
+		})
+	}
+
+	return filter.Paginate(result), nil
+}
+
+// Paginate returns the page of products the filter's offset and limit select
+func (f ProductFilter) Paginate(products []models.Product) []models.Product {
+	start := min(f.Offset, len(products))
+	end := len(products)
+	if f.Limit >= 0 {
+		end = min(start+f.Limit, len(products))
+	}
+	return products[start:end]
+}
@@ -1,68 +1,133 @@
 package repositories
 
 import (
-	"encoding/json"
+	"sync"
+
+	"item-comparison-ai-api/internal/migrations"
 	"item-comparison-ai-api/internal/models"
-	"os"
 )
 
 type ProductRepository interface {
 	LoadProducts() ([]models.Product, error)
 	SaveProducts([]models.Product) error
 	GetNextID([]models.Product) int
+	// Update runs fn on the current catalog and saves the result, holding the
+	// repository lock across the whole load-modify-save. Returning an error
+	// from fn discards its changes.
+	Update(fn func(tx *ProductTx) error) error
 }
 
-// productRepository implements the ProductRepository interface
+// productRepository implements the ProductRepository interface on top of the
+// generic Repository
 type productRepository struct {
-	baseRepo BaseRepositoryInterface
+	records *Repository[models.Product]
+	mark    *idMark
 }
 
-// LoadProducts reads products from the data.json file
-func (p *productRepository) LoadProducts() ([]models.Product, error) {
-	data, err := p.baseRepo.Load()
+// productCodec reads and writes the versioned data file format, upgrading
+// files written in older formats. The ID mark travels in the file next to
+// the products.
+type productCodec struct {
+	mark *idMark
+}
+
+func (c productCodec) Decode(data []byte) ([]models.Product, error) {
+	doc, err := migrations.DecodeDocument(data)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return make([]models.Product, 0), nil // Return empty slice if file doesn't exist
-		}
 		return nil, err
 	}
+	c.mark.raise(doc.LastID, doc.Products)
+	return doc.Products, nil
+}
 
-	var products []models.Product
-	if err := json.Unmarshal(data, &products); err != nil {
-		return nil, err
-	}
+func (c productCodec) Encode(products []models.Product) ([]byte, error) {
+	return migrations.EncodeDocument(migrations.Document{LastID: c.mark.raise(0, products), Products: products})
+}
 
-	// Convert []models.Product to []models.Product
-	result := make([]models.Product, len(products))
-	for i, p := range products {
-		result[i] = p
-	}
+// idMark is the highest product ID ever assigned. Purged products leave
+// their IDs below it, so a new product never takes over the ID, and with it
+// the history, prices and reviews, of a purged one.
+type idMark struct {
+	mu   sync.Mutex
+	last int
+}
+
+// raise lifts the mark to id and to the IDs of products, returning it
+func (m *idMark) raise(id int, products []models.Product) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.last = max(m.last, id, maxProductID(products))
+	return m.last
+}
 
-	return result, nil
+// next returns the ID after the mark and the IDs of products
+func (m *idMark) next(products []models.Product) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return max(m.last, maxProductID(products)) + 1
+}
+
+func productID(p models.Product) int { return p.ID }
+
+func setProductID(p *models.Product, id int) { p.ID = id }
+
+// LoadProducts reads products from the data.json file
+func (p *productRepository) LoadProducts() ([]models.Product, error) {
+	return p.records.Load()
 }
 
-// SaveProducts writes products to the data.json file
+// SaveProducts writes products to the data.json file. The file is read
+// first, so the ID mark stored in it is carried over.
 func (p *productRepository) SaveProducts(model []models.Product) error {
-	data := make([]interface{}, len(model))
-	for idx, item := range model {
-		data[idx] = item
+	if err := checkCatalog(model); err != nil {
+		return err
 	}
+	return p.records.Modify(func([]models.Product) ([]models.Product, error) {
+		return model, nil
+	})
+}
 
-	return p.baseRepo.Save(data)
+// Update loads, modifies and saves the catalog as one locked operation
+func (p *productRepository) Update(fn func(tx *ProductTx) error) error {
+	return p.records.Modify(func(products []models.Product) ([]models.Product, error) {
+		tx := NewProductTx(products, p.GetNextID)
+		if err := fn(tx); err != nil {
+			return nil, err
+		}
+		if err := checkCatalog(tx.Products); err != nil {
+			return nil, err
+		}
+		return tx.Products, nil
+	})
 }
 
-// GetNextID calculates the next available ID for a new product
+// GetNextID calculates the next available ID for a new product, above every
+// ID assigned so far
 func (p *productRepository) GetNextID(products []models.Product) int {
+	return p.mark.next(products)
+}
+
+func nextProductID(products []models.Product) int {
+	return maxProductID(products) + 1
+}
+
+func maxProductID(products []models.Product) int {
 	maxID := 0
 	for _, pr := range products {
 		if pr.ID > maxID {
 			maxID = pr.ID
 		}
 	}
-	return maxID + 1
+	return maxID
 }
 
 // NewProductRepository creates a new instance of ProductRepository
 func NewProductRepository(baseRepo BaseRepositoryInterface) ProductRepository {
-	return &productRepository{baseRepo: baseRepo}
+	mark := &idMark{}
+	return &productRepository{
+		records: NewRepository[models.Product](baseRepo, productCodec{mark: mark}, productID, setProductID),
+		mark:    mark,
+	}
 }
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"item-comparison-ai-api/config"
+	"item-comparison-ai-api/internal/database"
+)
+
+// DriverFactory builds the ProductRepository of a storage driver. Drivers read
+// their specific options from the AppConfig.
+type DriverFactory func(fileStore database.FileStore, conf *config.AppConfig) (ProductRepository, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]DriverFactory)
+)
+
+// RegisterDriver makes a storage driver available under name. It panics when
+// the name is taken, like database/sql.Register.
+func RegisterDriver(name string, factory DriverFactory) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if factory == nil {
+		panic("repositories: RegisterDriver factory is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("repositories: RegisterDriver called twice for driver " + name)
+	}
+	drivers[name] = factory
+}
+
+// Drivers returns the sorted names of the registered storage drivers
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OpenProductRepository builds the ProductRepository selected by
+// conf.StorageDriver
+func OpenProductRepository(fileStore database.FileStore, conf *config.AppConfig) (ProductRepository, error) {
+	driversMu.RLock()
+	factory, ok := drivers[conf.StorageDriver]
+	driversMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown storage driver %q (available: %v)", conf.StorageDriver, Drivers())
+	}
+
+	return factory(fileStore, conf)
+}
+
+func init() {
+	RegisterDriver("json", func(fileStore database.FileStore, conf *config.AppConfig) (ProductRepository, error) {
+		repo := NewProductRepository(NewBaseRepository(fileStore, conf))
+		if !conf.HotReload {
+			return repo, nil
+		}
+		reloading, err := NewReloadingRepository(repo, conf.DatabasePath, conf.HotReloadPollInterval)
+		if err != nil {
+			return nil, err
+		}
+		return reloading, nil
+	})
+	RegisterDriver("memory", func(fileStore database.FileStore, conf *config.AppConfig) (ProductRepository, error) {
+		return NewMemoryRepository(fileStore, conf)
+	})
+	RegisterDriver("journal", func(fileStore database.FileStore, conf *config.AppConfig) (ProductRepository, error) {
+		repo, err := NewJournalRepository(fileStore, conf)
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	})
+	RegisterDriver("sqlite", func(fileStore database.FileStore, conf *config.AppConfig) (ProductRepository, error) {
+		repo, err := NewSQLiteRepository(conf.SQLitePath)
+		if err != nil {
+			return nil, err
+		}
+		return repo, nil
+	})
+}
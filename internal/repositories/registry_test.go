@@ -0,0 +1,25 @@
+package repositories
+
+import (
+	"testing"
+
+	"item-comparison-ai-api/config"
+	"item-comparison-ai-api/internal/database"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrivers(t *testing.T) {
+	assert.Equal(t, []string{"journal", "json", "memory", "sqlite"}, Drivers())
+}
+
+func TestOpenProductRepository_UnknownDriver(t *testing.T) {
+	_, err := OpenProductRepository(&database.Database{}, &config.AppConfig{StorageDriver: "mongo"})
+	assert.ErrorContains(t, err, `unknown storage driver "mongo"`)
+}
+
+func TestRegisterDriver_Duplicate(t *testing.T) {
+	assert.Panics(t, func() {
+		RegisterDriver("json", func(database.FileStore, *config.AppConfig) (ProductRepository, error) { return nil, nil })
+	})
+}
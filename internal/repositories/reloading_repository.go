@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// EventCatalogReloaded is the event name logged and published when a changed
+// data file has been swapped in
+const EventCatalogReloaded = "catalog_reloaded"
+
+// ReloadEvent summarises a catalog reload
+type ReloadEvent struct {
+	Added   int       `json:"added"`
+	Changed int       `json:"changed"`
+	Removed int       `json:"removed"`
+	At      time.Time `json:"at"`
+}
+
+// ReloadingRepository serves the catalog from memory and swaps in the content
+// of the data file whenever it changes on disk. Edits that fail to load or
+// validate are logged and the last good catalog keeps being served.
+type ReloadingRepository struct {
+	inner ProductRepository
+
+	mu          sync.Mutex
+	products    []models.Product
+	subscribers []func(ReloadEvent)
+
+	cancel context.CancelFunc
+}
+
+// NewReloadingRepository loads the current catalog from inner and starts
+// watching filename for changes
+func NewReloadingRepository(inner ProductRepository, filename string, pollInterval time.Duration) (*ReloadingRepository, error) {
+	products, err := inner.LoadProducts()
+	if err != nil {
+		return nil, err
+	}
+	if products == nil {
+		products = make([]models.Product, 0)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &ReloadingRepository{
+		inner:    inner,
+		products: products,
+		cancel:   cancel,
+	}
+
+	go database.WatchFile(ctx, filename, pollInterval, func() {
+		if _, err := r.Reload(); err != nil {
+			logrus.WithError(err).WithField("file", filename).Error("catalog reload rejected, keeping last good state")
+		}
+	})
+
+	return r, nil
+}
+
+// OnReload registers fn to be called after every reload that changed the catalog
+func (r *ReloadingRepository) OnReload(fn func(ReloadEvent)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.subscribers = append(r.subscribers, fn)
+}
+
+// Reload reads the catalog from the underlying repository, runs the checks a
+// save would run and makes it current. Reloads that change nothing, such as the ones triggered by
+// our own saves, are silent.
+func (r *ReloadingRepository) Reload() (ReloadEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	products, err := r.inner.LoadProducts()
+	if err != nil {
+		return ReloadEvent{}, err
+	}
+	if err := validateCatalog(products); err != nil {
+		return ReloadEvent{}, err
+	}
+	if err := checkCatalog(products); err != nil {
+		return ReloadEvent{}, err
+	}
+
+	event := diffCatalogs(r.products, products)
+	if event.Added+event.Changed+event.Removed == 0 {
+		return event, nil
+	}
+	r.products = products
+
+	logrus.WithFields(logrus.Fields{
+		"event":   EventCatalogReloaded,
+		"added":   event.Added,
+		"changed": event.Changed,
+		"removed": event.Removed,
+	}).Info("catalog reloaded from disk")
+
+	for _, fn := range r.subscribers {
+		fn(event)
+	}
+
+	return event, nil
+}
+
+// LoadProducts returns a copy of the last good catalog
+func (r *ReloadingRepository) LoadProducts() ([]models.Product, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return cloneProducts(r.products), nil
+}
+
+// SaveProducts writes through to the underlying repository
+func (r *ReloadingRepository) SaveProducts(products []models.Product) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.inner.SaveProducts(products); err != nil {
+		return err
+	}
+	r.products = cloneProducts(products)
+	return nil
+}
+
+// Update runs fn through the underlying repository, so it works on the file
+// as it is under the file lock rather than on the cache, which may not have
+// caught up with an external edit yet. The committed catalog becomes current.
+func (r *ReloadingRepository) Update(fn func(tx *ProductTx) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var committed []models.Product
+	err := r.inner.Update(func(tx *ProductTx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		committed = tx.Products
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.products = cloneProducts(committed)
+	return nil
+}
+
+// GetNextID calculates the next available ID for a new product
+func (r *ReloadingRepository) GetNextID(products []models.Product) int {
+	return r.inner.GetNextID(products)
+}
+
+// Close stops watching the data file
+func (r *ReloadingRepository) Close() error {
+	r.cancel()
+	return nil
+}
+
+// validateCatalog rejects catalogs whose products cannot be addressed by ID
+func validateCatalog(products []models.Product) error {
+	seen := make(map[int]bool, len(products))
+	for i, p := range products {
+		if p.ID <= 0 {
+			return fmt.Errorf("product at index %d has invalid id %d", i, p.ID)
+		}
+		if seen[p.ID] {
+			return fmt.Errorf("duplicate product id %d", p.ID)
+		}
+		seen[p.ID] = true
+	}
+	return nil
+}
+
+// diffCatalogs counts the products added, changed and removed between two
+// versions of the catalog
+func diffCatalogs(before, after []models.Product) ReloadEvent {
+	event := ReloadEvent{At: time.Now().UTC()}
+
+	previous := make(map[int]models.Product, len(before))
+	for _, p := range before {
+		previous[p.ID] = p
+	}
+
+	for _, p := range after {
+		old, ok := previous[p.ID]
+		switch {
+		case !ok:
+			event.Added++
+		case !reflect.DeepEqual(old, p):
+			event.Changed++
+		}
+		delete(previous, p.ID)
+	}
+	event.Removed = len(previous)
+
+	return event
+}
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"item-comparison-ai-api/config"
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newReloadingTestRepository(t *testing.T) (*ReloadingRepository, string) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`[{"id":1,"name":"Laptop"},{"id":2,"name":"Smartphone"}]`), 0644))
+
+	inner := NewProductRepository(NewBaseRepository(&database.Database{}, &config.AppConfig{DatabasePath: path}))
+	repo, err := NewReloadingRepository(inner, path, 10*time.Millisecond)
+	assert.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+
+	return repo, path
+}
+
+func TestReloadingRepository_SwapsExternalEdits(t *testing.T) {
+	repo, path := newReloadingTestRepository(t)
+
+	events := make(chan ReloadEvent, 1)
+	repo.OnReload(func(e ReloadEvent) { events <- e })
+
+	// Give the watcher time to register before editing the file
+	time.Sleep(50 * time.Millisecond)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`[{"id":1,"name":"Gaming Laptop"},{"id":3,"name":"Headphones"}]`), 0644))
+
+	select {
+	case event := <-events:
+		assert.Equal(t, 1, event.Added)
+		assert.Equal(t, 1, event.Changed)
+		assert.Equal(t, 1, event.Removed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("catalog was not reloaded")
+	}
+
+	products, err := repo.LoadProducts()
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Product{{ID: 1, Name: "Gaming Laptop", Price: usd("0")}, {ID: 3, Name: "Headphones", Price: usd("0")}}, products)
+}
+
+func TestReloadingRepository_KeepsLastGoodState(t *testing.T) {
+	repo, path := newReloadingTestRepository(t)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`[{"id":1,"name":"Laptop"`), 0644))
+	_, err := repo.Reload()
+	assert.Error(t, err)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`[{"id":1,"name":"Laptop"},{"id":1,"name":"Clone"}]`), 0644))
+	_, err = repo.Reload()
+	assert.ErrorContains(t, err, "duplicate product id 1")
+
+	assert.NoError(t, os.WriteFile(path, []byte(`[{"id":1,"name":"Laptop","sku":"X"},{"id":2,"name":"Clone","sku":"X"}]`), 0644))
+	_, err = repo.Reload()
+	assert.EqualError(t, err, "sku X is already used by product 1")
+
+	products, err := repo.LoadProducts()
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+	assert.Equal(t, "Smartphone", products[1].Name)
+}
+
+func TestReloadingRepository_OwnSavesAreSilent(t *testing.T) {
+	repo, _ := newReloadingTestRepository(t)
+
+	assert.NoError(t, repo.SaveProducts([]models.Product{{ID: 1, Name: "Laptop"}}))
+
+	event, err := repo.Reload()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, event.Added+event.Changed+event.Removed)
+}
+
+func TestReloadingRepository_UpdateKeepsExternalEdits(t *testing.T) {
+	repo, path := newReloadingTestRepository(t)
+
+	// An edit the watcher has not picked up yet
+	assert.NoError(t, os.WriteFile(path, []byte(`[{"id":1,"name":"Gaming Laptop"},{"id":2,"name":"Smartphone"}]`), 0644))
+
+	assert.NoError(t, repo.Update(func(tx *ProductTx) error {
+		tx.Add(models.Product{Name: "Headphones"})
+		return nil
+	}))
+
+	products, err := repo.LoadProducts()
+	assert.NoError(t, err)
+	if assert.Len(t, products, 3) {
+		assert.Equal(t, "Gaming Laptop", products[0].Name)
+		assert.Equal(t, "Headphones", products[2].Name)
+	}
+}
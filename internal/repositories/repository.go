@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrRecordNotFound is returned by Repository when no record has the given ID
+var ErrRecordNotFound = errors.New("record not found")
+
+// Codec converts a whole collection to and from its stored form
+type Codec[T any] interface {
+	Decode(data []byte) ([]T, error)
+	Encode(items []T) ([]byte, error)
+}
+
+// JSONCodec stores a collection as an indented JSON array. An empty or
+// missing file decodes to an empty collection.
+type JSONCodec[T any] struct{}
+
+// Decode parses a JSON array of T
+func (JSONCodec[T]) Decode(data []byte) ([]T, error) {
+	items := make([]T, 0)
+	if len(data) == 0 {
+		return items, nil
+	}
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Encode writes items as an indented JSON array
+func (JSONCodec[T]) Encode(items []T) ([]byte, error) {
+	if items == nil {
+		items = make([]T, 0)
+	}
+	return json.MarshalIndent(items, "", "  ")
+}
+
+// Repository persists a collection of T through its own BaseRepositoryInterface,
+// usually a Client bound to one file. Records are keyed by an integer ID that
+// is read with id and assigned on Insert with setID.
+type Repository[T any] struct {
+	base  BaseRepositoryInterface
+	codec Codec[T]
+	id    func(T) int
+	setID func(*T, int)
+}
+
+// NewRepository creates a Repository storing its records in base
+func NewRepository[T any](base BaseRepositoryInterface, codec Codec[T], id func(T) int, setID func(*T, int)) *Repository[T] {
+	return &Repository[T]{
+		base:  base,
+		codec: codec,
+		id:    id,
+		setID: setID,
+	}
+}
+
+// Load returns every record
+func (r *Repository[T]) Load() ([]T, error) {
+	data, err := r.base.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	return r.codec.Decode(data)
+}
+
+// Save replaces every record with items
+func (r *Repository[T]) Save(items []T) error {
+	return r.base.WithLock(func(locked BaseRepositoryInterface) error {
+		return r.with(locked).write(items)
+	})
+}
+
+// Modify loads the records, passes them to fn and saves what it returns,
+// holding the write lock across the whole load-modify-save. Returning an
+// error from fn discards its changes.
+func (r *Repository[T]) Modify(fn func(items []T) ([]T, error)) error {
+	return r.base.WithLock(func(locked BaseRepositoryInterface) error {
+		r := r.with(locked)
+		items, err := r.Load()
+		if err != nil {
+			return err
+		}
+
+		items, err = fn(items)
+		if err != nil {
+			return err
+		}
+
+		return r.write(items)
+	})
+}
+
+// with returns a copy of r storing its records in base
+func (r *Repository[T]) with(base BaseRepositoryInterface) *Repository[T] {
+	locked := *r
+	locked.base = base
+	return &locked
+}
+
+// Get returns the record with the given ID
+func (r *Repository[T]) Get(id int) (T, error) {
+	items, err := r.Load()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	if i := r.index(items, id); i >= 0 {
+		return items[i], nil
+	}
+
+	var zero T
+	return zero, ErrRecordNotFound
+}
+
+// Insert assigns the next free ID to item and stores it
+func (r *Repository[T]) Insert(item T) (T, error) {
+	err := r.Modify(func(items []T) ([]T, error) {
+		r.setID(&item, r.NextID(items))
+		return append(items, item), nil
+	})
+	return item, err
+}
+
+// Update runs fn on the record with the given ID and stores the result. The
+// ID cannot be changed through fn.
+func (r *Repository[T]) Update(id int, fn func(item *T) error) (T, error) {
+	var updated T
+	err := r.Modify(func(items []T) ([]T, error) {
+		i := r.index(items, id)
+		if i < 0 {
+			return nil, ErrRecordNotFound
+		}
+
+		item := items[i]
+		if err := fn(&item); err != nil {
+			return nil, err
+		}
+		r.setID(&item, id)
+
+		items[i] = item
+		updated = item
+		return items, nil
+	})
+	return updated, err
+}
+
+// Delete removes the record with the given ID
+func (r *Repository[T]) Delete(id int) error {
+	return r.Modify(func(items []T) ([]T, error) {
+		i := r.index(items, id)
+		if i < 0 {
+			return nil, ErrRecordNotFound
+		}
+		return append(items[:i], items[i+1:]...), nil
+	})
+}
+
+// DeleteWhere removes every record match returns true for
+func (r *Repository[T]) DeleteWhere(match func(T) bool) error {
+	return r.Modify(func(items []T) ([]T, error) {
+		kept := items[:0]
+		for _, item := range items {
+			if !match(item) {
+				kept = append(kept, item)
+			}
+		}
+		return kept, nil
+	})
+}
+
+// NextID returns one more than the highest ID in items
+func (r *Repository[T]) NextID(items []T) int {
+	maxID := 0
+	for _, item := range items {
+		if id := r.id(item); id > maxID {
+			maxID = id
+		}
+	}
+	return maxID + 1
+}
+
+func (r *Repository[T]) index(items []T, id int) int {
+	for i, item := range items {
+		if r.id(item) == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *Repository[T]) write(items []T) error {
+	data, err := r.codec.Encode(items)
+	if err != nil {
+		return err
+	}
+
+	return r.base.Write(data)
+}
@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"item-comparison-ai-api/internal/database"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testNote struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+}
+
+func newNoteRepository(path string) *Repository[testNote] {
+	return NewRepository[testNote](
+		NewFileClient(&database.Database{}, path),
+		JSONCodec[testNote]{},
+		func(n testNote) int { return n.ID },
+		func(n *testNote, id int) { n.ID = id },
+	)
+}
+
+func TestRepository_CRUD(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.json")
+	repo := newNoteRepository(path)
+
+	notes, err := repo.Load()
+	assert.NoError(t, err)
+	assert.Empty(t, notes)
+
+	first, err := repo.Insert(testNote{ID: 42, Text: "first"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, first.ID)
+	second, err := repo.Insert(testNote{Text: "second"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, second.ID)
+
+	got, err := repo.Get(2)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", got.Text)
+
+	updated, err := repo.Update(1, func(n *testNote) error {
+		n.ID = 99
+		n.Text = "changed"
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, testNote{ID: 1, Text: "changed"}, updated)
+
+	_, err = repo.Update(1, func(n *testNote) error {
+		n.Text = "discarded"
+		return errors.New("boom")
+	})
+	assert.EqualError(t, err, "boom")
+
+	assert.NoError(t, repo.Delete(2))
+	assert.ErrorIs(t, repo.Delete(2), ErrRecordNotFound)
+	_, err = repo.Get(2)
+	assert.ErrorIs(t, err, ErrRecordNotFound)
+
+	// Reopening the file sees the persisted state
+	notes, err = newNoteRepository(path).Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []testNote{{ID: 1, Text: "changed"}}, notes)
+}
+
+func TestRepository_InstancesUseTheirOwnFile(t *testing.T) {
+	dir := t.TempDir()
+	notes := newNoteRepository(filepath.Join(dir, "notes.json"))
+	drafts := newNoteRepository(filepath.Join(dir, "drafts.json"))
+
+	_, err := notes.Insert(testNote{Text: "note"})
+	assert.NoError(t, err)
+
+	// Nested updates of different files must not wait on each other
+	err = drafts.Modify(func(items []testNote) ([]testNote, error) {
+		_, err := notes.Insert(testNote{Text: "another note"})
+		return append(items, testNote{ID: 1, Text: "draft"}), err
+	})
+	assert.NoError(t, err)
+
+	all, err := notes.Load()
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	data, err := os.ReadFile(filepath.Join(dir, "drafts.json"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"id":1,"text":"draft"}]`, string(data))
+}
@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/models"
+)
+
+// ErrReviewNotFound is returned when a product has no review with the
+// requested ID
+var ErrReviewNotFound = errors.New("review not found")
+
+// ReviewStore keeps the reviews of every product in its own file
+type ReviewStore struct {
+	records *Repository[models.Review]
+	now     func() time.Time
+}
+
+// NewReviewStore stores the reviews in the file at path
+func NewReviewStore(fileStore database.FileStore, path string) *ReviewStore {
+	return &ReviewStore{
+		records: NewRepository[models.Review](
+			NewFileClient(fileStore, path),
+			JSONCodec[models.Review]{},
+			func(r models.Review) int { return r.ID },
+			func(r *models.Review, id int) { r.ID = id },
+		),
+		now: time.Now,
+	}
+}
+
+// Create stores a new review of a product, pending moderation
+func (s *ReviewStore) Create(productID int, review models.Review) (models.Review, error) {
+	review.ProductID = productID
+	review.Status = models.ReviewPending
+	review.CreatedAt = s.now().UTC()
+	review.ModeratedAt = nil
+	return s.records.Insert(review)
+}
+
+// List returns the reviews of a product, newest first
+func (s *ReviewStore) List(productID int) ([]models.Review, error) {
+	reviews, err := s.records.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]models.Review, 0)
+	for _, r := range reviews {
+		if r.ProductID == productID {
+			result = append(result, r)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].ID > result[j].ID })
+
+	return result, nil
+}
+
+// errRatingsInSync aborts a rating sync that changed nothing, so the catalog
+// is not rewritten
+var errRatingsInSync = errors.New("ratings are in sync")
+
+// SyncRatings recomputes the rating of every product of repo from its
+// approved reviews, so products stored with hand-set ratings, like seeded
+// ones, show what their reviews say. The reviews are read inside the update,
+// and the catalog is only saved when a rating changed, so a data file in an
+// older format is left for the migrate command.
+func (s *ReviewStore) SyncRatings(repo ProductRepository) error {
+	err := repo.Update(func(tx *ProductTx) error {
+		reviews, err := s.records.Load()
+		if err != nil {
+			return err
+		}
+		byProduct := make(map[int][]models.Review)
+		for _, r := range reviews {
+			byProduct[r.ProductID] = append(byProduct[r.ProductID], r)
+		}
+		changed := false
+		for _, p := range tx.Products {
+			summary := models.SummarizeReviews(byProduct[p.ID])
+			if !reflect.DeepEqual(p.Ratings(), summary) {
+				tx.SetRatings(p.ID, summary)
+				changed = true
+			}
+		}
+		if !changed {
+			return errRatingsInSync
+		}
+		return nil
+	})
+	if errors.Is(err, errRatingsInSync) {
+		return nil
+	}
+	return err
+}
+
+// Purge removes the reviews of purged products
+func (s *ReviewStore) Purge(products []models.Product) error {
+	ids := productIDSet(products)
+	return s.records.DeleteWhere(func(r models.Review) bool { return ids[r.ProductID] })
+}
+
+// Moderate approves or rejects a review of a product
+func (s *ReviewStore) Moderate(productID, id int, status string) (models.Review, error) {
+	review, err := s.records.Update(id, func(r *models.Review) error {
+		if r.ProductID != productID {
+			return ErrReviewNotFound
+		}
+		now := s.now().UTC()
+		r.Status = status
+		r.ModeratedAt = &now
+		return nil
+	})
+	if errors.Is(err, ErrRecordNotFound) {
+		return models.Review{}, ErrReviewNotFound
+	}
+	return review, err
+}
+
+// Delete removes a review of a product
+func (s *ReviewStore) Delete(productID, id int) error {
+	return s.records.Modify(func(reviews []models.Review) ([]models.Review, error) {
+		for i, r := range reviews {
+			if r.ID == id && r.ProductID == productID {
+				return append(reviews[:i], reviews[i+1:]...), nil
+			}
+		}
+		return nil, ErrReviewNotFound
+	})
+}
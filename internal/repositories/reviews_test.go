@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReviewStore_ModerateAndDelete(t *testing.T) {
+	reviews := NewReviewStore(&database.Database{}, filepath.Join(t.TempDir(), "reviews"))
+
+	first, err := reviews.Create(1, models.Review{Rating: 5, Author: "ann", Status: models.ReviewApproved})
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReviewPending, first.Status)
+	assert.Equal(t, 1, first.ProductID)
+	second, err := reviews.Create(1, models.Review{Rating: 2, Author: "bob"})
+	assert.NoError(t, err)
+	_, err = reviews.Create(2, models.Review{Rating: 1, Author: "cy"})
+	assert.NoError(t, err)
+
+	approved, err := reviews.Moderate(1, first.ID, models.ReviewApproved)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReviewApproved, approved.Status)
+	assert.NotNil(t, approved.ModeratedAt)
+
+	// Reviews are only found under their own product
+	_, err = reviews.Moderate(2, second.ID, models.ReviewApproved)
+	assert.ErrorIs(t, err, ErrReviewNotFound)
+	assert.ErrorIs(t, reviews.Delete(2, second.ID), ErrReviewNotFound)
+
+	listed, err := reviews.List(1)
+	assert.NoError(t, err)
+	if assert.Len(t, listed, 2) {
+		assert.Equal(t, second.ID, listed[0].ID)
+	}
+
+	assert.NoError(t, reviews.Delete(1, second.ID))
+	listed, err = reviews.List(1)
+	assert.NoError(t, err)
+	assert.Len(t, listed, 1)
+}
+
+func TestSummarizeReviews(t *testing.T) {
+	summary := models.SummarizeReviews([]models.Review{
+		{Rating: 5, Status: models.ReviewApproved},
+		{Rating: 4, Status: models.ReviewApproved},
+		{Rating: 4, Status: models.ReviewApproved},
+		{Rating: 1, Status: models.ReviewPending},
+		{Rating: 1, Status: models.ReviewRejected},
+	})
+	assert.Equal(t, 4.33, summary.Rating)
+	assert.Equal(t, 3, summary.ReviewCount)
+	assert.Equal(t, map[int]int{1: 0, 2: 0, 3: 0, 4: 2, 5: 1}, summary.Distribution)
+
+	assert.Equal(t, models.RatingSummary{}, models.SummarizeReviews(nil))
+}
+
+func TestProductTx_RatingsAreReadOnly(t *testing.T) {
+	tx := NewProductTx([]models.Product{{ID: 1, Name: "Laptop", Rating: 4.5, ReviewCount: 2}}, nextProductID)
+
+	replaced, _ := tx.Replace(models.Product{ID: 1, Name: "Laptop", Rating: 1, ReviewCount: 99})
+	assert.Equal(t, 4.5, replaced.Rating)
+	assert.Equal(t, 2, replaced.ReviewCount)
+
+	inserted := tx.Insert(models.Product{Name: "Mouse", Rating: 5, ReviewCount: 10})
+	assert.Zero(t, inserted.Rating)
+	assert.Zero(t, inserted.ReviewCount)
+
+	assert.True(t, tx.SetRatings(inserted.ID, models.RatingSummary{Rating: 3, ReviewCount: 1, Distribution: map[int]int{3: 1}}))
+	mouse, _ := tx.Get(inserted.ID)
+	assert.Equal(t, 3.0, mouse.Rating)
+	assert.False(t, tx.SetRatings(99, models.RatingSummary{}))
+}
+
+func TestReviewStore_SyncRatings(t *testing.T) {
+	dir := t.TempDir()
+	products := NewProductRepository(NewFileClient(&database.Database{}, filepath.Join(dir, "data.json")))
+	assert.NoError(t, products.SaveProducts([]models.Product{
+		{ID: 1, Name: "Laptop", Rating: 4.5},
+		{ID: 2, Name: "Phone", Rating: 4.8, ReviewCount: 7},
+	}))
+	reviews := NewReviewStore(&database.Database{}, filepath.Join(dir, "reviews"))
+	review, err := reviews.Create(1, models.Review{Rating: 3, Author: "ann"})
+	assert.NoError(t, err)
+	_, err = reviews.Moderate(1, review.ID, models.ReviewApproved)
+	assert.NoError(t, err)
+
+	assert.NoError(t, reviews.SyncRatings(products))
+
+	stored, err := products.LoadProducts()
+	assert.NoError(t, err)
+	if assert.Len(t, stored, 2) {
+		assert.Equal(t, models.RatingSummary{Rating: 3, ReviewCount: 1, Distribution: map[int]int{1: 0, 2: 0, 3: 1, 4: 0, 5: 0}}, stored[0].Ratings())
+		assert.Equal(t, models.RatingSummary{}, stored[1].Ratings())
+	}
+
+	// A catalog already in sync, here a legacy bare array, is not rewritten
+	legacy := []byte(`[{"id":5,"name":"Monitor"}]`)
+	path := filepath.Join(dir, "legacy.json")
+	assert.NoError(t, os.WriteFile(path, legacy, 0644))
+	assert.NoError(t, reviews.SyncRatings(NewProductRepository(NewFileClient(&database.Database{}, path))))
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, legacy, data)
+}
@@ -0,0 +1,330 @@
+package repositories
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"item-comparison-ai-api/config"
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/migrations"
+	"item-comparison-ai-api/internal/models"
+)
+
+// Snapshot errors
+var (
+	ErrSnapshotNotFound    = errors.New("snapshot not found")
+	ErrSnapshotExists      = errors.New("snapshot already exists")
+	ErrInvalidSnapshotName = errors.New("invalid snapshot name")
+)
+
+var snapshotNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+const snapshotIndexFile = "index.json"
+
+// SnapshotInfo describes a stored snapshot
+type SnapshotInfo struct {
+	Name         string    `json:"name"`
+	CreatedAt    time.Time `json:"created_at"`
+	ProductCount int       `json:"product_count"`
+	Files        []string  `json:"files"`
+}
+
+// snapshotFile is the stored form of a snapshot. The catalog is kept as a
+// versioned data document, so restoring an old snapshot goes through the same
+// migrations as loading an old data file.
+type snapshotFile struct {
+	SnapshotInfo
+	Catalog  json.RawMessage   `json:"catalog"`
+	Contents map[string][]byte `json:"file_contents"`
+}
+
+// SnapshotManager takes and restores point-in-time copies of the catalog and
+// of every other file registered with Track. Products are read and written
+// through the ProductRepository and everything else through the FileStore, so
+// snapshots work with every storage driver.
+type SnapshotManager struct {
+	fileStore database.FileStore
+	products  ProductRepository
+	dir       string
+
+	mu      sync.Mutex
+	tracked map[string]string
+}
+
+// NewSnapshotManager stores snapshots of products in dir
+func NewSnapshotManager(fileStore database.FileStore, products ProductRepository, dir string) *SnapshotManager {
+	return &SnapshotManager{
+		fileStore: fileStore,
+		products:  products,
+		dir:       dir,
+		tracked:   make(map[string]string),
+	}
+}
+
+// Track includes the file at path in snapshots under the given name
+func (m *SnapshotManager) Track(name, path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.tracked[name] = path
+}
+
+// TrackStores includes the files of the stores kept next to the catalog in
+// snapshots: the revision and price history, reviews, brands and exchange
+// rates. Every command working with snapshots tracks them through this, so
+// their snapshots cover the same files. Two stores are left out on purpose:
+// image blobs live in a blob store, not a file, and a snapshot does not
+// carry them; idempotency records are a short-lived replay cache, and
+// restoring them would replay responses for a catalog that is gone.
+func (m *SnapshotManager) TrackStores(conf *config.AppConfig) {
+	m.Track("history", conf.HistoryPath)
+	m.Track("prices", conf.PriceHistoryPath)
+	m.Track("reviews", conf.ReviewsPath)
+	m.Track("brands", conf.BrandsPath)
+	m.Track("exchange_rates", conf.ExchangeRatesPath)
+}
+
+// Create takes a snapshot. An empty name is replaced by a timestamp.
+func (m *SnapshotManager) Create(name string) (SnapshotInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	products, err := m.products.LoadProducts()
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	contents := make(map[string][]byte)
+	for fileName, path := range m.tracked {
+		data, err := m.fileStore.Read(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return SnapshotInfo{}, fmt.Errorf("snapshot %s: %w", fileName, err)
+		}
+		contents[fileName] = data
+	}
+
+	return m.create(name, products, contents)
+}
+
+// create stores products and the contents of tracked files as a snapshot
+func (m *SnapshotManager) create(name string, products []models.Product, contents map[string][]byte) (SnapshotInfo, error) {
+	now := time.Now().UTC()
+	if name == "" {
+		name = now.Format("20060102T150405.000Z")
+	}
+	if !snapshotNamePattern.MatchString(name) {
+		return SnapshotInfo{}, ErrInvalidSnapshotName
+	}
+
+	index, err := m.readIndex()
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	for _, info := range index {
+		if info.Name == name {
+			return SnapshotInfo{}, ErrSnapshotExists
+		}
+	}
+
+	catalog, err := migrations.Encode(products)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	snapshot := snapshotFile{
+		SnapshotInfo: SnapshotInfo{Name: name, CreatedAt: now, ProductCount: len(products), Files: []string{}},
+		Catalog:      catalog,
+		Contents:     contents,
+	}
+	for fileName := range contents {
+		snapshot.Files = append(snapshot.Files, fileName)
+	}
+	sort.Strings(snapshot.Files)
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+	if err := m.fileStore.Write(m.snapshotPath(name), data, 0644); err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	index = append(index, snapshot.SnapshotInfo)
+	if err := m.writeIndex(index); err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	return snapshot.SnapshotInfo, nil
+}
+
+// List returns the stored snapshots, newest first
+func (m *SnapshotManager) List() ([]SnapshotInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	index, err := m.readIndex()
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(index, func(i, j int) bool { return index[i].CreatedAt.After(index[j].CreatedAt) })
+	return index, nil
+}
+
+// Download returns the raw stored snapshot
+func (m *SnapshotManager) Download(name string) ([]byte, error) {
+	if !snapshotNamePattern.MatchString(name) {
+		return nil, ErrInvalidSnapshotName
+	}
+
+	data, err := m.fileStore.Read(m.snapshotPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrSnapshotNotFound
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// Restore replaces the catalog and the tracked files with the content of a
+// snapshot; tracked files the snapshot does not have are emptied. The
+// snapshot is fully decoded before anything is written, and the current
+// state is first saved as a "pre-restore" snapshot so a restore can itself
+// be undone.
+//
+// Everything is written inside a catalog update, with the tracked files
+// locked like their own repositories lock them. When a write fails, the
+// tracked files get their pre-restore content back and the catalog is left
+// as it was.
+func (m *SnapshotManager) Restore(name string) (SnapshotInfo, error) {
+	data, err := m.Download(name)
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	var snapshot snapshotFile
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return SnapshotInfo{}, fmt.Errorf("snapshot %s is corrupt: %w", name, err)
+	}
+	products, err := migrations.Decode(snapshot.Catalog)
+	if err != nil {
+		return SnapshotInfo{}, fmt.Errorf("snapshot %s: %w", name, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var (
+		previous map[string][]byte
+		written  bool
+	)
+	err = m.products.Update(func(tx *ProductTx) error {
+		return m.withTrackedLocks(func(clients map[string]*Client) error {
+			previous = make(map[string][]byte)
+			for fileName, client := range clients {
+				data, err := client.Load()
+				if err != nil {
+					return fmt.Errorf("snapshot %s: %w", fileName, err)
+				}
+				if len(data) > 0 {
+					previous[fileName] = data
+				}
+			}
+			if _, err := m.create("pre-restore-"+time.Now().UTC().Format("20060102T150405.000Z"), tx.Products, previous); err != nil {
+				return err
+			}
+
+			tx.Products = products
+			if err := tx.Check(); err != nil {
+				return fmt.Errorf("snapshot %s: %w", name, err)
+			}
+			written = true
+			return writeTracked(clients, snapshot.Contents)
+		})
+	})
+	if err != nil && written {
+		rollback := m.withTrackedLocks(func(clients map[string]*Client) error {
+			return writeTracked(clients, previous)
+		})
+		if rollback != nil {
+			return SnapshotInfo{}, fmt.Errorf("%w (rolling back the tracked files failed too: %v)", err, rollback)
+		}
+	}
+	if err != nil {
+		return SnapshotInfo{}, err
+	}
+
+	return snapshot.SnapshotInfo, nil
+}
+
+// withTrackedLocks runs fn holding the write lock of every tracked file,
+// taken in name order, with a Client for each file by name
+func (m *SnapshotManager) withTrackedLocks(fn func(clients map[string]*Client) error) error {
+	names := make([]string, 0, len(m.tracked))
+	clients := make(map[string]*Client, len(m.tracked))
+	for fileName, path := range m.tracked {
+		names = append(names, fileName)
+		clients[fileName] = NewFileClient(m.fileStore, path)
+	}
+	sort.Strings(names)
+
+	var lock func(i int) error
+	lock = func(i int) error {
+		if i == len(names) {
+			return fn(clients)
+		}
+		return clients[names[i]].withLock(func(locked *Client) error {
+			clients[names[i]] = locked
+			return lock(i + 1)
+		})
+	}
+	return lock(0)
+}
+
+// writeTracked gives every tracked file its content in contents, emptying
+// the ones it has none for
+func writeTracked(clients map[string]*Client, contents map[string][]byte) error {
+	for fileName, client := range clients {
+		if err := client.Write(contents[fileName]); err != nil {
+			return fmt.Errorf("restore %s: %w", fileName, err)
+		}
+	}
+	return nil
+}
+
+func (m *SnapshotManager) snapshotPath(name string) string {
+	return filepath.Join(m.dir, name+".snapshot.json")
+}
+
+func (m *SnapshotManager) readIndex() ([]SnapshotInfo, error) {
+	data, err := m.fileStore.Read(filepath.Join(m.dir, snapshotIndexFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return make([]SnapshotInfo, 0), nil
+		}
+		return nil, err
+	}
+
+	var index []SnapshotInfo
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func (m *SnapshotManager) writeIndex(index []SnapshotInfo) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return m.fileStore.Write(filepath.Join(m.dir, snapshotIndexFile), data, 0644)
+}
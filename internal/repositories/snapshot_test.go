@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"item-comparison-ai-api/config"
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotManager_CreateListRestore(t *testing.T) {
+	dir := t.TempDir()
+	db := &database.Database{}
+	conf := &config.AppConfig{DatabasePath: filepath.Join(dir, "data.json")}
+	products := NewProductRepository(NewBaseRepository(db, conf))
+	assert.NoError(t, products.SaveProducts([]models.Product{{ID: 1, Name: "Laptop"}, {ID: 2, Name: "Smartphone"}}))
+
+	extraPath := filepath.Join(dir, "extra.json")
+	assert.NoError(t, os.WriteFile(extraPath, []byte(`{"v":1}`), 0644))
+
+	snapshots := NewSnapshotManager(db, products, filepath.Join(dir, "snapshots"))
+	snapshots.Track("extra", extraPath)
+
+	info, err := snapshots.Create("before-cleanup")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, info.ProductCount)
+	assert.Equal(t, []string{"extra"}, info.Files)
+
+	_, err = snapshots.Create("before-cleanup")
+	assert.ErrorIs(t, err, ErrSnapshotExists)
+	_, err = snapshots.Create("../escape")
+	assert.ErrorIs(t, err, ErrInvalidSnapshotName)
+
+	// Change everything the snapshot covers
+	assert.NoError(t, products.SaveProducts([]models.Product{{ID: 3, Name: "Headphones"}}))
+	assert.NoError(t, os.WriteFile(extraPath, []byte(`{"v":2}`), 0644))
+
+	_, err = snapshots.Restore("before-cleanup")
+	assert.NoError(t, err)
+
+	restored, err := products.LoadProducts()
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Product{{ID: 1, Name: "Laptop"}, {ID: 2, Name: "Smartphone"}}, restored)
+	extra, err := os.ReadFile(extraPath)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"v":1}`, string(extra))
+
+	// The state replaced by the restore was kept as a snapshot of its own
+	list, err := snapshots.List()
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Contains(t, list[0].Name, "pre-restore-")
+	assert.Equal(t, 1, list[0].ProductCount)
+
+	_, err = snapshots.Restore("missing")
+	assert.ErrorIs(t, err, ErrSnapshotNotFound)
+}
+
+// failingSaves runs update functions but never saves their result
+type failingSaves struct {
+	ProductRepository
+}
+
+func (r failingSaves) Update(fn func(tx *ProductTx) error) error {
+	products, err := r.LoadProducts()
+	if err != nil {
+		return err
+	}
+	if err := fn(NewProductTx(products, nextProductID)); err != nil {
+		return err
+	}
+	return errors.New("disk full")
+}
+
+func TestSnapshotManager_RestoreIsAllOrNothing(t *testing.T) {
+	dir := t.TempDir()
+	db := &database.Database{}
+	conf := &config.AppConfig{DatabasePath: filepath.Join(dir, "data.json")}
+	products := NewProductRepository(NewBaseRepository(db, conf))
+	assert.NoError(t, products.SaveProducts([]models.Product{{ID: 1, Name: "Laptop"}}))
+
+	extraPath := filepath.Join(dir, "extra.json")
+	newerPath := filepath.Join(dir, "newer.json")
+	assert.NoError(t, os.WriteFile(extraPath, []byte(`{"v":1}`), 0644))
+
+	snapshots := NewSnapshotManager(db, products, filepath.Join(dir, "snapshots"))
+	snapshots.Track("extra", extraPath)
+	_, err := snapshots.Create("old")
+	assert.NoError(t, err)
+
+	// A file tracked since the snapshot was taken
+	snapshots.Track("newer", newerPath)
+	assert.NoError(t, os.WriteFile(extraPath, []byte(`{"v":2}`), 0644))
+	assert.NoError(t, os.WriteFile(newerPath, []byte(`{"n":1}`), 0644))
+
+	failing := NewSnapshotManager(db, failingSaves{products}, filepath.Join(dir, "snapshots"))
+	failing.Track("extra", extraPath)
+	failing.Track("newer", newerPath)
+	_, err = failing.Restore("old")
+	assert.EqualError(t, err, "disk full")
+	extra, _ := os.ReadFile(extraPath)
+	assert.Equal(t, `{"v":2}`, string(extra), "a failed restore puts the files back")
+	newer, _ := os.ReadFile(newerPath)
+	assert.Equal(t, `{"n":1}`, string(newer))
+
+	_, err = snapshots.Restore("old")
+	assert.NoError(t, err)
+	extra, _ = os.ReadFile(extraPath)
+	assert.Equal(t, `{"v":1}`, string(extra))
+	newer, _ = os.ReadFile(newerPath)
+	assert.Empty(t, newer, "files the snapshot does not have are emptied")
+}
@@ -0,0 +1,498 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/money"
+
+	_ "modernc.org/sqlite" // pure-Go driver, registers "sqlite"
+)
+
+// sqliteMigrations upgrade the schema in order; PRAGMA user_version records
+// how many of them were applied
+var sqliteMigrations = []string{
+	`CREATE TABLE products (
+		id          INTEGER PRIMARY KEY,
+		name        TEXT NOT NULL DEFAULT '',
+		image_url   TEXT NOT NULL DEFAULT '',
+		description TEXT NOT NULL DEFAULT '',
+		price       REAL NOT NULL DEFAULT 0,
+		rating      REAL NOT NULL DEFAULT 0,
+		category    TEXT NOT NULL DEFAULT ''
+	);
+	CREATE INDEX idx_products_category ON products(category);
+	CREATE INDEX idx_products_price ON products(price);
+	CREATE INDEX idx_products_rating ON products(rating);
+	CREATE TABLE product_specifications (
+		product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+		key        TEXT NOT NULL,
+		value      TEXT NOT NULL,
+		PRIMARY KEY (product_id, key)
+	);
+	CREATE INDEX idx_product_specifications_key_value ON product_specifications(key, value);`,
+	`ALTER TABLE products ADD COLUMN deleted_at TEXT;
+	CREATE INDEX idx_products_deleted_at ON products(deleted_at);`,
+	// Uniqueness is checked before saving, since GTINs are compared
+	// normalized and a save may swap identifiers between rows
+	`ALTER TABLE products ADD COLUMN sku TEXT NOT NULL DEFAULT '';
+	ALTER TABLE products ADD COLUMN gtin TEXT NOT NULL DEFAULT '';
+	CREATE INDEX idx_products_sku ON products(sku);
+	CREATE INDEX idx_products_gtin ON products(gtin);`,
+	`ALTER TABLE products ADD COLUMN parent_id INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE products ADD COLUMN variant_axes TEXT NOT NULL DEFAULT '';
+	CREATE INDEX idx_products_parent_id ON products(parent_id);`,
+	// price keeps an approximate copy of the amount for sorting
+	`ALTER TABLE products ADD COLUMN price_amount TEXT NOT NULL DEFAULT '0';
+	ALTER TABLE products ADD COLUMN price_currency TEXT NOT NULL DEFAULT 'USD';
+	UPDATE products SET price_amount = CAST(price AS TEXT);`,
+	`ALTER TABLE products ADD COLUMN review_count INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE products ADD COLUMN rating_distribution TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE products ADD COLUMN images TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE products ADD COLUMN translations TEXT NOT NULL DEFAULT '';`,
+	`ALTER TABLE products ADD COLUMN brand_id INTEGER NOT NULL DEFAULT 0;
+	CREATE INDEX idx_products_brand_id ON products(brand_id);`,
+	// last_id is the highest ID ever assigned, so IDs of purged products are
+	// not reused
+	`CREATE TABLE product_ids (last_id INTEGER NOT NULL);
+	INSERT INTO product_ids SELECT COALESCE(MAX(id), 0) FROM products;`,
+}
+
+// sqliteSortColumns maps sortable product fields to their columns
+var sqliteSortColumns = map[string]string{
+	"id":       "p.id",
+	"name":     "p.name",
+	"price":    "p.price",
+	"rating":   "p.rating",
+	"category": "p.category",
+}
+
+const sqliteProductColumns = "p.id, p.sku, p.gtin, p.name, p.image_url, p.description, p.price_amount, p.price_currency, p.rating, p.review_count, p.rating_distribution, p.images, p.translations, p.category, p.brand_id, p.parent_id, p.variant_axes, p.deleted_at"
+
+// SQLiteRepository stores products in an embedded SQLite database
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// sqlExecutor is satisfied by both *sql.DB and *sql.Tx
+type sqlExecutor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// NewSQLiteRepository opens (or creates) the database at path and brings its
+// schema up to date
+func NewSQLiteRepository(path string) (*SQLiteRepository, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite serialises writers anyway; a single connection avoids SQLITE_BUSY
+	// between our own goroutines.
+	db.SetMaxOpenConns(1)
+
+	repo := &SQLiteRepository{db: db}
+	if err := repo.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *SQLiteRepository) migrate() error {
+	var version int
+	if err := r.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := r.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("sqlite: migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close releases the database handle
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+// LoadProducts returns the whole catalog ordered by ID
+func (r *SQLiteRepository) LoadProducts() ([]models.Product, error) {
+	return r.QueryProducts(ProductFilter{Sort: "id", Limit: -1, Deleted: IncludeDeleted})
+}
+
+// QueryProducts evaluates filtering, sorting and pagination in SQL
+func (r *SQLiteRepository) QueryProducts(filter ProductFilter) ([]models.Product, error) {
+	return queryProducts(r.db, filter)
+}
+
+func queryProducts(db sqlExecutor, filter ProductFilter) ([]models.Product, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+
+	switch filter.Deleted {
+	case ExcludeDeleted:
+		where = append(where, "p.deleted_at IS NULL")
+	case OnlyDeleted:
+		where = append(where, "p.deleted_at IS NOT NULL")
+	}
+	if filter.Category != "" {
+		where = append(where, "p.category = ?")
+		args = append(args, filter.Category)
+	}
+	if filter.SKU != "" {
+		where = append(where, "p.sku = ?")
+		args = append(args, filter.SKU)
+	}
+	if filter.BrandID != 0 {
+		where = append(where, "p.brand_id = ?")
+		args = append(args, filter.BrandID)
+	}
+	if filter.ParentID != 0 {
+		where = append(where, "p.parent_id = ?")
+		args = append(args, filter.ParentID)
+	}
+	if filter.ExcludeVariants {
+		where = append(where, "p.parent_id = 0")
+	}
+	for k, v := range filter.Specs {
+		where = append(where, "EXISTS (SELECT 1 FROM product_specifications s WHERE s.product_id = p.id AND s.key = ? AND s.value = ?)")
+		args = append(args, k, v)
+	}
+
+	query := "SELECT " + sqliteProductColumns + " FROM products p"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	order := "p.id"
+	if filter.Sort != "" {
+		field, desc, err := ParseSort(filter.Sort)
+		if err != nil {
+			return nil, err
+		}
+		order = sqliteSortColumns[field]
+		if desc {
+			order += " DESC"
+		}
+		order += ", p.id"
+	}
+	query += " ORDER BY " + order + " LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make([]models.Product, 0)
+	index := make(map[int]int)
+	for rows.Next() {
+		var (
+			p            models.Product
+			priceAmount  string
+			distribution string
+			images       string
+			translations string
+			variantAxes  string
+			deletedAt    sql.NullString
+		)
+		if err := rows.Scan(&p.ID, &p.SKU, &p.GTIN, &p.Name, &p.ImageURL, &p.Description, &priceAmount, &p.Price.Currency, &p.Rating, &p.ReviewCount, &distribution, &images, &translations, &p.Category, &p.BrandID, &p.ParentID, &variantAxes, &deletedAt); err != nil {
+			return nil, err
+		}
+		amount, err := money.ParseDecimal(priceAmount)
+		if err != nil {
+			return nil, fmt.Errorf("sqlite: product %d: price: %w", p.ID, err)
+		}
+		p.Price.Amount = amount
+		if distribution != "" {
+			if err := json.Unmarshal([]byte(distribution), &p.RatingDistribution); err != nil {
+				return nil, fmt.Errorf("sqlite: product %d: rating_distribution: %w", p.ID, err)
+			}
+		}
+		if images != "" {
+			if err := json.Unmarshal([]byte(images), &p.Images); err != nil {
+				return nil, fmt.Errorf("sqlite: product %d: images: %w", p.ID, err)
+			}
+		}
+		if translations != "" {
+			if err := json.Unmarshal([]byte(translations), &p.Translations); err != nil {
+				return nil, fmt.Errorf("sqlite: product %d: translations: %w", p.ID, err)
+			}
+		}
+		if variantAxes != "" {
+			if err := json.Unmarshal([]byte(variantAxes), &p.VariantAxes); err != nil {
+				return nil, fmt.Errorf("sqlite: product %d: variant_axes: %w", p.ID, err)
+			}
+		}
+		if deletedAt.Valid {
+			t, err := time.Parse(time.RFC3339Nano, deletedAt.String)
+			if err != nil {
+				return nil, fmt.Errorf("sqlite: product %d: deleted_at: %w", p.ID, err)
+			}
+			p.DeletedAt = &t
+		}
+		index[p.ID] = len(products)
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := loadSpecifications(db, products, index); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
+
+// loadSpecifications fills the specification maps of an already loaded page
+func loadSpecifications(db sqlExecutor, products []models.Product, index map[int]int) error {
+	if len(products) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(products))
+	args := make([]interface{}, len(products))
+	for i, p := range products {
+		placeholders[i] = "?"
+		args[i] = p.ID
+	}
+
+	rows, err := db.Query("SELECT product_id, key, value FROM product_specifications WHERE product_id IN ("+strings.Join(placeholders, ",")+")", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id         int
+			key, value string
+		)
+		if err := rows.Scan(&id, &key, &value); err != nil {
+			return err
+		}
+		p := &products[index[id]]
+		if p.Specifications == nil {
+			p.Specifications = make(map[string]string)
+		}
+		p.Specifications[key] = value
+	}
+
+	return rows.Err()
+}
+
+// SaveProducts makes the table content equal to the given catalog, writing
+// only the rows that changed
+func (r *SQLiteRepository) SaveProducts(products []models.Product) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := queryProducts(tx, ProductFilter{Limit: -1, Deleted: IncludeDeleted})
+	if err != nil {
+		return err
+	}
+	if err := saveProducts(tx, current, products); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lastProductID reads the highest ID ever assigned
+func lastProductID(tx *sql.Tx) (int, error) {
+	var last int
+	err := tx.QueryRow("SELECT last_id FROM product_ids").Scan(&last)
+	return last, err
+}
+
+// Update runs fn inside an immediate SQLite transaction, which takes the
+// database write lock up front and so also excludes other processes
+func (r *SQLiteRepository) Update(fn func(tx *ProductTx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := queryProducts(tx, ProductFilter{Limit: -1, Deleted: IncludeDeleted})
+	if err != nil {
+		return err
+	}
+
+	lastID, err := lastProductID(tx)
+	if err != nil {
+		return err
+	}
+	nextID := func(products []models.Product) int {
+		return max(lastID, maxProductID(products)) + 1
+	}
+
+	productTx := NewProductTx(cloneProducts(current), nextID)
+	if err := fn(productTx); err != nil {
+		return err
+	}
+	if err := saveProducts(tx, current, productTx.Products); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// saveProducts writes the difference between current and products
+func saveProducts(tx *sql.Tx, current, products []models.Product) error {
+	if err := checkCatalog(products); err != nil {
+		return err
+	}
+
+	existing := make(map[int]models.Product, len(current))
+	for _, p := range current {
+		existing[p.ID] = p
+	}
+
+	for _, p := range products {
+		old, ok := existing[p.ID]
+		delete(existing, p.ID)
+		if ok && reflect.DeepEqual(normalizeSpecs(old), normalizeSpecs(p)) {
+			continue
+		}
+		if err := upsertSQLiteProduct(tx, p); err != nil {
+			return err
+		}
+	}
+
+	for id := range existing {
+		if _, err := tx.Exec("DELETE FROM products WHERE id = ?", id); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec("UPDATE product_ids SET last_id = MAX(last_id, ?)", maxProductID(products))
+	return err
+}
+
+func upsertSQLiteProduct(tx *sql.Tx, p models.Product) error {
+	var deletedAt sql.NullString
+	if p.DeletedAt != nil {
+		deletedAt = sql.NullString{String: p.DeletedAt.UTC().Format(time.RFC3339Nano), Valid: true}
+	}
+
+	// Axes keep their order, so they are stored as a JSON array
+	var variantAxes string
+	if len(p.VariantAxes) > 0 {
+		data, err := json.Marshal(p.VariantAxes)
+		if err != nil {
+			return err
+		}
+		variantAxes = string(data)
+	}
+	var distribution string
+	if len(p.RatingDistribution) > 0 {
+		data, err := json.Marshal(p.RatingDistribution)
+		if err != nil {
+			return err
+		}
+		distribution = string(data)
+	}
+	var images string
+	if len(p.Images) > 0 {
+		data, err := json.Marshal(p.Images)
+		if err != nil {
+			return err
+		}
+		images = string(data)
+	}
+	var translations string
+	if len(p.Translations) > 0 {
+		data, err := json.Marshal(p.Translations)
+		if err != nil {
+			return err
+		}
+		translations = string(data)
+	}
+
+	_, err := tx.Exec(`INSERT INTO products (id, sku, gtin, name, image_url, description, price, price_amount, price_currency, rating, review_count, rating_distribution, images, translations, category, brand_id, parent_id, variant_axes, deleted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			sku = excluded.sku,
+			gtin = excluded.gtin,
+			name = excluded.name,
+			image_url = excluded.image_url,
+			description = excluded.description,
+			price = excluded.price,
+			price_amount = excluded.price_amount,
+			price_currency = excluded.price_currency,
+			rating = excluded.rating,
+			review_count = excluded.review_count,
+			rating_distribution = excluded.rating_distribution,
+			images = excluded.images,
+			translations = excluded.translations,
+			category = excluded.category,
+			brand_id = excluded.brand_id,
+			parent_id = excluded.parent_id,
+			variant_axes = excluded.variant_axes,
+			deleted_at = excluded.deleted_at`,
+		p.ID, p.SKU, p.GTIN, p.Name, p.ImageURL, p.Description, p.Price.Amount.Float64(), p.Price.Amount.String(), p.Price.Currency, p.Rating, p.ReviewCount, distribution, images, translations, p.Category, p.BrandID, p.ParentID, variantAxes, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DELETE FROM product_specifications WHERE product_id = ?", p.ID); err != nil {
+		return err
+	}
+	for k, v := range p.Specifications {
+		if _, err := tx.Exec("INSERT INTO product_specifications (product_id, key, value) VALUES (?, ?, ?)", p.ID, k, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// normalizeSpecs treats a nil and an empty specification map as equal, since
+// SQLite stores neither
+func normalizeSpecs(p models.Product) models.Product {
+	if len(p.Specifications) == 0 {
+		p.Specifications = nil
+	}
+	return p
+}
+
+// GetNextID calculates the next available ID for a new product, above every
+// ID assigned so far
+func (r *SQLiteRepository) GetNextID(products []models.Product) int {
+	var last int
+	if err := r.db.QueryRow("SELECT last_id FROM product_ids").Scan(&last); err != nil {
+		return nextProductID(products)
+	}
+	return max(last, maxProductID(products)) + 1
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"item-comparison-ai-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSQLiteTestRepository(t *testing.T) *SQLiteRepository {
+	repo, err := NewSQLiteRepository(filepath.Join(t.TempDir(), "catalog.db"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { repo.Close() })
+	return repo
+}
+
+var sqliteTestProducts = []models.Product{
+	{ID: 1, Name: "Laptop", Price: usd("1200"), Rating: 4.5, ReviewCount: 2, RatingDistribution: map[int]int{1: 0, 2: 0, 3: 0, 4: 1, 5: 1}, Category: "Electronics", Specifications: map[string]string{"RAM": "16GB", "Storage": "512GB SSD"}},
+	{ID: 2, Name: "Smartphone", ImageURL: "/images/products/2/a1.png", Price: usd("800"), Rating: 4.8, Category: "Electronics", Specifications: map[string]string{"RAM": "8GB"}, Images: []models.Image{
+		{ID: "a1", URL: "/images/products/2/a1.png", ContentType: "image/png", Width: 800, Height: 400, Size: 8037, UploadedAt: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC),
+			Thumbnails: []models.Thumbnail{{Name: "small", URL: "/images/products/2/a1-small.png", Width: 160, Height: 80}}},
+	}},
+	{ID: 3, Name: "Headphones", Price: usd("150"), Rating: 4.2, Category: "Accessories", Translations: map[string]models.Translation{"de": {Name: "Kopfhörer"}}},
+}
+
+func TestSQLiteRepository_SaveAndLoad(t *testing.T) {
+	repo := newSQLiteTestRepository(t)
+	assert.NoError(t, repo.SaveProducts(sqliteTestProducts))
+
+	loaded, err := repo.LoadProducts()
+	assert.NoError(t, err)
+	assert.Equal(t, sqliteTestProducts, loaded)
+
+	// Removing and editing products is reflected on the next load
+	updated := []models.Product{sqliteTestProducts[0], sqliteTestProducts[2]}
+	updated[0].Specifications = map[string]string{"RAM": "32GB"}
+	assert.NoError(t, repo.SaveProducts(updated))
+
+	loaded, err = repo.LoadProducts()
+	assert.NoError(t, err)
+	assert.Len(t, loaded, 2)
+	assert.Equal(t, map[string]string{"RAM": "32GB"}, loaded[0].Specifications)
+	assert.Equal(t, 3, loaded[1].ID)
+}
+
+func TestSQLiteRepository_QueryProducts(t *testing.T) {
+	repo := newSQLiteTestRepository(t)
+	assert.NoError(t, repo.SaveProducts(sqliteTestProducts))
+
+	products, err := repo.QueryProducts(ProductFilter{Category: "Electronics", Sort: "-price", Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+	assert.Equal(t, 1, products[0].ID)
+
+	products, err = repo.QueryProducts(ProductFilter{Specs: map[string]string{"RAM": "8GB"}, Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, "Smartphone", products[0].Name)
+
+	products, err = repo.QueryProducts(ProductFilter{Sort: "price", Limit: 1, Offset: 1})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, 2, products[0].ID)
+
+	// The in-memory fallback agrees with the SQL implementation
+	inMemory, err := FilterProducts(sqliteTestProducts, ProductFilter{Sort: "price", Limit: 1, Offset: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, products, inMemory)
+
+	_, err = repo.QueryProducts(ProductFilter{Sort: "unknown"})
+	assert.ErrorIs(t, err, ErrInvalidSortField)
+}
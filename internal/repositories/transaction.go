@@ -0,0 +1,218 @@
+package repositories
+
+import (
+	"item-comparison-ai-api/internal/i18n"
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/money"
+)
+
+// ProductTx is the catalog as seen inside ProductRepository.Update. Changes
+// made to Products are saved when the update function returns nil and
+// discarded otherwise.
+type ProductTx struct {
+	Products []models.Product
+
+	nextID  func([]models.Product) int
+	action  string
+	changes []Change
+}
+
+// NewProductTx starts a transaction over products; nextID assigns the IDs of
+// inserted products
+func NewProductTx(products []models.Product, nextID func([]models.Product) int) *ProductTx {
+	return &ProductTx{Products: products, nextID: nextID}
+}
+
+// SetAction names the revision action of the changes made from now on
+// through Replace and SetImages; it defaults to RevisionUpdate. Products
+// added are always recorded as created.
+func (tx *ProductTx) SetAction(action string) {
+	tx.action = action
+}
+
+// Changes returns the changes made through Insert, Add, Replace and
+// SetImages, in order, including the variants that followed a replaced
+// parent. Changes made to Products directly are not included.
+func (tx *ProductTx) Changes() []Change {
+	return tx.changes
+}
+
+// Check reports whether the catalog can be saved, as the repository checks
+// it again on save
+func (tx *ProductTx) Check() error {
+	return checkCatalog(tx.Products)
+}
+
+// track logs the change of a product to after; before is nil for a creation
+func (tx *ProductTx) track(action string, before *models.Product, after models.Product) {
+	if action == "" {
+		action = RevisionUpdate
+	}
+	tx.changes = append(tx.changes, Change{Action: action, Before: before, After: &after})
+}
+
+// Index returns the position of the product with the given ID, or -1
+func (tx *ProductTx) Index(id int) int {
+	for i, p := range tx.Products {
+		if p.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get returns the product with the given ID
+func (tx *ProductTx) Get(id int) (models.Product, bool) {
+	if i := tx.Index(id); i >= 0 {
+		return tx.Products[i], true
+	}
+	return models.Product{}, false
+}
+
+// IndexBySKU returns the position of the product with the given SKU, or -1
+func (tx *ProductTx) IndexBySKU(sku string) int {
+	for i, p := range tx.Products {
+		if sku != "" && p.SKU == sku {
+			return i
+		}
+	}
+	return -1
+}
+
+// CheckConstraints reports whether saving p would reuse the SKU or GTIN of
+// another product or break the variant structure. The repository enforces
+// this on save anyway; checking first lets callers tell which of several
+// changes is at fault.
+func (tx *ProductTx) CheckConstraints(p models.Product) error {
+	p = tx.resolve(p)
+
+	idx := newIdentifierIndex()
+	for _, other := range tx.Products {
+		if other.ID != p.ID {
+			idx.add(other)
+		}
+	}
+	if err := idx.check(p); err != nil {
+		return err
+	}
+
+	return newVariantIndex(tx.Products, p.ID).check(p)
+}
+
+// Insert assigns the next ID to p and appends it. IDs are assigned inside the
+// transaction, so concurrent inserts never share one. A variant inherits the
+// fields it leaves empty from its parent.
+func (tx *ProductTx) Insert(p models.Product) models.Product {
+	p.ID = tx.nextID(tx.Products)
+	return tx.Add(p)
+}
+
+// Add appends p under its own ID, which must not be taken. A new product has
+// no reviews or uploaded images yet, so its rating and gallery start empty.
+func (tx *ProductTx) Add(p models.Product) models.Product {
+	p = tx.resolve(p).WithRatings(models.RatingSummary{})
+	p.Images = nil
+	tx.Products = append(tx.Products, p)
+	tx.track(RevisionCreate, nil, p)
+	return p
+}
+
+// Replace overwrites the product with p.ID, returning it as stored, and
+// reports whether it existed. The rating computed from the reviews and the
+// image gallery are kept.
+// A variant inherits the fields it leaves empty from its parent, and the
+// variants of a parent follow the changes of the fields they share with it.
+func (tx *ProductTx) Replace(p models.Product) (models.Product, bool) {
+	i := tx.Index(p.ID)
+	if i < 0 {
+		return p, false
+	}
+
+	before := tx.Products[i]
+	p = tx.resolve(p).WithRatings(before.Ratings())
+	p.Images = before.Images
+	tx.Products[i] = p
+	tx.track(tx.action, &before, p)
+	if !p.IsVariant() {
+		for j, v := range tx.Products {
+			if v.ParentID == p.ID {
+				previous := v
+				tx.Products[j] = v.FollowParent(before, p)
+				tx.track(tx.action, &previous, tx.Products[j])
+			}
+		}
+	}
+	return p, true
+}
+
+// SetRatings stores the rating computed from the reviews of the product with
+// the given ID and reports whether it exists
+func (tx *ProductTx) SetRatings(id int, summary models.RatingSummary) bool {
+	i := tx.Index(id)
+	if i < 0 {
+		return false
+	}
+	tx.Products[i] = tx.Products[i].WithRatings(summary)
+	return true
+}
+
+// SetImages stores the image gallery of the product with the given ID,
+// returning the product as stored, and reports whether it exists
+func (tx *ProductTx) SetImages(id int, images []models.Image) (models.Product, bool) {
+	i := tx.Index(id)
+	if i < 0 {
+		return models.Product{}, false
+	}
+	before := tx.Products[i]
+	tx.Products[i] = before.WithImages(images)
+	tx.track(tx.action, &before, tx.Products[i])
+	return tx.Products[i], true
+}
+
+// resolve fills the fields a variant leaves empty from its parent. Prices
+// without a currency are in money.DefaultCurrency, translations are keyed by
+// normalized locale, and conversions and labels shown in responses are not
+// kept.
+func (tx *ProductTx) resolve(p models.Product) models.Product {
+	p.Translations = normalizeTranslations(p.Translations)
+	p.SpecLabels = nil
+	if p.IsVariant() {
+		if parent, found := tx.Get(p.ParentID); found {
+			p = p.InheritFrom(parent)
+		}
+	}
+	if p.Price.Currency == "" {
+		p.Price.Currency = money.DefaultCurrency
+	}
+	p.Price.Conversion = nil
+	return p
+}
+
+// Delete removes the product with the given ID and reports whether it existed
+func (tx *ProductTx) Delete(id int) bool {
+	i := tx.Index(id)
+	if i < 0 {
+		return false
+	}
+	tx.Products = append(tx.Products[:i], tx.Products[i+1:]...)
+	return true
+}
+
+// normalizeTranslations keys translations by normalized locale and drops
+// empty ones
+func normalizeTranslations(translations map[string]models.Translation) map[string]models.Translation {
+	normalized := make(map[string]models.Translation, len(translations))
+	for locale, t := range translations {
+		if t.IsEmpty() {
+			continue
+		}
+		if tag, ok := i18n.Normalize(locale); ok {
+			locale = tag
+		}
+		normalized[locale] = t
+	}
+	if len(normalized) == 0 {
+		return nil
+	}
+	return normalized
+}
@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"item-comparison-ai-api/config"
+	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/money"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestUpdate_ConcurrentWritersLoseNothing hammers every storage driver with
+// concurrent inserts and read-modify-write increments. Without a lock held
+// across load-modify-save, inserts would share IDs and increments would be
+// lost.
+func TestUpdate_ConcurrentWritersLoseNothing(t *testing.T) {
+	const writers = 50
+
+	for _, driver := range Drivers() {
+		t.Run(driver, func(t *testing.T) {
+			dir := t.TempDir()
+			conf := &config.AppConfig{
+				StorageDriver: driver,
+				DatabasePath:  filepath.Join(dir, "data.json"),
+				JournalPath:   filepath.Join(dir, "data.json.journal"),
+				SQLitePath:    filepath.Join(dir, "catalog.db"),
+			}
+			repo, err := OpenProductRepository(&database.Database{}, conf)
+			assert.NoError(t, err)
+			assert.NoError(t, repo.SaveProducts([]models.Product{{ID: 1, Name: "Counter"}}))
+
+			var wg sync.WaitGroup
+			for i := 0; i < writers; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					assert.NoError(t, repo.Update(func(tx *ProductTx) error {
+						tx.Insert(models.Product{Name: "Inserted"})
+						return nil
+					}))
+				}()
+				go func() {
+					defer wg.Done()
+					assert.NoError(t, repo.Update(func(tx *ProductTx) error {
+						counter, _ := tx.Get(1)
+						counter.Price.Amount = counter.Price.Amount.Add(money.NewDecimal(1, 0))
+						tx.Replace(counter)
+						return nil
+					}))
+				}()
+			}
+			wg.Wait()
+
+			products, err := repo.LoadProducts()
+			assert.NoError(t, err)
+			assert.Len(t, products, writers+1)
+
+			ids := make(map[int]bool)
+			for _, p := range products {
+				assert.False(t, ids[p.ID], "duplicate id %d", p.ID)
+				ids[p.ID] = true
+				if p.ID == 1 {
+					assert.Equal(t, money.NewDecimal(writers, 0), p.Price.Amount)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdate_ErrorDiscardsChanges(t *testing.T) {
+	repo, err := NewMemoryRepository(&database.Database{}, &config.AppConfig{})
+	assert.NoError(t, err)
+
+	err = repo.Update(func(tx *ProductTx) error {
+		tx.Insert(models.Product{Name: "Laptop"})
+		return assert.AnError
+	})
+	assert.ErrorIs(t, err, assert.AnError)
+
+	products, err := repo.LoadProducts()
+	assert.NoError(t, err)
+	assert.Empty(t, products)
+}
+
+func TestCheckConstraints_Identifiers(t *testing.T) {
+	products := []models.Product{
+		{ID: 1, SKU: "A-1", GTIN: "036000291452"},
+		{ID: 2, SKU: "B-1"},
+	}
+	assert.NoError(t, checkIdentifiers(products))
+
+	tx := NewProductTx(products, nextProductID)
+	assert.NoError(t, tx.CheckConstraints(models.Product{ID: 1, SKU: "A-1", GTIN: "036000291452"}))
+
+	err := tx.CheckConstraints(models.Product{SKU: "B-1"})
+	assert.ErrorIs(t, err, ErrDuplicateIdentifier)
+	assert.EqualError(t, err, "sku B-1 is already used by product 2")
+
+	// UPC-A and its EAN-13 form are the same item
+	err = tx.CheckConstraints(models.Product{ID: 3, GTIN: "0036000291452"})
+	assert.EqualError(t, err, "gtin 0036000291452 is already used by product 1")
+
+	assert.ErrorIs(t, checkIdentifiers(append(products, models.Product{ID: 3, SKU: "A-1"})), ErrDuplicateIdentifier)
+}
+
+func TestProductTx_ImagesAreReadOnly(t *testing.T) {
+	gallery := []models.Image{{ID: "a", URL: "/images/products/1/a.png"}, {ID: "b", URL: "/images/products/1/b.png"}}
+	tx := NewProductTx([]models.Product{{ID: 1, Name: "Laptop", ImageURL: "/images/laptop.png"}}, nextProductID)
+
+	// A primary image set by hand is kept
+	stored, found := tx.SetImages(1, gallery)
+	assert.True(t, found)
+	assert.Equal(t, "/images/laptop.png", stored.ImageURL)
+
+	replaced, _ := tx.Replace(models.Product{ID: 1, Name: "Laptop", ImageURL: gallery[1].URL})
+	assert.Equal(t, gallery, replaced.Images)
+
+	// Removing the primary image promotes the first remaining one
+	stored, _ = tx.SetImages(1, gallery[:1])
+	assert.Equal(t, gallery[0].URL, stored.ImageURL)
+	stored, _ = tx.SetImages(1, nil)
+	assert.Empty(t, stored.ImageURL)
+
+	inserted := tx.Insert(models.Product{Name: "Mouse", Images: gallery})
+	assert.Empty(t, inserted.Images)
+	_, found = tx.SetImages(99, nil)
+	assert.False(t, found)
+}
+
+func TestProductTx_Changes(t *testing.T) {
+	tx := NewProductTx([]models.Product{{ID: 1, Name: "Laptop"}}, nextProductID)
+
+	tx.Insert(models.Product{Name: "Mouse"})
+	tx.SetAction(RevisionPatch)
+	tx.Replace(models.Product{ID: 1, Name: "Gaming Laptop"})
+	tx.SetRatings(1, models.RatingSummary{Rating: 5, ReviewCount: 1})
+	tx.Replace(models.Product{ID: 99})
+
+	changes := tx.Changes()
+	if assert.Len(t, changes, 2) {
+		assert.Equal(t, RevisionCreate, changes[0].Action)
+		assert.Nil(t, changes[0].Before)
+		assert.Equal(t, 2, changes[0].After.ID)
+
+		assert.Equal(t, RevisionPatch, changes[1].Action)
+		assert.Equal(t, "Laptop", changes[1].Before.Name)
+		assert.Equal(t, "Gaming Laptop", changes[1].After.Name)
+	}
+}
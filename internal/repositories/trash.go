@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"item-comparison-ai-api/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// EventTrashPurged is the event name logged when trashed products are purged
+// automatically
+const EventTrashPurged = "trash_purged"
+
+// ProductRecords is a store keeping records of products outside the catalog,
+// such as their history or reviews, which go when the products are purged
+type ProductRecords interface {
+	Purge(products []models.Product) error
+}
+
+// PurgeProducts permanently removes the products match returns true for,
+// along with their records, and returns them. The records are only dropped
+// once the catalog is saved, so a failed save loses nothing. A store failing
+// to drop them afterwards is logged: the products are gone by then, and
+// their IDs are never handed out again, so leftover records stay unreachable.
+func PurgeProducts(repo ProductRepository, match func(models.Product) bool, records ...ProductRecords) ([]models.Product, error) {
+	var purged []models.Product
+	err := repo.Update(func(tx *ProductTx) error {
+		purged = nil
+		kept := make([]models.Product, 0, len(tx.Products))
+		for _, p := range tx.Products {
+			if match(p) {
+				purged = append(purged, p)
+				continue
+			}
+			kept = append(kept, p)
+		}
+		tx.Products = kept
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(purged) == 0 {
+		return nil, nil
+	}
+
+	for _, r := range records {
+		if err := r.Purge(purged); err != nil {
+			logrus.WithError(err).WithField("products", len(purged)).Error("failed to purge the records of purged products")
+		}
+	}
+	return purged, nil
+}
+
+// PurgeTrash permanently removes the trashed products deleted before cutoff,
+// along with their records. A zero cutoff purges the whole trash. It returns
+// the number of products removed.
+func PurgeTrash(repo ProductRepository, cutoff time.Time, records ...ProductRecords) (int, error) {
+	purged, err := PurgeProducts(repo, func(p models.Product) bool {
+		return p.IsDeleted() && (cutoff.IsZero() || p.DeletedAt.Before(cutoff))
+	}, records...)
+	return len(purged), err
+}
+
+// RunTrashPurger purges products that have been in the trash longer than
+// retention every interval, until ctx is done. A zero retention or interval
+// disables it.
+func RunTrashPurger(ctx context.Context, repo ProductRepository, retention, interval time.Duration, records ...ProductRecords) {
+	if retention <= 0 || interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := PurgeTrash(repo, time.Now().Add(-retention), records...)
+			if err != nil {
+				logrus.WithError(err).Error("trash purge failed")
+				continue
+			}
+			if purged > 0 {
+				logrus.WithFields(logrus.Fields{
+					"event":  EventTrashPurged,
+					"purged": purged,
+				}).Info("purged expired products from the trash")
+			}
+		}
+	}
+}
+
+// productIDSet returns the IDs of products as a set
+func productIDSet(products []models.Product) map[int]bool {
+	ids := make(map[int]bool, len(products))
+	for _, p := range products {
+		ids[p.ID] = true
+	}
+	return ids
+}
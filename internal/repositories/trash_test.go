@@ -0,0 +1,187 @@
+package repositories
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"item-comparison-ai-api/internal/database"
+
+	"item-comparison-ai-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeTrash_RespectsCutoff(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	recent := now.Add(-time.Hour)
+
+	repo := &memoryRepository{products: []models.Product{
+		{ID: 1, Name: "Live"},
+		{ID: 2, Name: "Old", DeletedAt: &old},
+		{ID: 3, Name: "Recent", DeletedAt: &recent},
+	}}
+
+	purged, err := PurgeTrash(repo, now.Add(-24*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	products, _ := repo.LoadProducts()
+	assert.Equal(t, []int{1, 3}, productIDs(products))
+
+	purged, err = PurgeTrash(repo, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	products, _ = repo.LoadProducts()
+	assert.Equal(t, []int{1}, productIDs(products))
+}
+
+// fakeRecords remembers the products it was asked to purge
+type fakeRecords struct {
+	purged []int
+	err    error
+}
+
+func (r *fakeRecords) Purge(products []models.Product) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.purged = append(r.purged, productIDs(products)...)
+	return nil
+}
+
+func TestPurgeTrash_PurgesRecords(t *testing.T) {
+	deletedAt := time.Now().Add(-time.Hour)
+	repo := &memoryRepository{products: []models.Product{
+		{ID: 1, Name: "Live"},
+		{ID: 2, Name: "Trashed", DeletedAt: &deletedAt},
+	}}
+
+	records := &fakeRecords{}
+	_, err := PurgeTrash(failingSaves{repo}, time.Time{}, records)
+	assert.Error(t, err)
+	products, _ := repo.LoadProducts()
+	assert.Equal(t, []int{1, 2}, productIDs(products), "a failed save keeps the products")
+	assert.Empty(t, records.purged, "a failed save keeps the records")
+
+	failing := &fakeRecords{err: errors.New("disk full")}
+	purged, err := PurgeTrash(repo, time.Time{}, failing, records)
+	assert.NoError(t, err, "the catalog is saved before the records are dropped")
+	assert.Equal(t, 1, purged)
+	assert.Equal(t, []int{2}, records.purged)
+	products, _ = repo.LoadProducts()
+	assert.Equal(t, []int{1}, productIDs(products))
+
+	purged, err = PurgeTrash(repo, time.Time{}, records)
+	assert.NoError(t, err)
+	assert.Zero(t, purged)
+	assert.Equal(t, []int{2}, records.purged, "records are only purged with products")
+}
+
+func TestPurgeTrash_IDsAreNotReused(t *testing.T) {
+	db := &database.Database{}
+	drivers := map[string]func(t *testing.T, dir string) ProductRepository{
+		"json": func(t *testing.T, dir string) ProductRepository {
+			return NewProductRepository(NewFileClient(db, filepath.Join(dir, "data.json")))
+		},
+		"journal": func(t *testing.T, dir string) ProductRepository {
+			conf := newJournalTestConfig(t)
+			conf.DatabasePath = filepath.Join(dir, "data.json")
+			conf.JournalPath = filepath.Join(dir, "data.json.journal")
+			repo, err := NewJournalRepository(db, conf)
+			assert.NoError(t, err)
+			t.Cleanup(func() { repo.Close() })
+			return repo
+		},
+		"sqlite": func(t *testing.T, dir string) ProductRepository {
+			repo, err := NewSQLiteRepository(filepath.Join(dir, "catalog.db"))
+			assert.NoError(t, err)
+			t.Cleanup(func() { repo.Close() })
+			return repo
+		},
+	}
+
+	for name, open := range drivers {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			repo := open(t, dir)
+			insert := func(repo ProductRepository) int {
+				var id int
+				assert.NoError(t, repo.Update(func(tx *ProductTx) error {
+					id = tx.Insert(models.Product{Name: "Product"}).ID
+					return nil
+				}))
+				return id
+			}
+
+			assert.Equal(t, 1, insert(repo))
+			assert.Equal(t, 2, insert(repo))
+			deletedAt := time.Now()
+			assert.NoError(t, repo.Update(func(tx *ProductTx) error {
+				p, _ := tx.Get(2)
+				p.DeletedAt = &deletedAt
+				tx.Replace(p)
+				return nil
+			}))
+			purged, err := PurgeTrash(repo, time.Time{})
+			assert.NoError(t, err)
+			assert.Equal(t, 1, purged)
+
+			assert.Equal(t, 3, insert(repo))
+			if journal, ok := repo.(*JournalRepository); ok {
+				assert.NoError(t, journal.Compact())
+			}
+			assert.NoError(t, repo.Update(func(tx *ProductTx) error {
+				tx.Delete(3)
+				return nil
+			}))
+			if closer, ok := repo.(interface{ Close() error }); ok {
+				assert.NoError(t, closer.Close())
+			}
+
+			assert.Equal(t, 4, insert(open(t, dir)), "the mark survives reopening")
+		})
+	}
+}
+
+func TestMemoryRepository_IDsAreNotReused(t *testing.T) {
+	repo := &memoryRepository{}
+	assert.NoError(t, repo.SaveProducts([]models.Product{{ID: 1}, {ID: 2}}))
+	assert.NoError(t, repo.SaveProducts([]models.Product{{ID: 1}}))
+	assert.Equal(t, 3, repo.GetNextID([]models.Product{{ID: 1}}))
+}
+
+func TestSQLiteRepository_FiltersDeleted(t *testing.T) {
+	repo := newSQLiteTestRepository(t)
+	deletedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	products := append([]models.Product(nil), sqliteTestProducts...)
+	products[1].DeletedAt = &deletedAt
+	assert.NoError(t, repo.SaveProducts(products))
+
+	live, err := repo.QueryProducts(ProductFilter{Limit: -1})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 3}, productIDs(live))
+
+	trash, err := repo.QueryProducts(ProductFilter{Limit: -1, Deleted: OnlyDeleted})
+	assert.NoError(t, err)
+	if assert.Len(t, trash, 1) {
+		assert.Equal(t, 2, trash[0].ID)
+		assert.True(t, deletedAt.Equal(*trash[0].DeletedAt))
+	}
+
+	all, err := repo.LoadProducts()
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+}
+
+func productIDs(products []models.Product) []int {
+	ids := make([]int, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	return ids
+}
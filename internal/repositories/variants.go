@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+
+	"item-comparison-ai-api/internal/models"
+)
+
+// ErrInvalidVariant is matched by every VariantError
+var ErrInvalidVariant = errors.New("invalid product variant")
+
+// VariantError is returned when a save would break the parent/variant
+// structure of the catalog
+type VariantError struct {
+	ID     int
+	Reason string
+}
+
+func (e *VariantError) Error() string {
+	if e.ID == 0 {
+		return "new product: " + e.Reason
+	}
+	return fmt.Sprintf("product %d: %s", e.ID, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrInvalidVariant) match
+func (e *VariantError) Is(target error) bool {
+	return target == ErrInvalidVariant
+}
+
+// variantIndex looks up the parents and live variants of a catalog
+type variantIndex struct {
+	byID     map[int]models.Product
+	variants map[int][]models.Product
+}
+
+func newVariantIndex(products []models.Product, skipID int) variantIndex {
+	idx := variantIndex{
+		byID:     make(map[int]models.Product, len(products)),
+		variants: make(map[int][]models.Product),
+	}
+	for _, p := range products {
+		if p.ID == skipID && skipID != 0 {
+			continue
+		}
+		idx.byID[p.ID] = p
+		if p.IsVariant() && !p.IsDeleted() {
+			idx.variants[p.ParentID] = append(idx.variants[p.ParentID], p)
+		}
+	}
+	return idx
+}
+
+// check validates p against the rest of the catalog. A live variant needs a
+// live parent that is not a variant itself, a value for every axis of the
+// parent and a combination of values no sibling has. A parent cannot be
+// trashed or drop an axis while it has live variants.
+func (idx variantIndex) check(p models.Product) error {
+	if p.IsDeleted() {
+		if !p.IsVariant() && len(idx.siblings(p.ID, p.ID)) > 0 {
+			return &VariantError{ID: p.ID, Reason: "has live variants, delete them first"}
+		}
+		return nil
+	}
+
+	if !p.IsVariant() {
+		return idx.checkVariantsOf(p)
+	}
+
+	if len(p.VariantAxes) > 0 {
+		return &VariantError{ID: p.ID, Reason: "a variant cannot have variant_axes"}
+	}
+	parent, ok := idx.byID[p.ParentID]
+	switch {
+	case !ok || parent.IsDeleted():
+		return &VariantError{ID: p.ID, Reason: fmt.Sprintf("parent %d does not exist", p.ParentID)}
+	case parent.IsVariant():
+		return &VariantError{ID: p.ID, Reason: fmt.Sprintf("parent %d is itself a variant", p.ParentID)}
+	case len(parent.VariantAxes) == 0:
+		return &VariantError{ID: p.ID, Reason: fmt.Sprintf("parent %d has no variant_axes", p.ParentID)}
+	}
+	for _, axis := range parent.VariantAxes {
+		if p.Specifications[axis] == "" {
+			return &VariantError{ID: p.ID, Reason: fmt.Sprintf("missing specification %q of variant axis", axis)}
+		}
+	}
+
+	key := p.VariantKey(parent.VariantAxes)
+	for _, sibling := range idx.siblings(p.ParentID, p.ID) {
+		if sibling.VariantKey(parent.VariantAxes) == key {
+			return &VariantError{ID: p.ID, Reason: fmt.Sprintf("variant %d already has the same axis values", sibling.ID)}
+		}
+	}
+	return nil
+}
+
+// checkVariantsOf validates the live variants of parent against its axes
+func (idx variantIndex) checkVariantsOf(parent models.Product) error {
+	variants := idx.siblings(parent.ID, parent.ID)
+	if len(variants) == 0 {
+		return nil
+	}
+	if len(parent.VariantAxes) == 0 {
+		return &VariantError{ID: parent.ID, Reason: "has live variants, variant_axes cannot be empty"}
+	}
+
+	seen := make(map[string]int, len(variants))
+	for _, v := range variants {
+		for _, axis := range parent.VariantAxes {
+			if v.Specifications[axis] == "" {
+				return &VariantError{ID: parent.ID, Reason: fmt.Sprintf("variant %d has no specification %q", v.ID, axis)}
+			}
+		}
+		key := v.VariantKey(parent.VariantAxes)
+		if other, ok := seen[key]; ok {
+			return &VariantError{ID: parent.ID, Reason: fmt.Sprintf("variants %d and %d have the same axis values", other, v.ID)}
+		}
+		seen[key] = v.ID
+	}
+	return nil
+}
+
+// siblings returns the live variants of parentID other than skipID
+func (idx variantIndex) siblings(parentID, skipID int) []models.Product {
+	result := make([]models.Product, 0, len(idx.variants[parentID]))
+	for _, v := range idx.variants[parentID] {
+		if v.ID != skipID {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// checkVariants rejects catalogs with an invalid parent/variant structure
+func checkVariants(products []models.Product) error {
+	idx := newVariantIndex(products, 0)
+	for _, p := range products {
+		if p.IsVariant() && !p.IsDeleted() {
+			if err := idx.check(p); err != nil {
+				return err
+			}
+		}
+	}
+	for _, p := range products {
+		if !p.IsVariant() && len(idx.variants[p.ID]) > 0 {
+			if err := idx.check(p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkCatalog enforces the constraints spanning several products: unique
+// identifiers and a valid variant structure. Every repository runs it before
+// saving.
+func checkCatalog(products []models.Product) error {
+	if err := checkIdentifiers(products); err != nil {
+		return err
+	}
+	return checkVariants(products)
+}
@@ -0,0 +1,126 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/money"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// usd is a price in US dollars
+func usd(amount string) money.Money {
+	return money.New(money.MustParseDecimal(amount), money.DefaultCurrency)
+}
+
+func phoneCatalog() []models.Product {
+	return []models.Product{
+		{ID: 1, Name: "Phone", Price: usd("800"), Category: "Electronics", VariantAxes: []string{"Color", "Storage"}, Specifications: map[string]string{"Camera": "108MP"}},
+	}
+}
+
+func TestProductTx_VariantsInheritFromParent(t *testing.T) {
+	tx := NewProductTx(phoneCatalog(), nextProductID)
+
+	black := tx.Insert(models.Product{ParentID: 1, Specifications: map[string]string{"Color": "black", "Storage": "128GB"}})
+	assert.Equal(t, "Phone", black.Name)
+	assert.Equal(t, usd("800"), black.Price)
+	assert.Equal(t, map[string]string{"Camera": "108MP", "Color": "black", "Storage": "128GB"}, black.Specifications)
+
+	big := tx.Insert(models.Product{ParentID: 1, Price: usd("950"), Specifications: map[string]string{"Color": "black", "Storage": "512GB"}})
+	assert.Equal(t, usd("950"), big.Price)
+	assert.NoError(t, checkCatalog(tx.Products))
+
+	// Shared fields follow the parent, overridden ones stay
+	parent, _ := tx.Get(1)
+	parent.Name = "Phone 2"
+	parent.Price = usd("700")
+	parent.Specifications = map[string]string{"Camera": "200MP"}
+	tx.Replace(parent)
+
+	black, _ = tx.Get(black.ID)
+	big, _ = tx.Get(big.ID)
+	assert.Equal(t, "Phone 2", black.Name)
+	assert.Equal(t, usd("700"), black.Price)
+	assert.Equal(t, "200MP", black.Specifications["Camera"])
+	assert.Equal(t, usd("950"), big.Price)
+	assert.Equal(t, "512GB", big.Specifications["Storage"])
+
+	// The variants that followed are changes of their own
+	changes := tx.Changes()[2:]
+	if assert.Len(t, changes, 3) {
+		assert.Equal(t, []int{1, black.ID, big.ID}, []int{changes[0].After.ID, changes[1].After.ID, changes[2].After.ID})
+		assert.Equal(t, usd("800"), changes[1].Before.Price)
+		assert.Equal(t, usd("700"), changes[1].After.Price)
+	}
+}
+
+func TestProductTx_VariantsShareTranslations(t *testing.T) {
+	catalog := phoneCatalog()
+	catalog[0].Translations = map[string]models.Translation{
+		"de": {Name: "Telefon", SpecLabels: map[string]string{"Camera": "Kamera"}},
+	}
+	tx := NewProductTx(catalog, nextProductID)
+
+	black := tx.Insert(models.Product{ParentID: 1, Specifications: map[string]string{"Color": "black", "Storage": "128GB"},
+		Translations: map[string]models.Translation{"DE": {SpecLabels: map[string]string{"Color": "Farbe"}}}})
+	assert.Equal(t, models.Translation{Name: "Telefon", SpecLabels: map[string]string{"Camera": "Kamera", "Color": "Farbe"}}, black.Translations["de"])
+
+	// A variant with a name of its own does not take the parent's
+	// translated name
+	pro := tx.Insert(models.Product{ParentID: 1, Name: "Phone Pro", Specifications: map[string]string{"Color": "black", "Storage": "512GB"}})
+	assert.Empty(t, pro.Translations["de"].Name)
+
+	parent, _ := tx.Get(1)
+	parent.Translations = map[string]models.Translation{
+		"de": {Name: "Telefon 2", SpecLabels: map[string]string{"Camera": "Kamera"}},
+		"fr": {Name: "Téléphone 2"},
+	}
+	tx.Replace(parent)
+
+	black, _ = tx.Get(black.ID)
+	pro, _ = tx.Get(pro.ID)
+	assert.Equal(t, "Telefon 2", black.Translations["de"].Name)
+	assert.Equal(t, "Farbe", black.Translations["de"].SpecLabels["Color"])
+	assert.Equal(t, "Téléphone 2", black.Translations["fr"].Name)
+	assert.Empty(t, pro.Translations["fr"].Name)
+}
+
+func TestCheckConstraints_Variants(t *testing.T) {
+	products := append(phoneCatalog(),
+		models.Product{ID: 2, Name: "Phone", ParentID: 1, Specifications: map[string]string{"Color": "black", "Storage": "128GB"}},
+		models.Product{ID: 3, Name: "Mouse"},
+	)
+	assert.NoError(t, checkCatalog(products))
+	tx := NewProductTx(products, nextProductID)
+
+	tests := []struct {
+		name    string
+		product models.Product
+		wantErr string
+	}{
+		{"same axis values as a sibling", models.Product{ParentID: 1, Specifications: map[string]string{"Color": "black", "Storage": "128GB"}}, "new product: variant 2 already has the same axis values"},
+		{"missing axis", models.Product{ParentID: 1, Specifications: map[string]string{"Color": "white"}}, `new product: missing specification "Storage" of variant axis`},
+		{"parent without axes", models.Product{ParentID: 3, Specifications: map[string]string{"Color": "white"}}, "new product: parent 3 has no variant_axes"},
+		{"parent is a variant", models.Product{ParentID: 2}, "new product: parent 2 is itself a variant"},
+		{"missing parent", models.Product{ParentID: 9}, "new product: parent 9 does not exist"},
+		{"parent dropping an axis", models.Product{ID: 1, Name: "Phone", VariantAxes: []string{"Color", "Size"}}, `product 1: variant 2 has no specification "Size"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tx.CheckConstraints(tt.product)
+			assert.ErrorIs(t, err, ErrInvalidVariant)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+
+	assert.NoError(t, tx.CheckConstraints(models.Product{ParentID: 1, Specifications: map[string]string{"Color": "white", "Storage": "128GB"}}))
+
+	// A parent cannot go to the trash before its variants
+	trashed := products[0]
+	now := time.Now()
+	trashed.DeletedAt = &now
+	assert.ErrorIs(t, tx.CheckConstraints(trashed), ErrInvalidVariant)
+}
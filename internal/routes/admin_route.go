@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"item-comparison-ai-api/internal/handlers"
+	"item-comparison-ai-api/internal/repositories"
+	"item-comparison-ai-api/internal/server"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminRouter - binds the administrative endpoints
+type AdminRouter struct {
+	Snapshots *repositories.SnapshotManager
+	Products  repositories.ProductRepository
+	// ExchangeRates binds the exchange-rate endpoints when set
+	ExchangeRates *repositories.ExchangeRateStore
+	// PurgeRecords are the stores whose records of a product go when it is
+	// purged from the trash
+	PurgeRecords []repositories.ProductRecords
+}
+
+// Bind - method responsible to bind controller and actions
+func (r *AdminRouter) Bind(router *gin.RouterGroup, app *server.Application) {
+	snapshotHandler := handlers.NewSnapshotHandler(r.Snapshots)
+	productHandler := handlers.NewProductHandler(r.Products).WithPurgeRecords(r.PurgeRecords...)
+
+	admin := router.Group("/admin")
+	admin.GET("/snapshots", snapshotHandler.ListSnapshots)
+	admin.POST("/snapshots", snapshotHandler.CreateSnapshot)
+	admin.GET("/snapshots/:name", snapshotHandler.DownloadSnapshot)
+	admin.POST("/snapshots/:name/restore", snapshotHandler.RestoreSnapshot)
+	admin.DELETE("/trash", productHandler.PurgeTrash)
+	admin.DELETE("/trash/:id", productHandler.PurgeProduct)
+
+	if r.ExchangeRates != nil {
+		rateHandler := handlers.NewExchangeRateHandler(r.ExchangeRates)
+		admin.GET("/exchange-rates", rateHandler.GetExchangeRates)
+		admin.PUT("/exchange-rates", rateHandler.UpdateExchangeRates)
+	}
+}
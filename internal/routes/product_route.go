@@ -1,9 +1,10 @@
 package routes
 
 import (
-	"item-comparison-ai-api/config"
-	"item-comparison-ai-api/internal/database"
+	"item-comparison-ai-api/internal/blobstore"
 	"item-comparison-ai-api/internal/handlers"
+	"item-comparison-ai-api/internal/i18n"
+	middlewares "item-comparison-ai-api/internal/middleware"
 	"item-comparison-ai-api/internal/repositories"
 	"item-comparison-ai-api/internal/server"
 
@@ -11,21 +12,107 @@ import (
 )
 
 // Handler - represents a route/controller binder
-type ProductRouter struct{}
+type ProductRouter struct {
+	Repository repositories.ProductRepository
+	// RequireIfMatch makes If-Match mandatory on product mutations
+	RequireIfMatch bool
+	// History records product revisions; the history endpoints are only
+	// bound when it is set
+	History *repositories.ProductHistory
+	// Idempotency stores the responses of product creations sent with an
+	// Idempotency-Key; without it the header is ignored
+	Idempotency *repositories.IdempotencyStore
+	// ExchangeRates converts prices for reads with ?currency=; without it
+	// only prices already in that currency can be shown
+	ExchangeRates *repositories.ExchangeRateStore
+	// PriceHistory records every price change; the price history endpoints
+	// are only bound when it is set
+	PriceHistory *repositories.PriceHistory
+	// Reviews stores product reviews; the review endpoints are only bound
+	// when it is set
+	Reviews *repositories.ReviewStore
+	// Images stores uploaded product images; the image endpoints and the
+	// route serving them are only bound when it is set
+	Images blobstore.Store
+	// MaxImageSize is the upload limit in bytes, zero for the default
+	MaxImageSize int64
+	// Locales enables localized reads; the missing translation report is
+	// only bound when it is set
+	Locales *i18n.Locales
+	// Brands stores the brands products link to; the brand endpoints are
+	// only bound when it is set
+	Brands *repositories.BrandStore
+}
 
 // Bind - method responsible to bind controller and actions
 func (r *ProductRouter) Bind(router *gin.RouterGroup, app *server.Application) {
-	db := database.NewClient(&database.Database{})
-
-	baseRepo := repositories.NewBaseRepository(db, config.New())
-	productRepo := repositories.NewProductRepository(baseRepo)
-	productHandler := handlers.NewProductHandler(productRepo)
+	productHandler := handlers.NewProductHandler(r.Repository).
+		WithRequireIfMatch(r.RequireIfMatch).
+		WithHistory(r.History).
+		WithExchangeRates(r.ExchangeRates).
+		WithPriceHistory(r.PriceHistory).
+		WithReviews(r.Reviews).
+		WithImages(r.Images, r.MaxImageSize).
+		WithLocales(r.Locales).
+		WithBrands(r.Brands)
 
 	// Define the GET endpoint for retrieving a product by ID
 	router.GET("/products", productHandler.GetAllProducts)
+	router.GET("/products/trash", productHandler.ListTrash)
+	router.GET("/products/export", productHandler.ExportProducts)
+	router.GET("/products/compare", productHandler.CompareProducts)
+	router.GET("/products/facets", productHandler.ProductFacets)
+	router.GET("/products/by-sku/:sku", productHandler.GetProductBySKU)
+	router.PUT("/products/by-sku/:sku", productHandler.UpsertProductBySKU)
 	router.GET("/products/:id", productHandler.GetProduct)
-	router.POST("/products", productHandler.CreateProduct)
+	if r.Idempotency != nil {
+		router.POST("/products", middlewares.Idempotency(r.Idempotency), productHandler.CreateProduct)
+	} else {
+		router.POST("/products", productHandler.CreateProduct)
+	}
+	router.POST("/products/bulk", productHandler.BulkProducts)
+	router.POST("/products/import", productHandler.ImportProducts)
 	router.PUT("/products/:id", productHandler.UpdateProduct)
 	router.PATCH("/products/:id", productHandler.PatchProduct)
 	router.DELETE("/products/:id", productHandler.DeleteProduct)
+	router.POST("/products/:id/restore", productHandler.RestoreProduct)
+	router.GET("/products/:id/variants", productHandler.ListVariants)
+	router.GET("/products/:id/variants/compare", productHandler.CompareVariants)
+
+	if r.History != nil {
+		router.GET("/products/:id/history", productHandler.GetHistory)
+		router.GET("/products/:id/history/:rev", productHandler.GetRevision)
+		router.POST("/products/:id/rollback/:rev", productHandler.RollbackProduct)
+	}
+
+	if r.PriceHistory != nil {
+		router.GET("/products/price-drops", productHandler.ListPriceDrops)
+		router.GET("/products/:id/price-history", productHandler.GetPriceHistory)
+	}
+
+	if r.Reviews != nil {
+		router.GET("/products/:id/reviews", productHandler.ListReviews)
+		router.POST("/products/:id/reviews", productHandler.CreateReview)
+		router.PATCH("/products/:id/reviews/:review_id", productHandler.ModerateReview)
+		router.DELETE("/products/:id/reviews/:review_id", productHandler.DeleteReview)
+	}
+
+	if r.Images != nil {
+		router.POST("/products/:id/images", productHandler.UploadImage)
+		router.DELETE("/products/:id/images/:image_id", productHandler.DeleteImage)
+		router.GET(handlers.ImagesPath+"/*key", productHandler.ServeImage)
+	}
+
+	if r.Brands != nil {
+		router.GET("/brands", productHandler.ListBrands)
+		router.POST("/brands", productHandler.CreateBrand)
+		router.GET("/brands/:brand", productHandler.GetBrand)
+		router.PUT("/brands/:brand", productHandler.UpdateBrand)
+		router.DELETE("/brands/:brand", productHandler.DeleteBrand)
+		router.GET("/brands/:brand/stats", productHandler.BrandStats)
+	}
+
+	if r.Locales != nil {
+		router.GET("/products/translations/missing", productHandler.MissingTranslations)
+	}
 }
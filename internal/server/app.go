@@ -18,14 +18,14 @@ import (
 // Application - represents a application server configuration
 type Application struct {
 	config     *config.AppConfig
-	database   *database.Database
+	database   database.FileStore
 	httpServer *http.Server
 	router     *gin.Engine
 	logger     logger.Logger
 }
 
 // New - responsible to creates a new instance from Application
-func New(config *config.AppConfig, db *database.Database, router *gin.Engine, logger logger.Logger) *Application {
+func New(config *config.AppConfig, db database.FileStore, router *gin.Engine, logger logger.Logger) *Application {
 	gin.SetMode(getGinExecMode(config))
 
 	var server = &http.Server{
@@ -54,7 +54,13 @@ func getGinExecMode(c *config.AppConfig) string {
 
 // WithMiddlewares - responsible to attach middlewares into http request pipeline
 func (a *Application) WithMiddlewares() *Application {
-	a.router.Use(cors.Default())
+	// Browsers only let scripts read and send the concurrency headers when
+	// they are listed explicitly
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowAllOrigins = true
+	corsConfig.AddAllowHeaders("If-Match", "Idempotency-Key")
+	corsConfig.AddExposeHeaders("ETag", "Idempotent-Replayed")
+	a.router.Use(cors.New(corsConfig))
 	a.router.Use(ginlogrus.Logger(a.logger.GetLogger()))
 	a.router.NoRoute(func(ctx *gin.Context) {
 		h.HandleError(ctx, h.ErrNotFound)
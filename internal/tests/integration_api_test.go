@@ -3,24 +3,42 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"item-comparison-ai-api/config"
+	"item-comparison-ai-api/internal/blobstore"
 	"item-comparison-ai-api/internal/database"
 	"item-comparison-ai-api/internal/handlers"
+	"item-comparison-ai-api/internal/i18n"
+	middlewares "item-comparison-ai-api/internal/middleware"
 	"item-comparison-ai-api/internal/models"
+	"item-comparison-ai-api/internal/money"
 	"item-comparison-ai-api/internal/repositories"
+	"item-comparison-ai-api/internal/routes"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
-// setupTestEnvironment creates a temporary data.json for testing
-func setupTestEnvironment(t *testing.T) func() {
+// usd is a price in US dollars
+func usd(amount string) money.Money {
+	return money.New(money.MustParseDecimal(amount), money.DefaultCurrency)
+}
+
+// setupTestEnvironment creates a temporary data.json for testing and opens
+// the repository selected by STORAGE_DRIVER (json by default) on top of it
+func setupTestEnvironment(t *testing.T) (repositories.ProductRepository, func()) {
 	// Create a temporary data.json file
 	tempFile, err := os.CreateTemp("", "test_data_*.json")
 	assert.NoError(t, err)
@@ -28,51 +46,93 @@ func setupTestEnvironment(t *testing.T) func() {
 	tempFile.Close()
 
 	t.Setenv("DATA_FILE_PATH", tempFileName)
+	t.Setenv("SQLITE_PATH", tempFileName+".db")
 
 	// Seed initial data
 	initialProducts := []models.Product{
-		{ID: 1, Name: "Laptop", ImageURL: "/images/laptop.png", Description: "High-performance laptop", Price: 1200.00, Rating: 4.5, Specifications: map[string]string{"RAM": "16GB", "Storage": "512GB SSD"}, Category: "Electronics"},
-		{ID: 2, Name: "Smartphone", ImageURL: "/images/smartphone.png", Description: "Latest model smartphone", Price: 800.00, Rating: 4.8, Specifications: map[string]string{"Camera": "108MP", "Battery": "5000mAh"}, Category: "Electronics"},
-		{ID: 3, Name: "Headphones", ImageURL: "/images/headphones.png", Description: "Noise-cancelling headphones", Price: 150.00, Rating: 4.2, Specifications: map[string]string{"Connectivity": "Bluetooth 5.0", "Driver size": "40mm"}, Category: "Accessories"},
+		{ID: 1, Name: "Laptop", ImageURL: "/images/laptop.png", Description: "High-performance laptop", Price: usd("1200"), Rating: 4.5, Specifications: map[string]string{"RAM": "16GB", "Storage": "512GB SSD"}, Category: "Electronics"},
+		{ID: 2, Name: "Smartphone", ImageURL: "/images/smartphone.png", Description: "Latest model smartphone", Price: usd("800"), Rating: 4.8, Specifications: map[string]string{"Camera": "108MP", "Battery": "5000mAh"}, Category: "Electronics"},
+		{ID: 3, Name: "Headphones", ImageURL: "/images/headphones.png", Description: "Noise-cancelling headphones", Price: usd("150"), Rating: 4.2, Specifications: map[string]string{"Connectivity": "Bluetooth 5.0", "Driver size": "40mm"}, Category: "Accessories"},
 	}
 	var config = config.New()
 
 	db := database.NewClient(&database.Database{})
-	baseRepo := repositories.NewBaseRepository(db, config)
-	repo := repositories.NewProductRepository(baseRepo)
+	repo, err := repositories.OpenProductRepository(db, config)
+	assert.NoError(t, err)
 	err = repo.SaveProducts(initialProducts)
 	assert.NoError(t, err)
 
 	// Return a cleanup function
-	return func() {
-		os.Remove(tempFileName) // Clean up the temporary file
+	return repo, func() {
+		if closer, ok := repo.(io.Closer); ok {
+			closer.Close()
+		}
+		// Clean up the temporary file and any driver side files
+		for _, name := range []string{tempFileName, tempFileName + ".journal", tempFileName + ".db", tempFileName + ".db-wal", tempFileName + ".db-shm"} {
+			os.Remove(name)
+		}
 	}
 }
 
-// setupRouter initializes the Gin router for testing
-func setupRouter() *gin.Engine {
+// setupRouter initializes the Gin router for testing with the same routes
+// the application binds
+func setupRouter(repo repositories.ProductRepository) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.Default()
-	db := database.NewClient(&database.Database{})
-	baseRepo := repositories.NewBaseRepository(db, config.New())
-	repo := repositories.NewProductRepository(baseRepo)
-	h := handlers.NewProductHandler(repo)
-	r.GET("/products", h.GetAllProducts)
-	r.GET("/products/:id", h.GetProduct)
-	r.POST("/products", h.CreateProduct)
-	r.PUT("/products/:id", h.UpdateProduct)
-	r.PATCH("/products/:id", h.PatchProduct)
-	r.DELETE("/products/:id", h.DeleteProduct)
+	productRouter := &routes.ProductRouter{Repository: repo}
+	productRouter.Bind(r.Group(""), nil)
 	return r
 }
 
+// testClient sends requests to a test server, failing the test when one
+// cannot be sent
+type testClient struct {
+	t      *testing.T
+	url    string
+	header http.Header
+}
+
+// newTestClient returns a client of server sending JSON bodies
+func newTestClient(t *testing.T, server *httptest.Server) *testClient {
+	return &testClient{t: t, url: server.URL, header: http.Header{"Content-Type": {"application/json"}}}
+}
+
+// with returns a copy of the client that also sets header key to value
+func (c *testClient) with(key, value string) *testClient {
+	header := c.header.Clone()
+	header.Set(key, value)
+	return &testClient{t: c.t, url: c.url, header: header}
+}
+
+// do sends a request and decodes the JSON response into out, if not nil.
+// The response body is closed by the time do returns.
+func (c *testClient) do(method, path, body string, out interface{}) *http.Response {
+	req, err := http.NewRequest(method, c.url+path, strings.NewReader(body))
+	assert.NoError(c.t, err)
+	req.Header = c.header.Clone()
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(c.t, err) {
+		c.t.FailNow()
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		json.NewDecoder(resp.Body).Decode(out)
+	}
+	return resp
+}
+
+// send is do for callers only interested in the status code
+func (c *testClient) send(method, path, body string, out interface{}) int {
+	return c.do(method, path, body, out).StatusCode
+}
+
 // TestIntegrationGetProductSuccess tests the success scenario for the /products/{id} endpoint
 func TestIntegrationGetProductSuccess(t *testing.T) {
-	cleanup := setupTestEnvironment(t)
+	repo, cleanup := setupTestEnvironment(t)
 	defer cleanup()
 
 	// Setup the router
-	router := setupRouter()
+	router := setupRouter(repo)
 
 	// Create a new test server
 	server := httptest.NewServer(router)
@@ -102,11 +162,11 @@ func TestIntegrationGetProductSuccess(t *testing.T) {
 
 // TestIntegrationNotFound tests the scenario where the endpoint is not found
 func TestIntegrationNotFound(t *testing.T) {
-	cleanup := setupTestEnvironment(t)
+	repo, cleanup := setupTestEnvironment(t)
 	defer cleanup()
 
 	// Setup the router
-	router := setupRouter()
+	router := setupRouter(repo)
 
 	// Create a new test server
 	server := httptest.NewServer(router)
@@ -123,10 +183,10 @@ func TestIntegrationNotFound(t *testing.T) {
 
 // TestIntegrationGetAllProducts tests the GetAllProducts endpoint
 func TestIntegrationGetAllProducts(t *testing.T) {
-	cleanup := setupTestEnvironment(t)
+	repo, cleanup := setupTestEnvironment(t)
 	defer cleanup()
 
-	router := setupRouter()
+	router := setupRouter(repo)
 	server := httptest.NewServer(router)
 	defer server.Close()
 
@@ -149,17 +209,17 @@ func TestIntegrationGetAllProducts(t *testing.T) {
 
 // TestIntegrationCreateProduct tests the CreateProduct endpoint
 func TestIntegrationCreateProduct(t *testing.T) {
-	cleanup := setupTestEnvironment(t)
+	repo, cleanup := setupTestEnvironment(t)
 	defer cleanup()
 
-	router := setupRouter()
+	router := setupRouter(repo)
 	server := httptest.NewServer(router)
 	defer server.Close()
 
 	newProduct := models.Product{
 		Name:        "New Product",
 		Description: "A brand new product",
-		Price:       100.00,
+		Price:       usd("100"),
 		Rating:      4.0,
 		Category:    "Electronics",
 	}
@@ -182,12 +242,249 @@ func TestIntegrationCreateProduct(t *testing.T) {
 	assert.Equal(t, 4, createdProduct.ID)
 }
 
+// TestIntegrationIdempotentCreate tests that retried creations with the same
+// Idempotency-Key create a single product
+func TestIntegrationIdempotentCreate(t *testing.T) {
+	repo, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	store := repositories.NewIdempotencyStore(&database.Database{}, filepath.Join(t.TempDir(), "idempotency"), time.Hour)
+	(&routes.ProductRouter{Repository: repo, Idempotency: store}).Bind(router.Group(""), nil)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	client := newTestClient(t, server)
+	retry := client.with("Idempotency-Key", "retry-1")
+
+	var created, replayed models.Product
+	first := retry.do(http.MethodPost, "/products", `{"name": "Mouse", "price": 25}`, &created)
+	assert.Equal(t, http.StatusCreated, first.StatusCode)
+	assert.Empty(t, first.Header.Get("Idempotent-Replayed"))
+
+	replay := retry.do(http.MethodPost, "/products", `{"name": "Mouse", "price": 25}`, &replayed)
+	assert.Equal(t, http.StatusCreated, replay.StatusCode)
+	assert.Equal(t, "true", replay.Header.Get("Idempotent-Replayed"))
+	assert.Equal(t, created, replayed)
+	assert.NotEmpty(t, first.Header.Get("ETag"))
+	assert.Equal(t, first.Header.Get("ETag"), replay.Header.Get("ETag"))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, retry.send(http.MethodPost, "/products", `{"name": "Keyboard"}`, nil))
+
+	products, err := repo.LoadProducts()
+	assert.NoError(t, err)
+	assert.Len(t, products, 4)
+
+	var other models.Product
+	assert.Equal(t, http.StatusCreated, client.with("Idempotency-Key", "retry-2").send(http.MethodPost, "/products", `{"name": "Mouse", "price": 25}`, &other))
+	assert.NotEqual(t, created.ID, other.ID)
+}
+
+// TestIntegrationIdempotencyReleasesPanics tests that a request whose handler
+// panicked can be retried with the same Idempotency-Key
+func TestIntegrationIdempotencyReleasesPanics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(gin.Recovery())
+	store := repositories.NewIdempotencyStore(&database.Database{}, filepath.Join(t.TempDir(), "idempotency"), time.Hour)
+	calls := 0
+	router.POST("/jobs", middlewares.Idempotency(store), func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			panic("first attempt fails")
+		}
+		c.JSON(http.StatusCreated, gin.H{"calls": calls})
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	retry := newTestClient(t, server).with("Idempotency-Key", "job-1")
+	assert.Equal(t, http.StatusInternalServerError, retry.send(http.MethodPost, "/jobs", `{}`, nil))
+	assert.Equal(t, http.StatusCreated, retry.send(http.MethodPost, "/jobs", `{}`, nil))
+	assert.Equal(t, 2, calls)
+}
+
+// TestIntegrationCurrencyConversion tests that prices are converted with the
+// exchange-rate table set through the admin endpoint
+func TestIntegrationCurrencyConversion(t *testing.T) {
+	repo, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	rates := repositories.NewExchangeRateStore(&database.Database{}, filepath.Join(t.TempDir(), "exchange_rates.json"))
+	(&routes.ProductRouter{Repository: repo, ExchangeRates: rates}).Bind(router.Group(""), nil)
+	(&routes.AdminRouter{Products: repo, ExchangeRates: rates}).Bind(router.Group(""), nil)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	client := newTestClient(t, server)
+	send := client.send
+
+	// Without rates only the stored currency can be shown
+	assert.Equal(t, http.StatusUnprocessableEntity, send(http.MethodGet, "/products/1?currency=EUR", "", nil))
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/1?currency=USD", "", nil))
+
+	var table money.Rates
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPut, "/admin/exchange-rates", `{"base": "USD", "rates": {"EUR": "-1"}}`, nil))
+	assert.Equal(t, http.StatusOK, send(http.MethodPut, "/admin/exchange-rates", `{"base": "USD", "updated_at": "2026-10-01T12:00:00Z", "rates": {"EUR": "0.92", "JPY": "150"}}`, &table))
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/admin/exchange-rates", "", &table))
+	assert.Equal(t, money.MustParseDecimal("0.92"), table.Rates["EUR"])
+
+	var laptop models.Product
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/1?currency=EUR", "", &laptop))
+	assert.Equal(t, money.MustParseDecimal("1104"), laptop.Price.Amount)
+	assert.Equal(t, "EUR", laptop.Price.Currency)
+	if assert.NotNil(t, laptop.Price.Conversion) {
+		assert.Equal(t, usd("1200"), laptop.Price.Conversion.From)
+		assert.Equal(t, money.MustParseDecimal("0.92"), laptop.Price.Conversion.Rate)
+		assert.Equal(t, time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC), laptop.Price.Conversion.RateUpdatedAt)
+	}
+
+	// A converted read is another representation with a weak tag of its own,
+	// which If-Match does not accept
+	stored := client.do(http.MethodGet, "/products/1", "", nil)
+	converted := client.do(http.MethodGet, "/products/1?currency=EUR", "", nil)
+	assert.False(t, strings.HasPrefix(stored.Header.Get("ETag"), "W/"))
+	assert.True(t, strings.HasPrefix(converted.Header.Get("ETag"), "W/"))
+	assert.NotEqual(t, "W/"+stored.Header.Get("ETag"), converted.Header.Get("ETag"))
+	patch := client.with("Content-Type", "application/merge-patch+json").with("If-Match", converted.Header.Get("ETag"))
+	assert.Equal(t, http.StatusPreconditionFailed, patch.send(http.MethodPatch, "/products/1", `{"name": "Laptop Pro"}`, nil))
+
+	// Prices in another currency go through the base
+	assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/products", `{"name": "Camera", "price": {"amount": "46000", "currency": "JPY"}}`, nil))
+	var comparison handlers.Comparison
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/compare?ids=3,4&currency=EUR", "", &comparison))
+	if assert.Len(t, comparison.Products, 2) {
+		assert.Equal(t, money.MustParseDecimal("138"), comparison.Products[0].Price.Amount)
+		assert.Equal(t, money.MustParseDecimal("282.13"), comparison.Products[1].Price.Amount)
+	}
+
+	// Mixed currencies sort by their value in the base currency, before paging
+	var sorted []models.Product
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products?sort=price&currency=EUR", "", &sorted))
+	assert.Equal(t, []int{3, 4, 2, 1}, productIDs(sorted))
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products?sort=-price&limit=2&offset=1", "", &sorted))
+	assert.Equal(t, []int{2, 4}, productIDs(sorted))
+
+	var listed []models.Product
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products?currency=JPY", "", &listed))
+	for _, p := range listed {
+		assert.Equal(t, "JPY", p.Price.Currency)
+		assert.Zero(t, p.Price.Amount.Places())
+	}
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/products?currency=EURO", "", nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, send(http.MethodGet, "/products?currency=GBP", "", nil))
+}
+
+// TestIntegrationUpsertBySKU tests that PUT /products/by-sku/:sku creates a
+// product once and updates it afterwards, and that identifiers stay unique
+func TestIntegrationUpsertBySKU(t *testing.T) {
+	repo, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	server := httptest.NewServer(setupRouter(repo))
+	defer server.Close()
+
+	send := newTestClient(t, server).send
+
+	var created, updated, found models.Product
+	assert.Equal(t, http.StatusCreated, send(http.MethodPut, "/products/by-sku/MOUSE-1", `{"name": "Mouse", "gtin": "4006381333931", "price": 25}`, &created))
+	assert.Equal(t, 4, created.ID)
+	assert.Equal(t, "MOUSE-1", created.SKU)
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPut, "/products/by-sku/MOUSE-1", `{"name": "Mouse", "gtin": "4006381333931", "price": 20}`, &updated))
+	assert.Equal(t, created.ID, updated.ID)
+	assert.Equal(t, usd("20"), updated.Price)
+
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/by-sku/MOUSE-1", "", &found))
+	assert.Equal(t, updated, found)
+
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/products/by-sku/NOPE", "", nil))
+
+	// The same GTIN written as GTIN-14 belongs to the mouse already
+	assert.Equal(t, http.StatusConflict, send(http.MethodPost, "/products", `{"name": "Other", "gtin": "04006381333931"}`, nil))
+
+	assert.Equal(t, http.StatusConflict, send(http.MethodPut, "/products/1", `{"name": "Laptop", "sku": "MOUSE-1"}`, nil))
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/products", `{"name": "Other", "gtin": "4006381333932"}`, nil))
+
+	products, err := repo.LoadProducts()
+	assert.NoError(t, err)
+	assert.Len(t, products, 4)
+}
+
+// TestIntegrationVariants tests grouping variants under a parent, the
+// collapsed listing and variant comparison
+func TestIntegrationVariants(t *testing.T) {
+	repo, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	server := httptest.NewServer(setupRouter(repo))
+	defer server.Close()
+
+	send := newTestClient(t, server).send
+
+	var parent, black, white models.Product
+	assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/products", `{"name": "Phone X", "price": 900, "category": "Electronics", "variant_axes": ["Color"], "specifications": {"Camera": "50MP"}}`, &parent))
+	assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/products", fmt.Sprintf(`{"parent_id": %d, "specifications": {"Color": "black"}}`, parent.ID), &black))
+	assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/products", fmt.Sprintf(`{"parent_id": %d, "price": 950, "specifications": {"Color": "white"}}`, parent.ID), &white))
+	assert.Equal(t, "Phone X", black.Name)
+	assert.Equal(t, usd("900"), black.Price)
+	assert.Equal(t, "50MP", black.Specifications["Camera"])
+
+	assert.Equal(t, http.StatusConflict, send(http.MethodPost, "/products", fmt.Sprintf(`{"parent_id": %d, "specifications": {"Color": "black"}}`, parent.ID), nil))
+	assert.Equal(t, http.StatusConflict, send(http.MethodDelete, fmt.Sprintf("/products/%d", parent.ID), "", nil))
+
+	var collapsed []handlers.CollapsedProduct
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products?variants=collapse&category=Electronics&sort=id", "", &collapsed))
+	if assert.Len(t, collapsed, 3) {
+		assert.Equal(t, parent.ID, collapsed[2].ID)
+		assert.Equal(t, 2, collapsed[2].VariantCount)
+	}
+
+	var variants []models.Product
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, fmt.Sprintf("/products/%d/variants", parent.ID), "", &variants))
+	assert.Len(t, variants, 2)
+
+	var comparison handlers.Comparison
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, fmt.Sprintf("/products/%d/variants/compare", parent.ID), "", &comparison))
+	assert.Equal(t, []string{"price", "specifications.Color"}, comparison.Differences)
+	assert.Equal(t, []string{"Color"}, comparison.Axes)
+
+	// Renaming the parent renames the variants that inherit the name
+	parent.Name = "Phone X2"
+	assert.Equal(t, http.StatusOK, send(http.MethodPut, fmt.Sprintf("/products/%d", parent.ID), mustJSON(t, parent), nil))
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, fmt.Sprintf("/products/compare?ids=%d,%d", black.ID, 1), "", &comparison))
+	assert.Equal(t, "Phone X2", comparison.Products[0].Name)
+	assert.Contains(t, comparison.Differences, "name")
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/products/compare?ids=1", "", nil))
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/products/compare?ids=1,99", "", nil))
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	data, err := json.Marshal(v)
+	assert.NoError(t, err)
+	return string(data)
+}
+
+func productIDs(products []models.Product) []int {
+	ids := make([]int, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
 // TestIntegrationUpdateProduct tests the UpdateProduct endpoint
 func TestIntegrationUpdateProduct(t *testing.T) {
-	cleanup := setupTestEnvironment(t)
+	repo, cleanup := setupTestEnvironment(t)
 	defer cleanup()
 
-	router := setupRouter()
+	router := setupRouter(repo)
 	server := httptest.NewServer(router)
 	defer server.Close()
 
@@ -195,7 +492,7 @@ func TestIntegrationUpdateProduct(t *testing.T) {
 		ID:          1,
 		Name:        "Updated Laptop",
 		Description: "Updated description",
-		Price:       1300.00,
+		Price:       usd("1300"),
 		Rating:      4.6,
 		Category:    "Electronics",
 	}
@@ -222,10 +519,10 @@ func TestIntegrationUpdateProduct(t *testing.T) {
 
 // TestIntegrationPatchProduct tests the PatchProduct endpoint
 func TestIntegrationPatchProduct(t *testing.T) {
-	cleanup := setupTestEnvironment(t)
+	repo, cleanup := setupTestEnvironment(t)
 	defer cleanup()
 
-	router := setupRouter()
+	router := setupRouter(repo)
 	server := httptest.NewServer(router)
 	defer server.Close()
 
@@ -252,17 +549,18 @@ func TestIntegrationPatchProduct(t *testing.T) {
 	err = json.Unmarshal(body, &returnedProduct)
 	assert.NoError(t, err)
 
-	assert.Equal(t, 1250.00, returnedProduct.Price)
-	assert.Equal(t, 4.7, returnedProduct.Rating)
+	assert.Equal(t, usd("1250"), returnedProduct.Price)
+	// The rating comes from reviews and cannot be patched
+	assert.Equal(t, 4.5, returnedProduct.Rating)
 	assert.Equal(t, "Premium Electronics", returnedProduct.Category)
 }
 
 // TestIntegrationDeleteProduct tests the DeleteProduct endpoint
 func TestIntegrationDeleteProduct(t *testing.T) {
-	cleanup := setupTestEnvironment(t)
+	repo, cleanup := setupTestEnvironment(t)
 	defer cleanup()
 
-	router := setupRouter()
+	router := setupRouter(repo)
 	server := httptest.NewServer(router)
 	defer server.Close()
 
@@ -274,3 +572,610 @@ func TestIntegrationDeleteProduct(t *testing.T) {
 
 	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
 }
+
+// TestIntegrationTrashAndRestore tests that deleted products move to the trash
+// and can be restored or purged
+func TestIntegrationTrashAndRestore(t *testing.T) {
+	repo, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	router := setupRouter(repo)
+	adminRouter := &routes.AdminRouter{Products: repo}
+	adminRouter.Bind(router.Group(""), nil)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	send := newTestClient(t, server).send
+
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/products/1", "", nil))
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/products/1", "", nil))
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/products/1", "", nil))
+	var live []models.Product
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products", "", &live))
+	assert.Len(t, live, 2)
+
+	var trash []models.Product
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/trash", "", &trash))
+	if assert.Len(t, trash, 1) {
+		assert.Equal(t, 1, trash[0].ID)
+		assert.NotNil(t, trash[0].DeletedAt)
+	}
+
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPost, "/products/2/restore", "", nil))
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/products/1/restore", "", nil))
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/1", "", nil))
+	trash = nil
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/trash", "", &trash))
+	assert.Empty(t, trash)
+
+	// Purging only removes trashed products
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/admin/trash/2", "", nil))
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/products/2", "", nil))
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/products/3", "", nil))
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/admin/trash/2", "", nil))
+
+	var purged map[string]int
+	assert.Equal(t, http.StatusOK, send(http.MethodDelete, "/admin/trash", "", &purged))
+	assert.Equal(t, map[string]int{"purged": 1}, purged)
+
+	products, err := repo.LoadProducts()
+	assert.NoError(t, err)
+	if assert.Len(t, products, 1) {
+		assert.Equal(t, 1, products[0].ID)
+	}
+
+	// IDs of purged products are not handed out again
+	var created models.Product
+	assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/products", `{"name": "Tablet"}`, &created))
+	assert.Equal(t, 4, created.ID)
+}
+
+// TestIntegrationHistoryAndRollback tests that mutations are recorded as
+// revisions and that a product can be rolled back to one of them
+func TestIntegrationHistoryAndRollback(t *testing.T) {
+	repo, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	historyPath := os.Getenv("DATA_FILE_PATH") + ".history"
+	defer os.Remove(historyPath)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	productRouter := &routes.ProductRouter{
+		Repository: repo,
+		History:    repositories.NewProductHistory(&database.Database{}, historyPath),
+	}
+	productRouter.Bind(router.Group(""), nil)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	send := newTestClient(t, server).with("X-Actor", "tester").send
+
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/products/1/history", "", nil))
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPatch, "/products/1", `{"price": 999.99}`, nil))
+	assert.Equal(t, http.StatusOK, send(http.MethodPatch, "/products/1", `{"specifications": {"RAM": "32GB", "Storage": null}}`, nil))
+
+	var revisions []repositories.Revision
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/1/history", "", &revisions))
+	if assert.Len(t, revisions, 2) {
+		assert.Equal(t, 1, revisions[0].Rev)
+		assert.Equal(t, "tester", revisions[0].Actor)
+		assert.Equal(t, []repositories.FieldChange{{Field: "price", Old: map[string]interface{}{"amount": "1200", "currency": "USD"}, New: map[string]interface{}{"amount": "999.99", "currency": "USD"}}}, revisions[0].Changes)
+		assert.Equal(t, []repositories.FieldChange{
+			{Field: "specifications.RAM", Old: "16GB", New: "32GB"},
+			{Field: "specifications.Storage", Old: "512GB SSD", New: nil},
+		}, revisions[1].Changes)
+	}
+
+	var revision repositories.Revision
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/1/history/1", "", &revision))
+	assert.Equal(t, usd("999.99"), revision.Product.Price)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/products/1/history/9", "", nil))
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/products/1/history/x", "", nil))
+
+	var product models.Product
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/products/1/rollback/1", "", &product))
+	assert.Equal(t, map[string]string{"RAM": "16GB", "Storage": "512GB SSD"}, product.Specifications)
+	assert.Equal(t, usd("999.99"), product.Price)
+
+	revisions = nil
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/1/history", "", &revisions))
+	if assert.Len(t, revisions, 3) {
+		assert.Equal(t, repositories.RevisionRollback, revisions[2].Action)
+	}
+}
+
+// TestIntegrationPriceHistory tests that price changes of every write path
+// are recorded and show up as price drops and lowest prices
+func TestIntegrationPriceHistory(t *testing.T) {
+	repo, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	prices := repositories.NewPriceHistory(&database.Database{}, filepath.Join(t.TempDir(), "prices"))
+	(&routes.ProductRouter{Repository: repo, PriceHistory: prices}).Bind(router.Group(""), nil)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	client := newTestClient(t, server)
+	send := client.send
+
+	var created models.Product
+	assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/products", `{"name": "Monitor", "price": 400}`, &created))
+	assert.Equal(t, http.StatusOK, send(http.MethodPut, fmt.Sprintf("/products/%d", created.ID), `{"name": "Monitor", "price": 380}`, nil))
+	assert.Equal(t, http.StatusOK, client.with("Content-Type", "application/merge-patch+json").send(http.MethodPatch, fmt.Sprintf("/products/%d", created.ID), `{"description": "27 inch"}`, nil))
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/products/bulk", fmt.Sprintf(`{"operations": [{"op": "patch", "id": %d, "patch": {"price": 300}}]}`, created.ID), nil))
+	assert.Equal(t, http.StatusOK, client.with("Content-Type", "text/csv").send(http.MethodPost, "/products/import", fmt.Sprintf("id,name,price\n%d,Monitor,320\n", created.ID), nil))
+
+	var history handlers.PriceHistory
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, fmt.Sprintf("/products/%d/price-history", created.ID), "", &history))
+	if assert.Len(t, history.Points, 4) {
+		assert.Equal(t, []string{"create", "update", "patch", "update"}, []string{history.Points[0].Action, history.Points[1].Action, history.Points[2].Action, history.Points[3].Action})
+	}
+	assert.Equal(t, usd("300"), history.Min)
+	assert.Equal(t, usd("400"), history.Max)
+	assert.Equal(t, usd("350"), history.Average)
+	assert.Equal(t, usd("300"), history.Lowest30Days)
+
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/products/1/price-history", "", nil))
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/products/x/price-history", "", nil))
+
+	var drops []handlers.PriceDrop
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/price-drops?min_pct=10", "", &drops))
+	if assert.Len(t, drops, 1) {
+		assert.Equal(t, "Monitor", drops[0].Name)
+		assert.Equal(t, usd("380"), drops[0].From)
+		assert.Equal(t, usd("300"), drops[0].To)
+		assert.Equal(t, 21.05, drops[0].Percent)
+	}
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/price-drops?since=1h", "", &drops))
+	assert.Len(t, drops, 2)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/price-drops?since="+time.Now().Add(time.Hour).UTC().Format(time.RFC3339), "", &drops))
+	assert.Empty(t, drops)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/products/price-drops?since=yesterday", "", nil))
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/products/price-drops?min_pct=-5", "", nil))
+
+	var comparison handlers.Comparison
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, fmt.Sprintf("/products/compare?ids=1,%d", created.ID), "", &comparison))
+	assert.Equal(t, map[int]money.Money{created.ID: usd("300")}, comparison.Lowest30Days)
+}
+
+// TestIntegrationReviews tests that the rating of a product is computed from
+// its approved reviews
+func TestIntegrationReviews(t *testing.T) {
+	repo, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	reviews := repositories.NewReviewStore(&database.Database{}, filepath.Join(t.TempDir(), "reviews"))
+	(&routes.ProductRouter{Repository: repo, Reviews: reviews}).Bind(router.Group(""), nil)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	send := newTestClient(t, server).send
+
+	var created []models.Review
+	for _, body := range []string{
+		`{"rating": 5, "title": "Great", "body": "Fast and quiet", "author": "ann"}`,
+		`{"rating": 4, "author": "bob"}`,
+		`{"rating": 1, "title": "Spam", "author": "eve"}`,
+	} {
+		var review models.Review
+		assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/products/2/reviews", body, &review))
+		assert.Equal(t, models.ReviewPending, review.Status)
+		created = append(created, review)
+	}
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/products/2/reviews", `{"rating": 6}`, nil))
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPost, "/products/99/reviews", `{"rating": 5, "author": "ann"}`, nil))
+
+	// Pending reviews are neither listed nor counted
+	var listed []models.Review
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/2/reviews", "", &listed))
+	assert.Empty(t, listed)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/2/reviews?status=pending", "", &listed))
+	assert.Len(t, listed, 3)
+
+	path := func(r models.Review) string { return fmt.Sprintf("/products/2/reviews/%d", r.ID) }
+	assert.Equal(t, http.StatusOK, send(http.MethodPatch, path(created[0]), `{"status": "approved"}`, nil))
+	assert.Equal(t, http.StatusOK, send(http.MethodPatch, path(created[1]), `{"status": "approved"}`, nil))
+	assert.Equal(t, http.StatusOK, send(http.MethodPatch, path(created[2]), `{"status": "rejected"}`, nil))
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPatch, path(created[2]), `{"status": "maybe"}`, nil))
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPatch, fmt.Sprintf("/products/1/reviews/%d", created[0].ID), `{"status": "approved"}`, nil))
+
+	var product models.Product
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/2", "", &product))
+	assert.Equal(t, 4.5, product.Rating)
+	assert.Equal(t, 2, product.ReviewCount)
+	assert.Equal(t, map[int]int{1: 0, 2: 0, 3: 0, 4: 1, 5: 1}, product.RatingDistribution)
+
+	// The rating cannot be written through the product
+	assert.Equal(t, http.StatusOK, send(http.MethodPatch, "/products/2", `{"rating": 1, "review_count": 100}`, &product))
+	assert.Equal(t, 4.5, product.Rating)
+	assert.Equal(t, 2, product.ReviewCount)
+
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, path(created[0]), "", nil))
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, path(created[0]), "", nil))
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/2", "", &product))
+	assert.Equal(t, 4.0, product.Rating)
+	assert.Equal(t, 1, product.ReviewCount)
+
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/2/reviews?status=all&limit=1", "", &listed))
+	if assert.Len(t, listed, 1) {
+		assert.Equal(t, created[2].ID, listed[0].ID)
+	}
+}
+
+// TestIntegrationBulkProducts tests the atomic and best-effort modes of the
+// bulk endpoint
+func TestIntegrationImages(t *testing.T) {
+	repo, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	store := blobstore.NewDisk(t.TempDir())
+	(&routes.ProductRouter{Repository: repo, Images: store, MaxImageSize: 64 << 10}).Bind(router.Group(""), nil)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	client := newTestClient(t, server)
+	send := client.send
+	upload := func(path string, content []byte, out interface{}) int {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("image", "upload.bin")
+		part.Write(content)
+		form.Close()
+		return client.with("Content-Type", form.FormDataContentType()).send(http.MethodPost, path, body.String(), out)
+	}
+
+	var product models.Product
+	assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/products", `{"name": "Camera", "price": 300}`, &product))
+	productPath := fmt.Sprintf("/products/%d", product.ID)
+
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 800, 400))))
+	var uploaded models.Image
+	assert.Equal(t, http.StatusCreated, upload(productPath+"/images", buf.Bytes(), &uploaded))
+	assert.Equal(t, "image/png", uploaded.ContentType)
+	assert.Equal(t, 800, uploaded.Width)
+	if assert.Len(t, uploaded.Thumbnails, 2) {
+		assert.Equal(t, models.Thumbnail{Name: "small", URL: strings.TrimSuffix(uploaded.URL, ".png") + "-small.png", Width: 160, Height: 80}, uploaded.Thumbnails[0])
+		assert.Equal(t, 240, uploaded.Thumbnails[1].Height)
+	}
+
+	// Thumbnails are served as stored
+	resp, err := http.Get(server.URL + uploaded.Thumbnails[0].URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "image/png", resp.Header.Get("Content-Type"))
+	thumb, err := png.Decode(resp.Body)
+	resp.Body.Close()
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 160, 80), thumb.Bounds())
+
+	// The first image becomes the primary image, and the gallery cannot be
+	// overwritten through the product
+	send(http.MethodGet, productPath, "", &product)
+	assert.Equal(t, uploaded.URL, product.ImageURL)
+	assert.Len(t, product.Images, 1)
+	send(http.MethodPut, productPath, `{"name": "Camera", "price": 280, "image_url": "`+uploaded.URL+`"}`, &product)
+	assert.Len(t, product.Images, 1)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, upload(productPath+"/images", []byte("not an image"), nil))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, upload(productPath+"/images", make([]byte, 65<<10), nil))
+	assert.Equal(t, http.StatusNotFound, upload("/products/99/images", buf.Bytes(), nil))
+
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, productPath+"/images/"+uploaded.ID, "", nil))
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, productPath+"/images/"+uploaded.ID, "", nil))
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, uploaded.URL, "", nil))
+	var emptied models.Product
+	send(http.MethodGet, productPath, "", &emptied)
+	assert.Empty(t, emptied.ImageURL)
+	assert.Empty(t, emptied.Images)
+}
+
+func TestIntegrationLocalization(t *testing.T) {
+	repo, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	locales := i18n.NewLocales("en", []string{"en", "de", "fr"})
+	(&routes.ProductRouter{Repository: repo, Locales: &locales}).Bind(router.Group(""), nil)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	client := newTestClient(t, server)
+	send := client.do
+
+	resp := send(http.MethodPatch, "/products/1", `{"translations": {"DE": {"name": "Notebook", "spec_labels": {"RAM": "Arbeitsspeicher"}}}}`, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var product models.Product
+	resp = send(http.MethodGet, "/products/1?locale=de", "", &product)
+	assert.Equal(t, "de", resp.Header.Get("Content-Language"))
+	assert.Equal(t, "Notebook", product.Name)
+	assert.Equal(t, "High-performance laptop", product.Description)
+	assert.Equal(t, map[string]string{"RAM": "Arbeitsspeicher", "Storage": "Storage"}, product.SpecLabels)
+
+	// Untranslated texts fall back along the preferred locales
+	var fallback models.Product
+	resp = client.with("Accept-Language", "fr-CH, de;q=0.8").do(http.MethodGet, "/products/1", "", &fallback)
+	assert.Equal(t, "fr", resp.Header.Get("Content-Language"))
+	assert.Equal(t, "Notebook", fallback.Name)
+
+	var stored models.Product
+	send(http.MethodGet, "/products/1", "", &stored)
+	assert.Equal(t, "Laptop", stored.Name)
+	assert.Nil(t, stored.SpecLabels)
+	assert.Equal(t, "Notebook", stored.Translations["de"].Name)
+
+	// A browser asking for the default locale gets the product as stored,
+	// with the strong tag If-Match needs
+	var english models.Product
+	resp = client.with("Accept-Language", "en-US,en;q=0.9").do(http.MethodGet, "/products/1", "", &english)
+	assert.Equal(t, "en", resp.Header.Get("Content-Language"))
+	assert.Equal(t, client.do(http.MethodGet, "/products/1", "", nil).Header.Get("ETag"), resp.Header.Get("ETag"))
+	assert.NotContains(t, resp.Header.Get("ETag"), "W/")
+	assert.Nil(t, english.SpecLabels)
+
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/products?locale=it", "", nil).StatusCode)
+
+	var invalid struct {
+		Fields []handlers.FieldError `json:"fields"`
+	}
+	resp = send(http.MethodPost, "/products", `{"name": "Mouse", "translations": {"not a tag": {"name": "Maus"}}}`, &invalid)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	if assert.Len(t, invalid.Fields, 1) {
+		assert.Equal(t, "translations[not a tag]", invalid.Fields[0].Field)
+		assert.Equal(t, handlers.CodeInvalidFormat, invalid.Fields[0].Code)
+	}
+
+	var reports []handlers.TranslationReport
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/translations/missing?locale=de", "", &reports).StatusCode)
+	if assert.Len(t, reports, 1) {
+		assert.Equal(t, 3, reports[0].Products)
+		assert.Equal(t, 0, reports[0].Complete)
+		assert.Equal(t, handlers.MissingTranslation{ID: 1, Name: "Laptop", Fields: []string{"description", "spec_labels[Storage]"}}, reports[0].Missing[0])
+	}
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/translations/missing", "", &reports).StatusCode)
+	assert.Len(t, reports, 2)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/products/translations/missing?locale=en", "", nil).StatusCode)
+}
+
+func TestIntegrationBrands(t *testing.T) {
+	repo, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	dir := t.TempDir()
+	brands := repositories.NewBrandStore(&database.Database{}, filepath.Join(dir, "brands"))
+	reviews := repositories.NewReviewStore(&database.Database{}, filepath.Join(dir, "reviews"))
+	(&routes.ProductRouter{Repository: repo, Brands: brands, Reviews: reviews}).Bind(router.Group(""), nil)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	send := newTestClient(t, server).send
+
+	var acme, zed models.Brand
+	assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/brands", `{"name": "Acme Audio", "country": "US", "logo_url": "/images/acme.png"}`, &acme))
+	assert.Equal(t, "acme-audio", acme.Slug)
+	assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/brands", `{"name": "Zed", "slug": "zed"}`, &zed))
+	assert.Equal(t, http.StatusConflict, send(http.MethodPost, "/brands", `{"name": "Zed again", "slug": "zed"}`, nil))
+
+	var verr struct {
+		Fields []handlers.FieldError `json:"fields"`
+	}
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/brands", `{"name": "Bad", "slug": "Not A Slug", "country": "USA"}`, &verr))
+	assert.Len(t, verr.Fields, 2)
+
+	var found models.Brand
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/brands/acme-audio", "", &found))
+	assert.Equal(t, acme, found)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/brands/99", "", nil))
+
+	// Products only link to existing brands
+	var product models.Product
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPatch, "/products/1", `{"brand_id": 99}`, &verr))
+	if assert.Len(t, verr.Fields, 1) {
+		assert.Equal(t, "brand_id", verr.Fields[0].Field)
+	}
+	assert.Equal(t, http.StatusOK, send(http.MethodPatch, "/products/1", fmt.Sprintf(`{"brand_id": %d}`, acme.ID), &product))
+	assert.Equal(t, acme.ID, product.BrandID)
+	assert.Equal(t, http.StatusOK, send(http.MethodPatch, "/products/3", fmt.Sprintf(`{"brand_id": %d}`, acme.ID), nil))
+	assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/products", fmt.Sprintf(`{"name": "Speaker", "price": {"amount": "90", "currency": "EUR"}, "category": "Audio", "brand_id": %d}`, zed.ID), nil))
+
+	var listed []models.Product
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, fmt.Sprintf("/products?brand_id=%d", acme.ID), "", &listed))
+	assert.Len(t, listed, 2)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodGet, "/products?brand_id=x", "", nil))
+
+	var facets handlers.Facets
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/facets?limit=1", "", &facets))
+	assert.Equal(t, 4, facets.Total)
+	assert.Equal(t, 1, facets.Unbranded)
+	assert.Equal(t, []handlers.BrandFacet{
+		{BrandID: acme.ID, Name: "Acme Audio", Slug: "acme-audio", Count: 2},
+		{BrandID: zed.ID, Name: "Zed", Slug: "zed", Count: 1},
+	}, facets.Brands)
+	assert.Equal(t, handlers.FacetValue{Value: "Electronics", Count: 2}, facets.Categories[0])
+
+	var comparison handlers.Comparison
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/products/compare?ids=1,2,3", "", &comparison))
+	assert.Equal(t, []handlers.BrandGroup{
+		{BrandID: acme.ID, Name: "Acme Audio", ProductIDs: []int{1, 3}},
+		{BrandID: 0, ProductIDs: []int{2}},
+	}, comparison.Brands)
+
+	var review models.Review
+	assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/products/1/reviews", `{"rating": 5, "author": "ann"}`, &review))
+	assert.Equal(t, http.StatusOK, send(http.MethodPatch, fmt.Sprintf("/products/1/reviews/%d", review.ID), `{"status": "approved"}`, nil))
+	assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/products/3/reviews", `{"rating": 2, "author": "bob"}`, &review))
+	assert.Equal(t, http.StatusOK, send(http.MethodPatch, fmt.Sprintf("/products/3/reviews/%d", review.ID), `{"status": "approved"}`, nil))
+
+	var stats handlers.BrandStats
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/brands/acme-audio/stats", "", &stats))
+	assert.Equal(t, 2, stats.ProductCount)
+	assert.Equal(t, 2, stats.ReviewCount)
+	assert.Equal(t, 3.5, stats.AverageRating)
+	if assert.Len(t, stats.PriceRanges, 1) {
+		assert.Equal(t, "150.00 USD", stats.PriceRanges[0].Min.String())
+		assert.Equal(t, "1200.00 USD", stats.PriceRanges[0].Max.String())
+	}
+	assert.Len(t, stats.Categories, 2)
+
+	// A brand with products cannot be deleted, trashed ones included
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/products/3", "", nil))
+	assert.Equal(t, http.StatusOK, send(http.MethodPatch, "/products/1", `{"brand_id": 0}`, nil))
+	assert.Equal(t, http.StatusConflict, send(http.MethodDelete, "/brands/acme-audio", "", nil))
+	assert.Equal(t, http.StatusOK, send(http.MethodPut, fmt.Sprintf("/brands/%d", acme.ID), `{"name": "Acme"}`, &found))
+	assert.Equal(t, "acme", found.Slug)
+
+	assert.Equal(t, http.StatusOK, send(http.MethodPatch, "/products/4", `{"brand_id": 0}`, nil))
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/brands/zed", "", nil))
+	var all []models.Brand
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/brands", "", &all))
+	assert.Len(t, all, 1)
+}
+
+func TestIntegrationBulkProducts(t *testing.T) {
+	repo, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	router := setupRouter(repo)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	type bulkResponse struct {
+		Committed bool `json:"committed"`
+		Results   []struct {
+			Index  int    `json:"index"`
+			Status int    `json:"status"`
+			ID     int    `json:"id"`
+			Error  string `json:"error"`
+		} `json:"results"`
+	}
+	client := newTestClient(t, server)
+	post := func(body string) (int, bulkResponse) {
+		var result bulkResponse
+		return client.send(http.MethodPost, "/products/bulk", body, &result), result
+	}
+
+	operations := `[
+		{"op": "create", "product": {"name": "Keyboard", "price": 49.9, "category": "Accessories"}},
+		{"op": "patch", "id": 1, "patch": {"price": 1100}},
+		{"op": "delete", "id": 99},
+		{"op": "delete", "id": 3}
+	]`
+
+	// One failing operation rolls back the whole atomic request
+	status, result := post(`{"mode": "atomic", "operations": ` + operations + `}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.False(t, result.Committed)
+	assert.Equal(t, http.StatusNotFound, result.Results[2].Status)
+	products, err := repo.LoadProducts()
+	assert.NoError(t, err)
+	assert.Len(t, products, 3)
+	assert.Equal(t, usd("1200"), products[0].Price)
+
+	// Best effort applies everything else
+	status, result = post(`{"mode": "best_effort", "operations": ` + operations + `}`)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, result.Committed)
+	if assert.Len(t, result.Results, 4) {
+		assert.Equal(t, http.StatusCreated, result.Results[0].Status)
+		assert.Equal(t, 4, result.Results[0].ID)
+		assert.Equal(t, http.StatusOK, result.Results[1].Status)
+		assert.Equal(t, http.StatusNotFound, result.Results[2].Status)
+		assert.Equal(t, "Not found", result.Results[2].Error)
+		assert.Equal(t, http.StatusNoContent, result.Results[3].Status)
+	}
+
+	var live []models.Product
+	assert.Equal(t, http.StatusOK, client.send(http.MethodGet, "/products?limit=10", "", &live))
+	if assert.Len(t, live, 3) {
+		assert.Equal(t, usd("1100"), live[0].Price)
+		assert.Equal(t, "Keyboard", live[2].Name)
+	}
+
+	status, _ = post(`{"mode": "sometimes", "operations": ` + operations + `}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = post(`{"operations": []}`)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+// TestIntegrationImportAndExport tests CSV imports with dry runs and row
+// errors, and exports filtered like the listing
+func TestIntegrationImportAndExport(t *testing.T) {
+	repo, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	router := setupRouter(repo)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	type importResponse struct {
+		handlers.ImportReport
+		Report handlers.ImportReport `json:"report"`
+	}
+	csv := newTestClient(t, server).with("Content-Type", "text/csv")
+	importCSV := func(query, body string) (int, importResponse) {
+		var result importResponse
+		return csv.send(http.MethodPost, "/products/import"+query, body, &result), result
+	}
+
+	invalid := "id,name,price,category,spec.RAM\n1,Laptop Pro,1500,Electronics,32GB\n,Mouse,oops,Accessories,\n"
+	status, result := importCSV("", invalid)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, 1, result.Report.Failed)
+	assert.Equal(t, []handlers.ImportError{{Row: 3, Error: `price: "oops" is not a number`}}, result.Report.Errors)
+
+	// Upserts never bring back IDs that are unknown or in the trash
+	status, result = importCSV("", "id,name\n99,Ghost\n")
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, []handlers.ImportError{{Row: 2, Error: "product 99 does not exist"}}, result.Report.Errors)
+
+	valid := "id,name,price,category,spec.RAM\n1,Laptop Pro,1500,Electronics,32GB\n,Mouse,25,Accessories,\n"
+	status, result = importCSV("?dry_run=true", valid)
+	assert.Equal(t, http.StatusOK, status)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, 1, result.Updated)
+	assert.Equal(t, 1, result.Created)
+	products, _ := repo.LoadProducts()
+	assert.Len(t, products, 3)
+
+	status, result = importCSV("", valid)
+	assert.Equal(t, http.StatusOK, status)
+	assert.False(t, result.DryRun)
+	products, _ = repo.LoadProducts()
+	if assert.Len(t, products, 4) {
+		assert.Equal(t, "Laptop Pro", products[0].Name)
+		assert.Equal(t, map[string]string{"RAM": "32GB"}, products[0].Specifications)
+		assert.Equal(t, 4, products[3].ID)
+	}
+
+	resp, err := http.Get(server.URL + "/products/export?format=csv&category=Accessories&sort=id")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "text/csv", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "id,sku,gtin,name,image_url,description,price,currency,rating,category,brand_id,parent_id,variant_axes,spec.Connectivity,spec.Driver size\n"+
+		"3,,,Headphones,/images/headphones.png,Noise-cancelling headphones,150,USD,4.2,Accessories,,,,Bluetooth 5.0,40mm\n"+
+		"4,,,Mouse,,,25,USD,0,Accessories,,,,,\n", string(body))
+
+	resp, err = http.Get(server.URL + "/products/export?format=jsonl")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ = io.ReadAll(resp.Body)
+	assert.Equal(t, 4, bytes.Count(body, []byte("\n")))
+
+	resp, err = http.Get(server.URL + "/products/export?format=xml")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}